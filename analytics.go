@@ -0,0 +1,351 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Dimension is a section attribute enrollment can be grouped by.
+type Dimension string
+
+// Dimensions enrollment can be grouped by.
+const (
+	BySubject      Dimension = "subject"
+	ByCollege      Dimension = "college"
+	ByMode         Dimension = "mode"
+	ByScheduleType Dimension = "schedule_type"
+	ByCampus       Dimension = "campus"
+	ByDays         Dimension = "days"
+	ByHour         Dimension = "hour"
+)
+
+// dimensions lists every Dimension, in the order help text shows them.
+var dimensions = []Dimension{BySubject, ByCollege, ByMode, ByScheduleType, ByCampus, ByDays, ByHour}
+
+// defaultPercentiles are reported when no percentiles are requested.
+var defaultPercentiles = []float64{50, 90}
+
+// noValue labels sections with an empty grouping field.
+const noValue = "(none)"
+
+// ParseDimension accepts a Dimension name, case-insensitively.
+func ParseDimension(s string) (Dimension, error) {
+	for _, d := range dimensions {
+		if strings.EqualFold(strings.TrimSpace(s), string(d)) {
+			return d, nil
+		}
+	}
+	names := make([]string, len(dimensions))
+	for i, d := range dimensions {
+		names[i] = string(d)
+	}
+	return "", fmt.Errorf("unknown grouping %q (want one of %s)", s, strings.Join(names, ", "))
+}
+
+// ParsePercentiles parses a comma-separated list of percentiles such as "50,90,99".
+func ParsePercentiles(s string) ([]float64, error) {
+	var percentiles []float64
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		p, err := strconv.ParseFloat(field, 64)
+		if err != nil || p < 0 || p > 100 {
+			return nil, fmt.Errorf("percentile %q must be a number from 0 to 100", field)
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, nil
+}
+
+// sectionEnrollment returns a section's enrollment. Every meeting row of a
+// section repeats the same count, so the first row is used.
+func sectionEnrollment(section ClassSection) int {
+	if len(section.Rows) == 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(section.Rows[0].ActualEnrollment))
+	return n
+}
+
+// dimensionValue returns the value a section is grouped under.
+func dimensionValue(section ClassSection, by Dimension) string {
+	var value string
+	switch by {
+	case BySubject:
+		value = section.Subject
+	case ByCollege:
+		value = section.Rows[0].College
+	case ByMode:
+		value = section.InstructionMode
+	case ByScheduleType:
+		value = section.Rows[0].ScheduleTypeCode
+	case ByCampus:
+		value = section.Rows[0].CampusCode
+	case ByDays:
+		var days strings.Builder
+		for _, m := range section.Meetings {
+			days.WriteString(m.Days)
+		}
+		value = normalizeDays(days.String())
+		if value == "" {
+			value = "TBA"
+		}
+	case ByHour:
+		value = "TBA"
+		if len(section.Meetings) > 0 {
+			start := section.Meetings[0].Start
+			for _, m := range section.Meetings[1:] {
+				start = min(start, m.Start)
+			}
+			value = fmt.Sprintf("%02d:00", start/60)
+		}
+	}
+	if value = strings.TrimSpace(value); value == "" {
+		return noValue
+	}
+	return value
+}
+
+// EnrollmentGroup aggregates the sections that share a dimension value.
+type EnrollmentGroup struct {
+	Key         string             `json:"key"`
+	Sections    int                `json:"sections"`
+	Enrollment  int                `json:"enrollment"`
+	Mean        float64            `json:"mean"`
+	Min         int                `json:"min"`
+	Max         int                `json:"max"`
+	Percentiles map[string]float64 `json:"percentiles"` // Keyed like "p90".
+}
+
+// EnrollmentByGroup totals enrollment and section counts per dimension value,
+// with per-section percentiles, largest enrollment first.
+func EnrollmentByGroup(sections []ClassSection, by Dimension, percentiles []float64) []EnrollmentGroup {
+	if len(percentiles) == 0 {
+		percentiles = defaultPercentiles
+	}
+	counts := make(map[string][]int)
+	var order []string
+	for _, section := range sections {
+		key := dimensionValue(section, by)
+		if _, ok := counts[key]; !ok {
+			order = append(order, key)
+		}
+		counts[key] = append(counts[key], sectionEnrollment(section))
+	}
+
+	groups := make([]EnrollmentGroup, 0, len(order))
+	for _, key := range order {
+		groups = append(groups, summarizeEnrollment(key, counts[key], percentiles))
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Enrollment != groups[j].Enrollment {
+			return groups[i].Enrollment > groups[j].Enrollment
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
+
+// summarizeEnrollment computes the statistics for one group's section enrollments.
+func summarizeEnrollment(key string, counts []int, percentiles []float64) EnrollmentGroup {
+	sort.Ints(counts)
+	group := EnrollmentGroup{
+		Key:         key,
+		Sections:    len(counts),
+		Min:         counts[0],
+		Max:         counts[len(counts)-1],
+		Percentiles: make(map[string]float64, len(percentiles)),
+	}
+	for _, n := range counts {
+		group.Enrollment += n
+	}
+	group.Mean = math.Round(float64(group.Enrollment)/float64(len(counts))*100) / 100
+	for _, p := range percentiles {
+		group.Percentiles[percentileKey(p)] = Percentile(counts, p)
+	}
+	return group
+}
+
+// percentileKey names a percentile in EnrollmentGroup.Percentiles, e.g. "p90".
+func percentileKey(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// Percentile returns the p-th percentile of sorted values, interpolating
+// linearly between the closest ranks. It returns 0 for no values.
+func Percentile(sorted []int, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	fraction := rank - float64(lower)
+	value := float64(sorted[lower]) + fraction*float64(sorted[upper]-sorted[lower])
+	return math.Round(value*100) / 100
+}
+
+// SectionEnrollment is one section and its enrollment.
+type SectionEnrollment struct {
+	CRN          string `json:"crn"`
+	Subject      string `json:"subject"`
+	CourseNumber string `json:"course_number"`
+	Section      string `json:"section"`
+	Title        string `json:"title"`
+	Instructor   string `json:"instructor,omitempty"`
+	Mode         string `json:"instruction_mode"`
+	Enrollment   int    `json:"enrollment"`
+}
+
+// TopSections returns the n sections with the highest enrollment.
+func TopSections(sections []ClassSection, n int) []SectionEnrollment {
+	top := make([]SectionEnrollment, 0, len(sections))
+	for _, section := range sections {
+		top = append(top, SectionEnrollment{
+			CRN:          section.CRN,
+			Subject:      section.Subject,
+			CourseNumber: section.CourseNumber,
+			Section:      section.Section,
+			Title:        section.Title,
+			Instructor:   section.Instructor,
+			Mode:         section.InstructionMode,
+			Enrollment:   sectionEnrollment(section),
+		})
+	}
+	sort.SliceStable(top, func(i, j int) bool {
+		return top[i].Enrollment > top[j].Enrollment
+	})
+	return top[:min(n, len(top))]
+}
+
+// analyticsTopPattern finds "top 10" or "10 most" in a question.
+var analyticsTopPattern = regexp.MustCompile(`\btop (\d+)\b|\b(\d+) (?:most|largest|biggest|fullest)\b`)
+
+// analyticsRankPattern marks questions asking for the sections with the
+// highest enrollment. "Largest", "biggest" and "most popular" only count next
+// to a section noun, so "the largest room" or "the most popular professor" does not.
+var analyticsRankPattern = regexp.MustCompile(`\b(?:most full|fullest|most enrolled|highest enrollment|most students)\b|` +
+	`\b(?:largest|biggest|most popular)\s+(?:[\w&-]+\s+){0,2}?(?:sections?|class(?:es)?|courses?|lectures?|offerings)\b|` +
+	`\b(?:sections?|class(?:es)?|courses?|lectures?|offerings)\s+(?:[\w&-]+\s+){0,2}?(?:largest|biggest|most popular)\b`)
+
+// analyticsCountWords mark questions asking for enrollment or section totals.
+var analyticsCountWords = []string{"how many students", "how many people", "total enrollment", "how many sections", "number of students", "number of sections"}
+
+// analyticsModes maps instruction mode phrases to the schedule's mode names,
+// longest phrases first so "asynchronous" is not read as "synchronous".
+var analyticsModes = []struct{ phrase, mode string }{
+	{"online asynchronous", "Online Asynchronous"},
+	{"online synchronous", "Online Synchronous"},
+	{"asynchronous", "Online Asynchronous"},
+	{"synchronous", "Online Synchronous"},
+	{"in-person", "In-Person"},
+	{"in person", "In-Person"},
+	{"hybrid", "Hybrid"},
+	{"online", "Online"},
+}
+
+// defaultTopSections is how many sections a ranking question lists by default.
+const defaultTopSections = 5
+
+// analyticsAnswer answers enrollment questions such as "which CS sections are
+// most full?" or "how many students take online asynchronous classes?" with
+// numbers computed from the schedule. It returns false for other questions.
+func analyticsAnswer(question string, courses []Course) (Answer, bool) {
+	lower := strings.ToLower(question)
+	rank := analyticsRankPattern.MatchString(lower)
+	count := containsAny(lower, analyticsCountWords)
+	if !rank && !count {
+		return Answer{}, false
+	}
+
+	rows, scope := analyticsRows(question, courses)
+	sections := groupSections(rows)
+	if len(sections) == 0 {
+		return Answer{Text: fmt.Sprintf("There are no %ssections in the schedule.", scope), Grounded: true, Retrieval: RetrievalAnalytics}, true
+	}
+
+	var b strings.Builder
+	if rank {
+		n := defaultTopSections
+		if m := analyticsTopPattern.FindStringSubmatch(lower); m != nil {
+			n, _ = strconv.Atoi(m[1] + m[2])
+		}
+		// The schedule records enrollment but not capacity, so "full" is read as "most enrolled".
+		fmt.Fprintf(&b, "The %ssections with the highest enrollment (the schedule lists enrollment, not capacity):\n", scope)
+		for i, s := range TopSections(sections, max(n, 1)) {
+			fmt.Fprintf(&b, "%d. %s %s-%s %s (CRN %s", i+1, s.Subject, s.CourseNumber, s.Section, s.Title, s.CRN)
+			if s.Instructor != "" {
+				fmt.Fprintf(&b, ", %s", s.Instructor)
+			}
+			fmt.Fprintf(&b, "): %d students\n", s.Enrollment)
+		}
+	} else {
+		stats := summarizeEnrollment("", sectionCounts(sections), defaultPercentiles)
+		fmt.Fprintf(&b, "%d students are enrolled in %d %ssections (median %.0f, largest %d per section).\n",
+			stats.Enrollment, len(sections), scope, stats.Percentiles["p50"], stats.Max)
+	}
+	return Answer{Text: strings.TrimRight(b.String(), "\n"), Grounded: true, Retrieval: RetrievalAnalytics}, true
+}
+
+// sectionCounts lists the enrollment of each section.
+func sectionCounts(sections []ClassSection) []int {
+	counts := make([]int, len(sections))
+	for i, section := range sections {
+		counts[i] = sectionEnrollment(section)
+	}
+	return counts
+}
+
+// analyticsRows selects the schedule rows a question asks about: those of
+// the courses or sections it names, as in "CS 272" or "Computer Science
+// 272", or else of the subjects it names, narrowed to the instruction mode it
+// names. It also describes them for the answer, e.g. "CS online asynchronous ".
+func analyticsRows(question string, courses []Course) ([]Course, string) {
+	rows := courses
+	var scope []string
+
+	if codes := findCourseCodes(question, uniqueSubjects(courses)); len(codes) > 0 {
+		rows = nil
+		for _, code := range codes {
+			rows = append(rows, FilterSections(courses, code.Filter())...)
+		}
+		scope = append(scope, joinCourseCodes(codes))
+	} else if subjects := questionSubjects(question, courses); len(subjects) > 0 {
+		rows = nil
+		for _, course := range courses {
+			if slices.Contains(subjects, course.Subject) {
+				rows = append(rows, course)
+			}
+		}
+		scope = append(scope, strings.Join(subjects, "/"))
+	}
+
+	lower := strings.ToLower(question)
+	for _, m := range analyticsModes {
+		if strings.Contains(lower, m.phrase) {
+			rows = FilterSections(rows, SectionFilter{Mode: m.mode})
+			scope = append(scope, strings.ToLower(m.mode))
+			break
+		}
+	}
+	if len(scope) == 0 {
+		return rows, ""
+	}
+	return rows, strings.Join(scope, " ") + " "
+}
+
+// containsAny reports whether s contains any of the phrases.
+func containsAny(s string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(s, phrase) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// statsRow builds a one-row section with the given enrollment for analytics tests.
+func statsRow(subject, crn, mode, days, begin, enrollment string) Course {
+	return Course{
+		Subject:             subject,
+		CourseNumber:        "100",
+		Section:             crn[len(crn)-2:],
+		CRN:                 crn,
+		Title:               subject + " Course",
+		College:             "AS",
+		ScheduleTypeCode:    "L",
+		CampusCode:          "M",
+		InstructionModeDesc: mode,
+		MeetDays:            days,
+		BeginTime:           begin,
+		EndTime:             "1200",
+		ActualEnrollment:    enrollment,
+	}
+}
+
+var statsCourses = []Course{
+	statsRow("CS", "70001", "In-Person", "MW", "0900", "40"),
+	statsRow("CS", "70002", "In-Person", "TR", "1000", "10"),
+	statsRow("CS", "70003", "Online Asynchronous", "", "", "25"),
+	statsRow("MATH", "70004", "Online Asynchronous", "", "", "15"),
+	statsRow("MATH", "70005", "Hybrid", "MW", "0930", "30"),
+}
+
+func TestEnrollmentByGroup(t *testing.T) {
+	sections := groupSections(statsCourses)
+
+	bySubject := EnrollmentByGroup(sections, BySubject, []float64{50, 100})
+	if len(bySubject) != 2 || bySubject[0].Key != "CS" || bySubject[0].Enrollment != 75 || bySubject[0].Sections != 3 {
+		t.Fatalf("Expected CS first with 75 students in 3 sections, got %+v", bySubject)
+	}
+	if cs := bySubject[0]; cs.Percentiles["p50"] != 25 || cs.Percentiles["p100"] != 40 || cs.Mean != 25 {
+		t.Errorf("Expected median 25, max 40 and mean 25, got %+v", cs)
+	}
+
+	byHour := EnrollmentByGroup(sections, ByHour, nil)
+	hours := map[string]int{}
+	for _, g := range byHour {
+		hours[g.Key] = g.Enrollment
+	}
+	if hours["09:00"] != 70 || hours["10:00"] != 10 || hours["TBA"] != 40 {
+		t.Errorf("Unexpected enrollment by hour %v", hours)
+	}
+
+	if _, err := ParseDimension("weekday"); err == nil {
+		t.Error("Expected an unknown dimension to be rejected")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []int{10, 20, 30, 40}
+	for _, tc := range []struct {
+		p    float64
+		want float64
+	}{{0, 10}, {50, 25}, {90, 37}, {100, 40}} {
+		if got := Percentile(values, tc.p); got != tc.want {
+			t.Errorf("Percentile(%v) = %v, want %v", tc.p, got, tc.want)
+		}
+	}
+	if Percentile(nil, 50) != 0 {
+		t.Error("Expected 0 for no values")
+	}
+}
+
+func TestAnalyticsAnswer(t *testing.T) {
+	answer, ok := analyticsAnswer("Which CS sections are most full?", statsCourses)
+	if !ok || answer.Retrieval != RetrievalAnalytics {
+		t.Fatalf("Expected an analytics answer, got %+v", answer)
+	}
+	if !strings.Contains(answer.Text, "1. CS 100-01") || strings.Contains(answer.Text, "MATH") {
+		t.Errorf("Expected CS sections ranked by enrollment, got:\n%s", answer.Text)
+	}
+
+	answer, ok = analyticsAnswer("How many students take online asynchronous classes?", statsCourses)
+	if !ok || !strings.HasPrefix(answer.Text, "40 students are enrolled in 2 online asynchronous sections") {
+		t.Errorf("Expected 40 students in 2 sections, got %q", answer.Text)
+	}
+
+	// Questions naming a course count that course's sections alone.
+	courses := append(statsCourses[:len(statsCourses):len(statsCourses)], Course{Subject: "CS", CourseNumber: "272", Section: "01", CRN: "70006", ActualEnrollment: "100"})
+	for _, question := range []string{"How many students are in CS 100?", "How many sections of CS 100 are there?", "How many students take Computer Science 100?"} {
+		answer, ok := analyticsAnswer(question, courses)
+		if !ok || !strings.HasPrefix(answer.Text, "75 students are enrolled in 3 CS 100 sections") {
+			t.Errorf("%q: expected 75 students in 3 CS 100 sections, got %q", question, answer.Text)
+		}
+	}
+
+	answer, ok = analyticsAnswer("What are the 2 biggest MATH classes?", statsCourses)
+	if !ok || !strings.Contains(answer.Text, "1. MATH 100-05") || strings.Contains(answer.Text, "3.") {
+		t.Errorf("Expected the 2 MATH sections ranked, got:\n%s", answer.Text)
+	}
+
+	for _, question := range []string{"Who teaches CS 100?", "What is the largest room in Harney?", "Who is the most popular CS professor?"} {
+		if _, ok := analyticsAnswer(question, statsCourses); ok {
+			t.Errorf("Expected %q to be left to retrieval", question)
+		}
+	}
+}
+
+func TestServerAnalytics(t *testing.T) {
+	server := newTestServer()
+	stats := getJSON(t, server, http.MethodGet, "/analytics/enrollment?by=mode&percentiles=50,75", "", http.StatusOK)
+	if stats["sections"] != float64(2) {
+		t.Errorf("Expected 2 sections, got %v", stats["sections"])
+	}
+	top := getJSON(t, server, http.MethodGet, "/analytics/top-sections?n=1", "", http.StatusOK)
+	if sections := top["sections"].([]interface{}); len(sections) != 1 {
+		t.Errorf("Expected 1 top section, got %v", sections)
+	}
+	getJSON(t, server, http.MethodGet, "/analytics/enrollment?by=weekday", "", http.StatusBadRequest)
+	getJSON(t, server, http.MethodGet, "/analytics/enrollment?percentiles=101", "", http.StatusBadRequest)
+}
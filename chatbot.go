@@ -2,6 +2,7 @@ package main
 
 import (
     "context"
+    "encoding/json"
     "fmt"
     "strings"
 	"log"
@@ -18,19 +19,135 @@ type ChatBot struct {
     chromaClient         *chroma.Client
     courseCollection     *chroma.Collection
     instructorCollection *chroma.Collection
+    fallback             FallbackPolicy
+    topK                 int
+    terms                []*termIndex // Every loaded term in order; the default term's index shares the fields above.
+    defaultTerm          *termIndex
 }
 
+// termIndex is one term's schedule and collections
+type termIndex struct {
+    term                 Term
+    metadata             *MetadataExtractor
+    courseCollection     *chroma.Collection
+    instructorCollection *chroma.Collection
+}
+
+// courses returns the term's course records, or nil if no schedule was loaded
+func (index *termIndex) courses() []Course {
+    if index.metadata == nil {
+        return nil
+    }
+    return index.metadata.courses
+}
 
-// NewChatBot initializes a ChatBot with an LLM client, metadata extractor, and ChromaDB context
-func NewChatBot(llmClient *LLMClient, metadata *MetadataExtractor, chromaCtx context.Context, chromaClient *chroma.Client, courseCollection, instructorCollection *chroma.Collection) *ChatBot {
-    return &ChatBot{
+// instructors returns the canonical names of the term's instructors, listed once when the term was loaded
+func (index *termIndex) instructors() []string {
+    if index.metadata == nil {
+        return nil
+    }
+    if index.metadata.Instructors == nil {
+        // Extractors built directly from records, as in tests, have no list yet
+        return uniqueInstructors(index.metadata.courses)
+    }
+    return index.metadata.Instructors
+}
+
+
+// NewChatBot initializes a ChatBot with its configuration, an LLM client, metadata extractor, and ChromaDB context
+func NewChatBot(config Config, llmClient *LLMClient, metadata *MetadataExtractor, chromaCtx context.Context, chromaClient *chroma.Client, courseCollection, instructorCollection *chroma.Collection) *ChatBot {
+    bot := &ChatBot{
         llmClient:         llmClient,
         metadata:          metadata,
         chromaCtx:         chromaCtx,
         chromaClient:      chromaClient,
         courseCollection:  courseCollection,
         instructorCollection: instructorCollection,
+        fallback:          config.FallbackPolicy(),
+        topK:              config.TopK,
+    }
+    term, _ := termFromFileName(config.CSVPath)
+    bot.defaultTerm = &termIndex{term: term, metadata: metadata, courseCollection: courseCollection, instructorCollection: instructorCollection}
+    bot.terms = []*termIndex{bot.defaultTerm}
+    return bot
+}
+
+// AddTerm makes another term's schedule and collections available to questions that name it.
+// Terms are kept in chronological order.
+func (bot *ChatBot) AddTerm(term Term, metadata *MetadataExtractor, courseCollection, instructorCollection *chroma.Collection) {
+    index := &termIndex{term: term, metadata: metadata, courseCollection: courseCollection, instructorCollection: instructorCollection}
+    at := len(bot.terms)
+    for i, existing := range bot.terms {
+        if existing.term == term {
+            bot.terms[i] = index
+            return
+        }
+        if term.Before(existing.term) && at == len(bot.terms) {
+            at = i
+        }
     }
+    bot.terms = append(bot.terms[:at], append([]*termIndex{index}, bot.terms[at:]...)...)
+}
+
+// SetDefaultTerm chooses the term used for questions that do not name one
+func (bot *ChatBot) SetDefaultTerm(term Term) error {
+    index := bot.term(term)
+    if index == nil {
+        return fmt.Errorf("%w: %s", ErrTermNotLoaded, term)
+    }
+    bot.defaultTerm = index
+    bot.metadata = index.metadata
+    bot.courseCollection = index.courseCollection
+    bot.instructorCollection = index.instructorCollection
+    return nil
+}
+
+// Terms lists the loaded terms in chronological order
+func (bot *ChatBot) Terms() []Term {
+    terms := make([]Term, 0, len(bot.terms))
+    for _, index := range bot.terms {
+        terms = append(terms, index.term)
+    }
+    return terms
+}
+
+// term returns the index of a loaded term, or nil
+func (bot *ChatBot) term(term Term) *termIndex {
+    for _, index := range bot.terms {
+        if index.term == term {
+            return index
+        }
+    }
+    return nil
+}
+
+// termCourses returns the course records of the named term, or of the default term when name is empty
+func (bot *ChatBot) termCourses(name string) ([]Course, error) {
+    if strings.TrimSpace(name) == "" {
+        return bot.courses(), nil
+    }
+    term, err := ParseTerm(name)
+    if err != nil {
+        return nil, err
+    }
+    index := bot.term(term)
+    if index == nil {
+        return nil, fmt.Errorf("%w: %s", ErrTermNotLoaded, term)
+    }
+    return index.courses(), nil
+}
+
+// courses returns the loaded course records, or nil if no schedule was loaded
+func (bot *ChatBot) courses() []Course {
+    if bot.metadata == nil {
+        return nil
+    }
+    return bot.metadata.courses
+}
+
+// SetFallbackPolicy chooses how the chatbot answers when no catalog documents match a question
+func (bot *ChatBot) SetFallbackPolicy(policy FallbackPolicy) {
+    bot.fallback = policy
 }
 
 
@@ -49,22 +166,28 @@ func (bot *ChatBot) QueryCourses(term string) string {
 
 	
 	
-    // Query the collection using the canonical name
-    queryResults, err := bot.courseCollection.Query(bot.chromaCtx, []string{canonicalName}, 5, nil, nil, nil)
-    if err != nil {
-        log.Printf("Error querying collection: %v", err)
-        return "An error occurred while searching for courses."
+    // Use the instructor's profile when the schedule is loaded, otherwise query the collection using the canonical name
+    var documents []string
+    if profiles := instructorProfiles(FilterSections(bot.courses(), SectionFilter{Instructor: canonicalName})); len(profiles) > 0 {
+        documents = profileDocuments(profiles, bot.courses())
+    } else {
+        var err error
+        documents, _, err = bot.retrieve(bot.chromaCtx, bot.courseCollection, bot.courses(), canonicalName, ScheduleConstraint{})
+        if err != nil {
+            log.Printf("Error querying collection: %v", err)
+            return "An error occurred while searching for courses."
+        }
     }
 
     // Check if results are empty
-    if len(queryResults.Documents) == 0 {
+    if len(documents) == 0 {
         return fmt.Sprintf("No courses found for %s.", canonicalName)
     }
 
     // Format the results
     var result strings.Builder
     result.WriteString(fmt.Sprintf("Here are the courses taught by %s:\n", canonicalName))
-    for _, doc := range queryResults.Documents {
+    for _, doc := range documents {
         result.WriteString(fmt.Sprintf("- %s\n", doc))
     }
 
@@ -72,8 +195,25 @@ func (bot *ChatBot) QueryCourses(term string) string {
 }
 
 
+// AnswerQuestion answers a question and returns only the answer text
 func (bot *ChatBot) AnswerQuestion(question string) (string, error) {
-    fmt.Printf("Processing question: %s\n", question)
+    answer, err := bot.Ask(question)
+    if err != nil {
+        return "", err
+    }
+    return answer.Text, nil
+}
+
+// Ask answers a question from retrieved catalog documents, applying the
+// configured fallback policy when retrieval finds nothing
+func (bot *ChatBot) Ask(question string) (Answer, error) {
+    return bot.AskStream(context.Background(), question, nil)
+}
+
+// AskStream answers a question like Ask, passing the answer to onToken piece by piece
+// as it is generated. Cancelling ctx stops generation and returns ctx.Err().
+func (bot *ChatBot) AskStream(ctx context.Context, question string, onToken func(string)) (Answer, error) {
+    log.Printf("Processing question: %s", question)
 
     instructors := InitializeInstructors()
     for _, instructor := range instructors {
@@ -84,35 +224,256 @@ func (bot *ChatBot) AnswerQuestion(question string) (string, error) {
         }
     }
 
-    // Use the appropriate collection for the query
+    // Questions about which terms a course runs in are answered from every loaded schedule
+    if answer, ok := crossTermAnswer(question, bot.terms); ok {
+        emit(onToken, answer.Text)
+        return answer, nil
+    }
+
+    // Answer from the term the question names, or the default term
+    index := bot.defaultTerm
+    if term, named := questionTerm(question, bot.defaultTerm.term); named && term != index.term {
+        if index = bot.term(term); index == nil {
+            answer := Answer{Text: bot.termNotLoadedMessage(term)}
+            emit(onToken, answer.Text)
+            return answer, nil
+        }
+    }
+
+    // Enrollment questions are answered from the numbers, not by the LLM
+    if answer, ok := analyticsAnswer(question, index.courses()); ok {
+        emit(onToken, answer.Text)
+        return answer, nil
+    }
+
+    // Use the appropriate collection for the query; instructor profiles answer questions about who teaches
     var collectionToQuery *chroma.Collection
-    if strings.Contains(strings.ToLower(question), "instructor") {
-        collectionToQuery = bot.instructorCollection
+    if strings.Contains(strings.ToLower(question), "instructor") || teachingPattern.MatchString(question) {
+        collectionToQuery = index.instructorCollection
     } else {
-        collectionToQuery = bot.courseCollection
+        collectionToQuery = index.courseCollection
     }
 
-    documents := Query(bot.chromaCtx, bot.chromaClient, collectionToQuery, question)
+    // Days and times in the question, such as "after 3pm" or "not on Fridays", filter the sections
+    constraint, constrained := ParseScheduleConstraint(question)
+
+    // Questions naming a course are answered from every section of it, not just the closest matches
+    var documents []string
+    var retrieval string
+    matches := "the relevant matches"
+    codes := findCourseCodes(question, uniqueSubjects(index.courses()))
+    if len(codes) > 0 && len(index.courses()) > 0 {
+        documents, retrieval = lookupCourses(index.courses(), codes), RetrievalDirect
+        if len(documents) == 0 {
+            answer := Answer{Text: notOfferedMessage(codes, index.term), Grounded: true, Retrieval: RetrievalDirect}
+            emit(onToken, answer.Text)
+            return answer, nil
+        }
+        if constrained {
+            documents = lookupCourses(constraint.FilterRows(index.courses()), codes)
+            if len(documents) == 0 {
+                answer := Answer{Text: noMatchingSectionsMessage(codes, constraint, index.term), Grounded: true, Retrieval: RetrievalDirect}
+                emit(onToken, answer.Text)
+                return answer, nil
+            }
+        }
+        matches = fmt.Sprintf("all %d schedule rows of %s", len(documents), joinCourseCodes(codes))
+    } else if answer, ok := listingAnswer(question, index.courses(), index.instructors(), index.term); ok {
+        // Questions asking which courses are offered are answered with every matching section
+        emit(onToken, answer.Text)
+        return answer, nil
+    } else if profiles := namedInstructorProfiles(question, index.courses(), index.instructors()); len(profiles) > 0 {
+        // Questions naming an instructor are answered from their profile and the sections it lists
+        courses := index.courses()
+        if constrained {
+            courses = constraint.FilterRows(courses)
+        }
+        documents, retrieval = profileDocuments(profiles, courses), RetrievalDirect
+        matches = fmt.Sprintf("the teaching profile of %s and the schedule rows of their sections", joinInstructorNames(profiles))
+    } else {
+        var err error
+        documents, retrieval, err = bot.retrieve(ctx, collectionToQuery, index.courses(), question, constraint)
+        if err != nil {
+            return Answer{}, err
+        }
+    }
 
     if len(documents) > 0 {
-        preamble := "Based on the available information, here are the relevant matches:\n\n"
+        source := "the available information"
+        if !index.term.IsZero() {
+            source = fmt.Sprintf("the %s schedule", index.term)
+        }
+        if constrained {
+            matches += fmt.Sprintf(" for sections that %s", constraint)
+        }
+        preamble := fmt.Sprintf("Based on %s, here are %s:\n\n", source, matches)
         for _, doc := range documents {
-            preamble += fmt.Sprintf("- %s\n", strings.Join(doc, " "))
+            preamble += fmt.Sprintf("- %s\n", doc)
         }
         preamble += "\nPlease use this information to answer the user's question."
 
-        response, err := bot.llmClient.ChatCompletion(question, preamble)
+        response, usage, err := bot.complete(ctx, question, preamble, onToken)
         if err != nil {
-            return "", fmt.Errorf("ChatCompletion failed: %w", err)
+            return Answer{}, err
+        }
+        return Answer{Text: response, Grounded: true, Documents: documents, Retrieval: retrieval, Usage: usage}, nil
+    }
+
+    answer, err := bot.fallbackAnswer(ctx, question, index.courses(), onToken)
+    answer.Retrieval = retrieval
+    return answer, err
+}
+
+// termNotLoadedMessage explains that a question named a term with no schedule loaded
+func (bot *ChatBot) termNotLoadedMessage(term Term) string {
+    var loaded []string
+    for _, t := range bot.Terms() {
+        if !t.IsZero() {
+            loaded = append(loaded, t.String())
+        }
+    }
+    if len(loaded) == 0 {
+        return fmt.Sprintf("The %s schedule is not loaded, so I can't answer questions about it.", term)
+    }
+    return fmt.Sprintf("The %s schedule is not loaded, so I can't answer questions about it. Loaded terms: %s.", term, strings.Join(loaded, ", "))
+}
+
+// retrieve finds catalog documents for a query in the given collection. If the vector store fails,
+// it logs the error and degrades to lexical search over the loaded courses so the bot keeps working.
+// Vector search looks for the topK closest courses first and returns all their sections, so one course
+// with many sections does not crowd out the rest; collections without course summaries are searched
+// by section. Only sections satisfying the constraint are returned; vector search fetches extra results
+// to make up for the ones it drops. The second result names the retrieval method used. Only
+// cancellation of ctx is returned as an error.
+func (bot *ChatBot) retrieve(ctx context.Context, collection *chroma.Collection, courses []Course, query string, constraint ScheduleConstraint) ([]string, string, error) {
+    nResults := bot.topK
+    if !constraint.Empty() {
+        nResults = bot.topK * constrainedOverfetch
+    }
+    results, err := QueryWhere(ctx, bot.chromaClient, collection, query, nResults, courseLevel)
+    if summaries := flattenDocuments(results); err == nil && len(summaries) > 0 {
+        return expandCourseSummaries(summaries, courses, constraint, bot.topK), RetrievalVector, nil
+    }
+    if err == nil {
+        results, err = Query(ctx, bot.chromaClient, collection, query, nResults)
+    }
+    if err == nil {
+        documents := flattenDocuments(results)
+        if !constraint.Empty() {
+            documents = constraint.filterDocuments(documents, courses, bot.topK)
         }
-        return response, nil
+        return documents, RetrievalVector, nil
+    }
+    if ctx.Err() != nil {
+        return nil, "", ctx.Err()
     }
 
-    // Fallback: general system message
-    systemMessage := "Provide accurate information based on the context of university courses and instructors."
-    response, err := bot.llmClient.ChatCompletion(question, systemMessage)
+    log.Printf("Vector search unavailable, falling back to lexical search: %v", err)
+    if !constraint.Empty() {
+        courses = constraint.FilterRows(courses)
+    }
+    return lexicalSearch(query, courses, bot.topK), RetrievalLexical, nil
+}
+
+// constrainedOverfetch is how many times topK results vector search fetches when a day or time
+// constraint will discard some of them
+const constrainedOverfetch = 10
+
+// complete asks the LLM for a completion, streaming it to onToken when one is given
+func (bot *ChatBot) complete(ctx context.Context, question, systemMessage string, onToken func(string)) (string, Usage, error) {
+    if onToken == nil {
+        response, usage, err := bot.llmClient.ChatCompletionContext(ctx, question, systemMessage)
+        if err != nil {
+            return "", usage, fmt.Errorf("ChatCompletion failed: %w", err)
+        }
+        return response, usage, nil
+    }
+
+    response, usage, err := bot.llmClient.ChatCompletionStream(ctx, question, systemMessage, onToken)
     if err != nil {
-        return "", fmt.Errorf("ChatCompletion failed: %w", err)
+        if ctx.Err() != nil {
+            return "", usage, err
+        }
+        return "", usage, fmt.Errorf("ChatCompletionStream failed: %w", err)
+    }
+    return response, usage, nil
+}
+
+// fallbackAnswer answers a question that matched no catalog documents according to the fallback policy
+func (bot *ChatBot) fallbackAnswer(ctx context.Context, question string, courses []Course, onToken func(string)) (Answer, error) {
+    answer := Answer{Policy: bot.fallback}
+
+    switch bot.fallback {
+    case FallbackSuggest:
+        answer.Text = suggestionMessage(suggestCourses(question, courses, maxSuggestions))
+    case FallbackGeneral:
+        systemMessage := "No university catalog information is available for this question. " +
+            "Answer from general knowledge, do not invent specific course titles, numbers, sections or instructors, " +
+            "and say that the answer is not based on the course catalog."
+        emit(onToken, generalKnowledgeDisclaimer+"\n\n")
+        response, usage, err := bot.complete(ctx, question, systemMessage, onToken)
+        if err != nil {
+            return Answer{}, err
+        }
+        answer.Usage = usage
+        emit(onToken, "\n"+policyNote(FallbackGeneral))
+        answer.Text = generalKnowledgeDisclaimer + "\n\n" + response + "\n" + policyNote(FallbackGeneral)
+        return answer, nil
+    default:
+        answer.Text = refusalMessage()
+    }
+
+    emit(onToken, answer.Text)
+    return answer, nil
+}
+
+// emit passes text to onToken if a callback was given
+func emit(onToken func(string), text string) {
+    if onToken != nil {
+        onToken(text)
+    }
+}
+
+// flattenDocuments joins the per-query document lists returned by Query, skipping empty entries
+func flattenDocuments(results [][]string) []string {
+    var documents []string
+    for _, docs := range results {
+        for _, doc := range docs {
+            if strings.TrimSpace(doc) != "" {
+                documents = append(documents, doc)
+            }
+        }
+    }
+    return documents
+}
+
+// Citation identifies a catalog section an answer was grounded in
+type Citation struct {
+    CRN          string `json:"crn"`
+    Subject      string `json:"subject"`
+    CourseNumber string `json:"course_number"`
+    Section      string `json:"section"`
+    Title        string `json:"title"`
+    Instructor   string `json:"instructor,omitempty"`
+}
+
+// Citations lists the sections behind a grounded answer. Documents that are not course records,
+// such as instructor names, are skipped.
+func (a Answer) Citations() []Citation {
+    citations := []Citation{}
+    for _, doc := range a.Documents {
+        var course Course
+        if err := json.Unmarshal([]byte(doc), &course); err != nil || course.CRN == "" {
+            continue
+        }
+        citations = append(citations, Citation{
+            CRN:          course.CRN,
+            Subject:      course.Subject,
+            CourseNumber: course.CourseNumber,
+            Section:      course.Section,
+            Title:        strings.TrimSpace(course.Title),
+            Instructor:   strings.TrimSpace(course.InstructorFirstName + " " + course.InstructorLastName),
+        })
     }
-    return response, nil
+    return citations
 }
@@ -1,31 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
-	
+
+	openai "github.com/sashabaranov/go-openai"
 )
 
 func RealChatBot() *ChatBot {
-    apiKey := os.Getenv("OPENAI_API_KEY")
-    if apiKey == "" {
+    config := testConfig()
+    if config.APIKey == "" {
         log.Fatal("API key is missing. Please set OPENAI_API_KEY environment variable.")
     }
 
-    llmClient := NewLLMClient(apiKey)
-
-    // Open and parse the CSV file
-    csvFilePath := "Fall 2024 Class Schedule 08082024.csv"
-    csvFile, err := os.Open(csvFilePath)
-    if err != nil {
-        log.Fatalf("Failed to open CSV file: %v", err)
-    }
-    defer csvFile.Close()
+    llmClient := NewLLMClient(config)
 
-    courses, err := ReadCSV(csvFile)
+    // Parse the CSV file
+    courses, _, err := ReadSourceFile(config.CSVPath, "", nil)
     if err != nil {
         log.Fatalf("Failed to read CSV file: %v", err)
     }
@@ -35,14 +33,24 @@ func RealChatBot() *ChatBot {
     metadataExtractor := &MetadataExtractor{courses: courses}
 
     // Add courses and instructors to ChromaDB
-    chromaCtx, chromaClient, courseCollection, instructorCollection := Add(metadataExtractor.courses)
+    chromaCtx, chromaClient, courseCollection, instructorCollection, _, err := Add(config, metadataExtractor.courses)
+    if err != nil {
+        log.Fatalf("Failed to add courses to ChromaDB: %v", err)
+    }
 
     // Return the chatbot
-    return NewChatBot(llmClient, metadataExtractor, chromaCtx, chromaClient, courseCollection, instructorCollection)
+    return NewChatBot(config, llmClient, metadataExtractor, chromaCtx, chromaClient, courseCollection, instructorCollection)
 }
 
 
 
+// testConfig returns the default configuration with the API key taken from the environment
+func testConfig() Config {
+    config := DefaultConfig()
+    config.APIKey = os.Getenv(config.APIKeyEnv)
+    return config
+}
+
 func TestCanonicalName(t *testing.T) {
     instructors := InitializeInstructors()
     name := findCanonicalName("Phil Peterson", instructors)
@@ -113,3 +121,63 @@ func TestMultiple(t *testing.T) {
 		t.Errorf("Expected answer to contain 'Philip Choong', got: %v", answer)
 	}
 }
+
+func TestParseFallbackPolicy(t *testing.T) {
+	for _, policy := range []FallbackPolicy{FallbackRefuse, FallbackSuggest, FallbackGeneral} {
+		parsed, err := ParseFallbackPolicy(policy.String())
+		if err != nil || parsed != policy {
+			t.Errorf("ParseFallbackPolicy(%q) = %v, %v", policy.String(), parsed, err)
+		}
+	}
+	if _, err := ParseFallbackPolicy("guess"); err == nil {
+		t.Errorf("Expected an error for an unknown policy")
+	}
+}
+
+func TestSuggestCourses(t *testing.T) {
+	suggestions := suggestCourses("Are there any activist classes?", testCourses, maxSuggestions)
+	if len(suggestions) != 1 || !strings.Contains(suggestions[0], "Black Activists & Visionaries") {
+		t.Errorf("Expected a suggestion for 'Black Activists & Visionaries', got %v", suggestions)
+	}
+
+	suggestions = suggestCourses("Tell me about BAT", testCourses, maxSuggestions)
+	if len(suggestions) != 1 || !strings.HasPrefix(suggestions[0], "BAT 101") {
+		t.Errorf("Expected a suggestion for BAT 101, got %v", suggestions)
+	}
+
+	if suggestions := suggestCourses("underwater basket weaving", testCourses, maxSuggestions); len(suggestions) != 0 {
+		t.Errorf("Expected no suggestions, got %v", suggestions)
+	}
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, token := range []string{"Philip ", "Peterson ", "teaches CS 272."} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", token)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL + "/v1"
+	llm := &LLMClient{client: openai.NewClientWithConfig(config), model: openai.GPT4oMini}
+
+	var tokens []string
+	response, _, err := llm.ChatCompletionStream(context.Background(), "Who teaches CS 272?", "system", func(token string) {
+		tokens = append(tokens, token)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tokens) != 3 || response != "Philip Peterson teaches CS 272." {
+		t.Errorf("Expected 3 tokens forming the full answer, got %q from %q", response, tokens)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := llm.ChatCompletionStream(ctx, "Who teaches CS 272?", "system", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
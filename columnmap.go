@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// canonicalField is a Course field a column mapping can fill, with the
+// header the standard export uses for it.
+type canonicalField struct {
+	name   string
+	header string
+	field  func(*Course) *string
+}
+
+// canonicalFields lists the Course fields by their mapping names.
+var canonicalFields = []canonicalField{
+	{"subject", "SUBJ", func(c *Course) *string { return &c.Subject }},
+	{"course_number", "CRSE NUM", func(c *Course) *string { return &c.CourseNumber }},
+	{"section", "SEC", func(c *Course) *string { return &c.Section }},
+	{"crn", "CRN", func(c *Course) *string { return &c.CRN }},
+	{"schedule_type", "Schedule Type Code", func(c *Course) *string { return &c.ScheduleTypeCode }},
+	{"campus", "Campus Code", func(c *Course) *string { return &c.CampusCode }},
+	{"title", "Title Short Desc", func(c *Course) *string { return &c.Title }},
+	{"instruction_mode", "Instruction Mode Desc", func(c *Course) *string { return &c.InstructionModeDesc }},
+	{"meeting_types", "Meeting Type Codes", func(c *Course) *string { return &c.MeetingTypeCodes }},
+	{"meet_days", "Meet Days", func(c *Course) *string { return &c.MeetDays }},
+	{"begin_time", "Begin Time", func(c *Course) *string { return &c.BeginTime }},
+	{"end_time", "End Time", func(c *Course) *string { return &c.EndTime }},
+	{"meet_start", "Meet Start", func(c *Course) *string { return &c.MeetStart }},
+	{"meet_end", "Meet End", func(c *Course) *string { return &c.MeetEnd }},
+	{"building", "BLDG", func(c *Course) *string { return &c.Building }},
+	{"room", "RM", func(c *Course) *string { return &c.Room }},
+	{"enrollment", "Actual Enrollment", func(c *Course) *string { return &c.ActualEnrollment }},
+	{"instructor_first_name", "Primary Instructor First Name", func(c *Course) *string { return &c.InstructorFirstName }},
+	{"instructor_last_name", "Primary Instructor Last Name", func(c *Course) *string { return &c.InstructorLastName }},
+	{"instructor_email", "Primary Instructor Email", func(c *Course) *string { return &c.InstructorEmail }},
+	{"college", "College", func(c *Course) *string { return &c.College }},
+}
+
+// lookupCanonicalField returns the Course field with a mapping name, or nil
+// if the name is an extra attribute.
+func lookupCanonicalField(name string) *canonicalField {
+	for i := range canonicalFields {
+		if canonicalFields[i].name == name {
+			return &canonicalFields[i]
+		}
+	}
+	return nil
+}
+
+// ColumnMapping describes how a registrar export's columns become section
+// records. Fields are keyed by canonical field name ("subject", "crn", ...)
+// or, for anything else, by the name of an extra attribute such as
+// "capacity". Source columns no field uses are kept as extra attributes
+// under their header unless DropUnmapped is set.
+type ColumnMapping struct {
+	Delimiter    string                  `json:"delimiter"` // One character; default ",".
+	Fields       map[string]FieldMapping `json:"fields"`
+	DropUnmapped bool                    `json:"drop_unmapped"`
+}
+
+// FieldMapping fills one field from one or more source columns. Values of
+// several columns are joined with Separator, then each transform is applied
+// in order, then Values replaces whole values, e.g. {"P": "In-Person"}.
+// Default is used when the result is empty.
+type FieldMapping struct {
+	Columns    []string          `json:"columns"`
+	Separator  string            `json:"separator"` // Default " ".
+	Transforms []string          `json:"transforms"`
+	Values     map[string]string `json:"values"`
+	Default    string            `json:"default"`
+}
+
+// UnmarshalJSON accepts either a column header, as in "subject": "Subject Code",
+// or an object with "column" or "columns" and the other options.
+func (f *FieldMapping) UnmarshalJSON(data []byte) error {
+	var column string
+	if err := json.Unmarshal(data, &column); err == nil {
+		*f = FieldMapping{Columns: []string{column}}
+		return nil
+	}
+	type plain FieldMapping
+	var object struct {
+		plain
+		Column string `json:"column"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	*f = FieldMapping(object.plain)
+	if object.Column != "" {
+		f.Columns = append([]string{object.Column}, f.Columns...)
+	}
+	return nil
+}
+
+// valueTransforms are the transforms a FieldMapping may name.
+var valueTransforms = map[string]func(string) string{
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"title":      titleCase,
+	"days":       dayLetters,
+	"time":       clockTime,
+	"date":       meetDate,
+	"first_name": firstName,
+	"last_name":  lastName,
+}
+
+// DefaultColumnMapping maps the standard export's headers, which the Course
+// csv tags also name.
+func DefaultColumnMapping() *ColumnMapping {
+	mapping := &ColumnMapping{Fields: make(map[string]FieldMapping, len(canonicalFields))}
+	for _, field := range canonicalFields {
+		mapping.Fields[field.name] = FieldMapping{Columns: []string{field.header}}
+	}
+	return mapping
+}
+
+// LoadColumnMapping reads and checks a JSON column-mapping file.
+func LoadColumnMapping(path string) (*ColumnMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading column mapping: %w", err)
+	}
+	var mapping ColumnMapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("parsing column mapping %s: %w", path, err)
+	}
+	if err := mapping.Validate(); err != nil {
+		return nil, fmt.Errorf("column mapping %s: %w", path, err)
+	}
+	return &mapping, nil
+}
+
+// Validate reports every problem with the mapping at once.
+func (m *ColumnMapping) Validate() error {
+	var problems []error
+	if m.Delimiter != "" && utf8.RuneCountInString(m.Delimiter) != 1 {
+		problems = append(problems, fmt.Errorf("delimiter %q must be one character", m.Delimiter))
+	}
+	if len(m.Fields) == 0 {
+		problems = append(problems, errors.New("no fields are mapped"))
+	}
+	for _, name := range sortedFieldNames(m.Fields) {
+		field := m.Fields[name]
+		if len(field.Columns) == 0 {
+			problems = append(problems, fmt.Errorf("field %s names no columns", name))
+		}
+		for _, transform := range field.Transforms {
+			if _, ok := valueTransforms[transform]; !ok {
+				problems = append(problems, fmt.Errorf("field %s has unknown transform %q", name, transform))
+			}
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// delimiter returns the mapping's field delimiter.
+func (m *ColumnMapping) delimiter() rune {
+	if m.Delimiter == "" {
+		return ','
+	}
+	r, _ := utf8.DecodeRuneInString(m.Delimiter)
+	return r
+}
+
+// boundField is a FieldMapping resolved against a header row.
+type boundField struct {
+	name      string
+	canonical *canonicalField
+	columns   []int
+	mapping   FieldMapping
+}
+
+// columnBinding is a ColumnMapping resolved against a header row.
+type columnBinding struct {
+	fields []boundField
+	extras map[int]string // Column index to header, for columns kept as extra attributes.
+}
+
+// bind resolves the mapping's columns against a header row, matching
+// headers case-insensitively. Every mapped column must be present.
+func (m *ColumnMapping) bind(header []string) (*columnBinding, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := positions[name]; !ok {
+			positions[name] = i
+		}
+	}
+
+	binding := &columnBinding{extras: make(map[int]string)}
+	used := make(map[int]bool)
+	var problems []error
+	for _, name := range sortedFieldNames(m.Fields) {
+		field := boundField{name: name, canonical: lookupCanonicalField(name), mapping: m.Fields[name]}
+		for _, column := range field.mapping.Columns {
+			i, ok := positions[strings.ToLower(strings.TrimSpace(column))]
+			if !ok {
+				problems = append(problems, fmt.Errorf("column %q for field %s is not in the header", column, name))
+				continue
+			}
+			field.columns = append(field.columns, i)
+			used[i] = true
+		}
+		binding.fields = append(binding.fields, field)
+	}
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+
+	if !m.DropUnmapped {
+		for i, name := range header {
+			if name = strings.TrimSpace(name); name != "" && !used[i] {
+				binding.extras[i] = name
+			}
+		}
+	}
+	return binding, nil
+}
+
+// course builds a section record from one data row.
+func (b *columnBinding) course(record []string) Course {
+	var course Course
+	for _, field := range b.fields {
+		value := field.value(record)
+		if field.canonical != nil {
+			*field.canonical.field(&course) = value
+		} else if value != "" {
+			setExtra(&course, field.name, value)
+		}
+	}
+	for i, name := range b.extras {
+		if i < len(record) && strings.TrimSpace(record[i]) != "" {
+			setExtra(&course, name, strings.TrimSpace(record[i]))
+		}
+	}
+	return course
+}
+
+// value computes the field's value from a data row.
+func (f boundField) value(record []string) string {
+	var parts []string
+	for _, i := range f.columns {
+		if i < len(record) {
+			if part := strings.TrimSpace(record[i]); part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+	separator := f.mapping.Separator
+	if separator == "" {
+		separator = " "
+	}
+	value := strings.Join(parts, separator)
+	for _, transform := range f.mapping.Transforms {
+		value = strings.TrimSpace(valueTransforms[transform](value))
+	}
+	if replacement, ok := f.mapping.Values[value]; ok {
+		value = replacement
+	}
+	if value == "" {
+		value = f.mapping.Default
+	}
+	return value
+}
+
+func setExtra(course *Course, name, value string) {
+	if course.Extra == nil {
+		course.Extra = make(map[string]string)
+	}
+	course.Extra[name] = value
+}
+
+// ReadMappedCSV reads delimited schedule rows through a column mapping.
+// The first row is the header; blank rows are skipped.
+// See ReadSource for other formats.
+func ReadMappedCSV(r io.Reader, mapping *ColumnMapping) ([]Course, []string, error) {
+	return ReadSource(r, delimitedReader{format: FormatCSV, comma: mapping.delimiter()}, mapping)
+}
+
+func sortedFieldNames(fields map[string]FieldMapping) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// titleCase capitalizes the first letter of each word and lowercases the rest.
+func titleCase(s string) string {
+	words := strings.Fields(strings.ToLower(s))
+	for i, word := range words {
+		r, size := utf8.DecodeRuneInString(word)
+		words[i] = strings.ToUpper(string(r)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// dayNames maps day names and abbreviations to schedule day letters,
+// longer names first so "thursday" is not read as "t" and "h".
+var dayNames = strings.NewReplacer(
+	"monday", "M", "tuesday", "T", "wednesday", "W", "thursday", "R", "friday", "F", "saturday", "S", "sunday", "U",
+	"thurs", "R", "tues", "T",
+	"mon", "M", "tue", "T", "wed", "W", "thu", "R", "fri", "F", "sat", "S", "sun", "U",
+	"th", "R", "tu", "T", "sa", "S", "su", "U",
+)
+
+// dayLetters converts days such as "Mon/Wed", "TuTh" or "T Th" to the
+// schedule's day letters, e.g. "MW" and "TR".
+func dayLetters(s string) string {
+	return normalizeDays(dayNames.Replace(strings.ToLower(s)))
+}
+
+// clockTime converts a time such as "1:30 PM" or "13:30" to the schedule's
+// "HHMM" form. Values that are not times are returned unchanged.
+func clockTime(s string) string {
+	if _, ok := parseClock(s); ok {
+		return s
+	}
+	minutes, err := parseTimeOfDay(s)
+	if err != nil {
+		return s
+	}
+	return fmt.Sprintf("%02d%02d", minutes/60, minutes%60)
+}
+
+// meetDate converts a date such as "2024-08-26" or "08/26/2024" to the
+// schedule's "8/26/24" form. Values that are not dates are returned unchanged.
+func meetDate(s string) string {
+	for _, layout := range []string{"1/2/06", "2006-01-02", "1/2/2006", "Jan 2, 2006", "2-Jan-2006"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t.Format("1/2/06")
+		}
+	}
+	return s
+}
+
+// splitName splits "Last, First" or "First Last" into first and last names.
+func splitName(s string) (string, string) {
+	if last, first, ok := strings.Cut(s, ","); ok {
+		return strings.TrimSpace(first), strings.TrimSpace(last)
+	}
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, ' '); i >= 0 {
+		return strings.TrimSpace(s[:i]), s[i+1:]
+	}
+	return "", s
+}
+
+func firstName(s string) string {
+	first, _ := splitName(s)
+	return first
+}
+
+func lastName(s string) string {
+	_, last := splitName(s)
+	return last
+}
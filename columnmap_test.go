@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// partnerExport is a schedule in a partner campus's layout, with extra columns.
+const partnerExport = "\ufeffTerm;Subject Code;Catalog Nbr;Class Section;Class Nbr;Course Title;Mode;Days;Start;End;Start Date;End Date;Facility;Instructor;Secondary Instructor;Enrl Cap;Enrl Tot;Units\n" +
+	"2251;cs;272;01;91001;SOFTWARE DEVELOPMENT;P;Mon/Wed;1:30 PM;3:15 PM;2025-01-21;2025-05-09;HR 235;Peterson, Philip;Benson, Gregory;40;38;4\n" +
+	"2251;math;201;02;91002;DISCRETE MATHEMATICS;OA;;;;2025-01-21;2025-05-09;;Doe, Jane;;35;12;4\n" +
+	";;;;;;;;;;;;;;;;;\n"
+
+const partnerMapping = `{
+	"delimiter": ";",
+	"fields": {
+		"subject": {"column": "Subject Code", "transforms": ["upper"]},
+		"course_number": "Catalog Nbr",
+		"section": "Class Section",
+		"crn": "Class Nbr",
+		"title": {"column": "Course Title", "transforms": ["title"]},
+		"instruction_mode": {"column": "Mode", "values": {"P": "In-Person", "OA": "Online Asynchronous"}},
+		"meet_days": {"column": "Days", "transforms": ["days"]},
+		"begin_time": {"column": "Start", "transforms": ["time"]},
+		"end_time": {"column": "End", "transforms": ["time"]},
+		"meet_start": {"column": "Start Date", "transforms": ["date"]},
+		"meet_end": {"column": "End Date", "transforms": ["date"]},
+		"building": {"column": "Facility", "transforms": ["first_name"], "default": "TBA"},
+		"room": {"column": "Facility", "transforms": ["last_name"]},
+		"enrollment": "Enrl Tot",
+		"instructor_first_name": {"column": "Instructor", "transforms": ["first_name"]},
+		"instructor_last_name": {"column": "Instructor", "transforms": ["last_name"]},
+		"capacity": "Enrl Cap",
+		"course_code": {"columns": ["Subject Code", "Catalog Nbr"], "separator": "-", "transforms": ["upper"]}
+	}
+}`
+
+func readPartnerExport(t *testing.T) []Course {
+	t.Helper()
+	var mapping ColumnMapping
+	if err := json.Unmarshal([]byte(partnerMapping), &mapping); err != nil {
+		t.Fatal(err)
+	}
+	if err := mapping.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	courses, header, err := ReadMappedCSV(strings.NewReader(partnerExport), &mapping)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if header[0] != "Term" {
+		t.Errorf("Expected the byte order mark stripped from the header, got %q", header[0])
+	}
+	if len(courses) != 2 {
+		t.Fatalf("Expected 2 sections with the blank row skipped, got %d", len(courses))
+	}
+	return courses
+}
+
+func TestReadMappedCSV(t *testing.T) {
+	courses := readPartnerExport(t)
+	cs := courses[0]
+	want := Course{
+		Subject: "CS", CourseNumber: "272", Section: "01", CRN: "91001", Title: "Software Development",
+		InstructionModeDesc: "In-Person", MeetDays: "MW", BeginTime: "1330", EndTime: "1515",
+		MeetStart: "1/21/25", MeetEnd: "5/9/25", Building: "HR", Room: "235", ActualEnrollment: "38",
+		InstructorFirstName: "Philip", InstructorLastName: "Peterson",
+	}
+	extra := cs.Extra
+	cs.Extra = nil
+	if !reflect.DeepEqual(cs, want) {
+		t.Errorf("Expected %+v, got %+v", want, cs)
+	}
+	if extra["capacity"] != "40" || extra["course_code"] != "CS-272" {
+		t.Errorf("Expected mapped extra attributes, got %v", extra)
+	}
+	if extra["Secondary Instructor"] != "Benson, Gregory" || extra["Units"] != "4" || extra["Term"] != "2251" {
+		t.Errorf("Expected unmapped columns kept as extras, got %v", extra)
+	}
+	if _, ok := extra["Enrl Tot"]; ok {
+		t.Error("Expected mapped columns not to be repeated as extras")
+	}
+
+	math := courses[1]
+	if math.InstructionModeDesc != "Online Asynchronous" || math.Building != "TBA" || math.MeetDays != "" || math.BeginTime != "" {
+		t.Errorf("Expected an online section with defaults applied, got %+v", math)
+	}
+	if _, ok := parseMeeting(cs); !ok {
+		t.Error("Expected the mapped times and dates to parse as a meeting")
+	}
+}
+
+func TestColumnMappingErrors(t *testing.T) {
+	var mapping ColumnMapping
+	if err := json.Unmarshal([]byte(`{"fields": {"subject": {"column": "Subj", "transforms": ["reverse"]}, "crn": {}}}`), &mapping); err != nil {
+		t.Fatal(err)
+	}
+	err := mapping.Validate()
+	if err == nil || !strings.Contains(err.Error(), `unknown transform "reverse"`) || !strings.Contains(err.Error(), "field crn names no columns") {
+		t.Errorf("Expected both problems reported, got %v", err)
+	}
+
+	mapping = ColumnMapping{Fields: map[string]FieldMapping{"subject": {Columns: []string{"Subj"}}, "crn": {Columns: []string{"Class Nbr"}}}}
+	_, _, err = ReadMappedCSV(strings.NewReader("Subject,CRN\nCS,1\n"), &mapping)
+	if err == nil || !strings.Contains(err.Error(), `column "Class Nbr" for field crn`) || !strings.Contains(err.Error(), `column "Subj" for field subject`) {
+		t.Errorf("Expected missing columns reported, got %v", err)
+	}
+
+	if _, _, err := ReadMappedCSV(strings.NewReader("SUBJ,CRN\nCS,1\n"), DefaultColumnMapping()); err == nil {
+		t.Error("Expected the standard mapping to need every standard column")
+	}
+}
+
+func TestDefaultColumnMapping(t *testing.T) {
+	file, err := os.Open("Fall 2024 Class Schedule 08082024.csv")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer file.Close()
+	courses, _, err := ReadMappedCSV(file, DefaultColumnMapping())
+	if err != nil {
+		t.Fatalf("Expected the standard export to load, got %v", err)
+	}
+	first := courses[0]
+	if first.Subject != "AAS" || first.CRN != "42180" || first.InstructorLastName != "Davis" || first.College != "LA" || first.Extra != nil {
+		t.Errorf("Expected the first AAS 100 row with no extras, got %+v", first)
+	}
+}
+
+func TestValueTransforms(t *testing.T) {
+	for input, want := range map[string]string{"TuTh": "TR", "T Th": "TR", "Mon/Wed/Fri": "MWF", "MWF": "MWF", "Saturday": "S", "thursday": "R"} {
+		if got := dayLetters(input); got != want {
+			t.Errorf("dayLetters(%q) = %q, want %q", input, got, want)
+		}
+	}
+	for input, want := range map[string]string{"1:30 PM": "1330", "09:05": "0905", "0945": "0945", "TBA": "TBA"} {
+		if got := clockTime(input); got != want {
+			t.Errorf("clockTime(%q) = %q, want %q", input, got, want)
+		}
+	}
+	if first, last := splitName("Van Loggerenberg, Marthinus"); first != "Marthinus" || last != "Van Loggerenberg" {
+		t.Errorf("Expected last name before the comma, got %q %q", first, last)
+	}
+}
+
+func TestServerExtraFilter(t *testing.T) {
+	courses := readPartnerExport(t)
+	bot := NewChatBot(DefaultConfig(), nil, &MetadataExtractor{courses: courses}, nil, nil, nil, nil)
+	server := NewServer(DefaultConfig(), bot, NewSessionManager(NewMemorySessionStore(), time.Hour))
+	sections := getJSON(t, server, http.MethodGet, "/sections?extra.capacity=35", "", http.StatusOK)
+	if sections["total"] != float64(1) {
+		t.Errorf("Expected one section with capacity 35, got %v", sections["total"])
+	}
+	if got := lexicalSearch("Benson", courses, 5); len(got) != 1 || !strings.Contains(got[0], "91001") {
+		t.Errorf("Expected the secondary instructor column to be searchable, got %v", got)
+	}
+}
@@ -0,0 +1,878 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Exit codes shared by all subcommands.
+const (
+	exitOK          = 0
+	exitFailure     = 1 // The command ran but failed.
+	exitUsage       = 2 // Bad flags, arguments or configuration.
+	exitUnavailable = 3 // ChromaDB could not be reached.
+	exitNotFound    = 4 // The requested collection or section does not exist.
+)
+
+// command is a subcommand of the catalog binary.
+type command struct {
+	name    string
+	args    string // Positional arguments shown in usage.
+	summary string
+	run     func(fs *flag.FlagSet, args []string) int
+}
+
+// commands lists the subcommands in the order usage shows them.
+var commands = []command{
+	{"ingest", "[FILE]", "load a schedule file into the course and instructor collections", runIngest},
+	{"ask", "QUESTION...", "answer one question and exit", runAsk},
+	{"repl", "", "answer questions interactively", runRepl},
+	{"serve", "", "serve the HTTP JSON API", runServe},
+	{"inspect", "", "show collection stats and look up a CRN", runInspect},
+	{"plan", "\"SUBJ NUM[@INSTRUCTOR,...]\"...", "list conflict-free schedules for the given courses", runPlan},
+	{"calendar", "CRN...", "export sections as an iCalendar (.ics) file", runCalendar},
+	{"rooms", "[BUILDING [ROOM]]", "show room schedules, free rooms and double bookings", runRooms},
+	{"report", "", "write per-instructor teaching-load reports", runReport},
+	{"stats", "", "show enrollment statistics by subject, college, mode and more", runStats},
+	{"diff", "OLD NEW", "compare two schedule snapshots by CRN", runDiff},
+	{"lint", "[FILE]", "check a schedule file for data-quality problems", runLint},
+}
+
+// newFlagSet creates the flag set for a subcommand with a usage message that
+// lists its flags, including the shared configuration flags.
+func newFlagSet(cmd command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags] %s\n\nFlags:\n", os.Args[0], cmd.name, cmd.args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// loadCommandConfig parses a subcommand's flags and loads its configuration.
+// It returns a non-negative exit code when the command should stop.
+func loadCommandConfig(fs *flag.FlagSet, args []string) (Config, int) {
+	config, err := LoadConfig(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return Config{}, exitOK
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return Config{}, exitUsage
+	}
+	return config, -1
+}
+
+// storeExitCode maps a vector store error to an exit code.
+func storeExitCode(err error) int {
+	switch {
+	case errors.Is(err, ErrConnection):
+		return exitUnavailable
+	case errors.Is(err, ErrCollectionMissing):
+		return exitNotFound
+	}
+	return exitFailure
+}
+
+// newChatBot loads the schedule of every configured term and opens each
+// term's existing collections. If the vector store is unavailable, the bot
+// still works using lexical search.
+func newChatBot(config Config) (*ChatBot, error) {
+	llmClient := NewLLMClient(config)
+	files, err := config.termFiles()
+	if err != nil {
+		return nil, err
+	}
+	defaultTerm, err := config.defaultTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	loaded, err := loadTerms(config, files, llmClient)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to initialize MetadataExtractor: %w", err)
+	}
+
+	var bot *ChatBot
+	for i, file := range files {
+		single, err := config.ForTerm(file.term)
+		if err != nil {
+			return nil, err
+		}
+		metadataExtractor := loaded[i]
+
+		chromaCtx, chromaClient, courseCollection, instructorCollection, err := OpenCollections(single, false)
+		if err != nil {
+			log.Printf("Vector store unavailable for %s, answers will use lexical search: %v", single.CSVPath, err)
+			chromaCtx = context.Background()
+		}
+
+		if bot == nil {
+			bot = NewChatBot(single, llmClient, metadataExtractor, chromaCtx, chromaClient, courseCollection, instructorCollection)
+			// A configured term's file name need not name the term.
+			bot.defaultTerm.term = file.term
+			continue
+		}
+		bot.AddTerm(file.term, metadataExtractor, courseCollection, instructorCollection)
+	}
+	if err := bot.SetDefaultTerm(defaultTerm); err != nil {
+		return nil, err
+	}
+	return bot, nil
+}
+
+// runIngest loads a schedule file into the configured collections. A course
+// collection that already has documents is not loaded again: that is reported
+// as a failure unless -replace clears the collections first.
+func runIngest(fs *flag.FlagSet, args []string) int {
+	replace := fs.Bool("replace", false, "delete the collections' existing documents and load the file afresh")
+	config, code := loadCommandConfig(fs, args)
+	if code >= 0 {
+		return code
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	if err := config.RequireAPIKey(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	// A file on the command line replaces the selected term's schedule;
+	// otherwise every configured term is ingested into its own collections.
+	var terms []termFile
+	if fs.NArg() == 1 && len(config.Terms) == 0 {
+		term, _ := termFromFileName(fs.Arg(0))
+		terms = []termFile{{term: term, path: fs.Arg(0)}}
+	} else if fs.NArg() == 1 {
+		term, err := config.defaultTerm()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		terms = []termFile{{term: term, path: fs.Arg(0)}}
+	} else {
+		var err error
+		if terms, err = config.termFiles(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+	}
+
+	llmClient := NewLLMClient(config)
+	for _, file := range terms {
+		single, err := config.ForTerm(file.term)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		if fs.NArg() == 1 {
+			// The file's own extension, or -format, says how to read it.
+			single.CSVPath, single.Format = file.path, config.Format
+		}
+
+		metadataExtractor, err := loadTermMetadata(single, file.term, llmClient)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load %s: %v\n", single.CSVPath, err)
+			return exitFailure
+		}
+
+		if *replace {
+			if err := DropCollections(single); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to clear %s and %s: %v\n", single.CourseCollection, single.InstructorCollection, err)
+				return storeExitCode(err)
+			}
+		}
+		_, _, _, _, loaded, err := Add(single, metadataExtractor.courses)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to ingest %s: %v\n", single.CSVPath, err)
+			return storeExitCode(err)
+		}
+		if !loaded {
+			fmt.Fprintf(os.Stderr, "%s is already populated, so nothing was ingested from %s; use -replace to reload it.\n",
+				single.CourseCollection, single.CSVPath)
+			return exitFailure
+		}
+
+		fmt.Printf("Ingested %d sections from %s into %s and %s.\n",
+			len(metadataExtractor.courses), single.CSVPath, single.CourseCollection, single.InstructorCollection)
+	}
+	return exitOK
+}
+
+// runAsk answers the question given on the command line.
+func runAsk(fs *flag.FlagSet, args []string) int {
+	asJSON := fs.Bool("json", false, "print the answer and its sources as JSON")
+	config, code := loadCommandConfig(fs, args)
+	if code >= 0 {
+		return code
+	}
+	question := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if question == "" {
+		fs.Usage()
+		return exitUsage
+	}
+	if err := config.RequireAPIKey(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	bot, err := newChatBot(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	answer, err := bot.Ask(question)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error processing your question: %v\n", err)
+		return exitFailure
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(answer); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		return exitOK
+	}
+	fmt.Println(answer.Text)
+	return exitOK
+}
+
+// runRepl answers questions read from standard input until EOF.
+func runRepl(fs *flag.FlagSet, args []string) int {
+	config, code := loadCommandConfig(fs, args)
+	if code >= 0 {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if err := config.RequireAPIKey(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	bot, err := newChatBot(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	sessions := NewSessionManager(NewMemorySessionStore(), time.Duration(config.SessionTTL))
+
+	fmt.Println("Entering interactive mode. Type your questions below:")
+	if err := runInteractiveMode(bot, sessions); err != nil {
+		log.Println("Error reading input:", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// runServe serves the HTTP API until interrupted.
+func runServe(fs *flag.FlagSet, args []string) int {
+	addr := fs.String("addr", ":8080", "address to listen on")
+	config, code := loadCommandConfig(fs, args)
+	if code >= 0 {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if err := config.RequireAPIKey(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	bot, err := newChatBot(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	store, err := NewSessionStore(config.SessionDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	sessions := NewSessionManager(store, time.Duration(config.SessionTTL))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go sessions.RunJanitor(ctx, time.Minute)
+	if err := NewServer(config, bot, sessions).ListenAndServe(ctx, *addr); err != nil {
+		fmt.Fprintf(os.Stderr, "Server failed: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// runInspect prints document counts for the configured collections and,
+// with -crn, the stored documents for that section.
+func runInspect(fs *flag.FlagSet, args []string) int {
+	crn := fs.String("crn", "", "look up the section with this CRN")
+	config, code := loadCommandConfig(fs, args)
+	if code >= 0 {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	config, _, err := config.Selected()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	ctx, _, courseCollection, instructorCollection, err := OpenCollections(config, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return storeExitCode(err)
+	}
+
+	for _, collection := range []struct {
+		name  string
+		count func(context.Context) (int32, error)
+	}{
+		{courseCollection.Name, courseCollection.Count},
+		{instructorCollection.Name, instructorCollection.Count},
+	} {
+		count, err := collection.count(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to count %s: %v\n", collection.name, err)
+			return storeExitCode(classifyStoreError(ctx, nil, "count "+collection.name, ErrQueryFailed, err))
+		}
+		fmt.Printf("%-30s %d documents\n", collection.name, count)
+	}
+
+	if *crn == "" {
+		return exitOK
+	}
+
+	// Course documents are the JSON encoding of Course, so the CRN appears as "CRN":"<crn>".
+	contains := map[string]interface{}{"$contains": fmt.Sprintf(`"CRN":%q`, *crn)}
+	results, err := courseCollection.Get(ctx, nil, contains, nil, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to look up CRN %s: %v\n", *crn, err)
+		return exitFailure
+	}
+	if len(results.Documents) == 0 {
+		fmt.Fprintf(os.Stderr, "No section with CRN %s in %s.\n", *crn, courseCollection.Name)
+		return exitNotFound
+	}
+
+	fmt.Printf("\nCRN %s (%d documents):\n", *crn, len(results.Documents))
+	for i, doc := range results.Documents {
+		fmt.Printf("- id %s: %s\n", results.Ids[i], doc)
+	}
+	return exitOK
+}
+
+// runPlan lists conflict-free section combinations for the requested courses,
+// best fit to the preference flags first. It needs neither ChromaDB nor an API key.
+func runPlan(fs *flag.FlagSet, args []string) int {
+	notBefore := fs.String("not-before", "", "prefer classes starting at or after this time, e.g. 10am")
+	daysOff := fs.String("days-off", "", "prefer no classes on these days, e.g. F")
+	maxDays := fs.Int("max-days", 0, "prefer at most this many campus days (0 for no preference)")
+	limit := fs.Int("limit", 5, "show at most this many schedules")
+	asJSON := fs.Bool("json", false, "print the schedules as JSON")
+	icsPath := fs.String("ics", "", "also write the best schedule as an iCalendar file")
+	config, code := loadCommandConfig(fs, args)
+	if code >= 0 {
+		return code
+	}
+	if fs.NArg() == 0 || *limit < 1 || *maxDays < 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	var requests []CourseRequest
+	for _, arg := range fs.Args() {
+		request, err := ParseCourseRequest(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		requests = append(requests, request)
+	}
+	prefs := Preferences{DaysOff: normalizeDays(*daysOff), MaxCampusDays: *maxDays}
+	if *notBefore != "" {
+		minutes, err := parseTimeOfDay(*notBefore)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		prefs.NotBefore = minutes
+	}
+
+	courses, err := loadDefaultCourses(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	schedules, err := PlanSchedules(courses, requests, prefs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	total := len(schedules)
+	if len(schedules) > *limit {
+		schedules = schedules[:*limit]
+	}
+	if *icsPath != "" && total > 0 {
+		if err := writeCalendarFile(*icsPath, schedules[0].Sections); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(map[string]interface{}{"total": total, "schedules": schedules}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		return exitOK
+	}
+	if total == 0 {
+		fmt.Println("Every combination of those courses has a time conflict.")
+		return exitOK
+	}
+	fmt.Printf("%d conflict-free schedules; showing the best %d.\n\n", total, len(schedules))
+	fmt.Print(formatSchedules(schedules))
+	return exitOK
+}
+
+// runCalendar writes the sections with the given CRNs as an iCalendar file.
+func runCalendar(fs *flag.FlagSet, args []string) int {
+	output := fs.String("o", "", "write the calendar to this file instead of standard output")
+	config, code := loadCommandConfig(fs, args)
+	if code >= 0 {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	courses, err := loadDefaultCourses(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	sections, missing := sectionsByCRN(courses, fs.Args())
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "No section with CRN %s.\n", strings.Join(missing, ", "))
+		return exitNotFound
+	}
+
+	if *output != "" {
+		if err := writeCalendarFile(*output, sections); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		return exitOK
+	}
+	if _, err := WriteICalendar(os.Stdout, sections, time.Now()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// writeCalendarFile writes the sections as an iCalendar file at path.
+func writeCalendarFile(path string, sections []ClassSection) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create calendar: %w", err)
+	}
+	events, err := WriteICalendar(file, sections, time.Now())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("write calendar %s: %w", path, err)
+	}
+	log.Printf("Wrote %d calendar events to %s", events, path)
+	return nil
+}
+
+// runRooms answers room questions from the timetable. With BUILDING and ROOM
+// it prints the room's weekly schedule, or whether it is free when -days and
+// -from are given; with only BUILDING it lists the rooms free in that window.
+func runRooms(fs *flag.FlagSet, args []string) int {
+	days := fs.String("days", "", "day letters of the time window, e.g. T")
+	from := fs.String("from", "", "start of the time window, e.g. 2pm")
+	to := fs.String("to", "", "end of the time window (default: the minute at -from)")
+	doubleBooked := fs.Bool("double-booked", false, "list sections booked into the same room at overlapping times")
+	unplaced := fs.Bool("unplaced", false, "list online and TBA rows that have no room")
+	config, code := loadCommandConfig(fs, args)
+	if code >= 0 {
+		return code
+	}
+	windowed := *days != "" || *from != "" || *to != ""
+	switch {
+	case *doubleBooked || *unplaced:
+		if fs.NArg() > 0 || windowed {
+			fs.Usage()
+			return exitUsage
+		}
+	case fs.NArg() == 0 || fs.NArg() > 2 || (fs.NArg() == 1 && !windowed):
+		fs.Usage()
+		return exitUsage
+	}
+
+	var start, end int
+	if windowed {
+		var err error
+		*days, start, end, err = timeWindow(url.Values{"days": {*days}, "from": {*from}, "to": {*to}})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+	}
+
+	courses, err := loadDefaultCourses(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	index := NewRoomIndex(courses)
+
+	switch {
+	case *doubleBooked:
+		for _, booking := range index.DoubleBookings() {
+			note := ""
+			if booking.CrossListed {
+				note = " (likely cross-listed)"
+			}
+			fmt.Printf("%s: %s and %s%s\n", booking.Room, describeBooking(booking.First), describeBooking(booking.Second), note)
+		}
+	case *unplaced:
+		for _, row := range index.Unplaced {
+			fmt.Printf("%-9s CRN %s %s %s-%s (%s)\n", row.Reason, row.CRN, row.Subject, row.CourseNumber, row.Section, row.Mode)
+		}
+	case fs.NArg() == 1:
+		building := fs.Arg(0)
+		if len(index.Rooms(building)) == 0 {
+			fmt.Fprintf(os.Stderr, "No rooms in building %s.\n", building)
+			return exitNotFound
+		}
+		free := index.FreeRooms(building, *days, start, end)
+		fmt.Printf("%d rooms in %s are free %s %s-%s:\n", len(free), strings.ToUpper(building), *days, formatClock(start), formatClock(end))
+		for _, room := range free {
+			fmt.Printf("  %s\n", room)
+		}
+	default:
+		building, room := fs.Arg(0), fs.Arg(1)
+		bookings, ok := index.Schedule(building, room)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "No sections are scheduled in %s.\n", newRoomKey(building, room))
+			return exitNotFound
+		}
+		if windowed {
+			occupants := index.Occupants(building, room, *days, start, end)
+			if len(occupants) == 0 {
+				fmt.Printf("%s is free %s %s-%s.\n", newRoomKey(building, room), *days, formatClock(start), formatClock(end))
+				return exitOK
+			}
+			bookings = occupants
+			fmt.Printf("%s is in use %s %s-%s:\n", newRoomKey(building, room), *days, formatClock(start), formatClock(end))
+		}
+		for _, booking := range bookings {
+			fmt.Printf("  %-28s %s\n", booking.Meeting, describeBooking(booking))
+		}
+	}
+	return exitOK
+}
+
+// describeBooking names the section holding a room booking.
+func describeBooking(booking RoomBooking) string {
+	s := fmt.Sprintf("%s %s-%s CRN %s", booking.Subject, booking.CourseNumber, booking.Section, booking.CRN)
+	if booking.Instructor != "" {
+		s += " (" + booking.Instructor + ")"
+	}
+	return s
+}
+
+// runReport writes the per-instructor teaching-load report.
+func runReport(fs *flag.FlagSet, args []string) int {
+	formatName := fs.String("format", ReportMarkdown, "output format: csv, markdown or json")
+	instructor := fs.String("instructor", "", "only report instructors whose name contains this text")
+	output := fs.String("o", "", "write the report to this file instead of standard output")
+	config, code := loadCommandConfig(fs, args)
+	if code >= 0 {
+		return code
+	}
+	format, err := ParseReportFormat(*formatName)
+	if err != nil || fs.NArg() > 0 {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		fs.Usage()
+		return exitUsage
+	}
+
+	courses, err := loadDefaultCourses(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	loads := filterLoads(InstructorLoads(courses), *instructor)
+
+	if *output == "" {
+		if err := WriteLoadReport(os.Stdout, loads, format); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		return exitOK
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	err = WriteLoadReport(file, loads, format)
+	// A failed close can lose the end of the report, so it is an error too.
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "write report %s: %v\n", *output, err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// runStats prints enrollment statistics grouped by a section attribute, or
+// with -top-sections, the sections with the highest enrollment.
+func runStats(fs *flag.FlagSet, args []string) int {
+	byName := fs.String("by", string(BySubject), "group by subject, college, mode, schedule_type, campus, days or hour")
+	top := fs.Int("top", 0, "show only the N groups with the highest enrollment (0 for all)")
+	topSections := fs.Int("top-sections", 0, "list the N sections with the highest enrollment instead of groups")
+	percentileList := fs.String("percentiles", "50,90", "comma-separated percentiles of section enrollment to report")
+	subject := fs.String("subject", "", "only count sections in this subject")
+	mode := fs.String("mode", "", "only count sections whose instruction mode contains this text")
+	college := fs.String("college", "", "only count sections in this college")
+	asJSON := fs.Bool("json", false, "print the statistics as JSON")
+	config, code := loadCommandConfig(fs, args)
+	if code >= 0 {
+		return code
+	}
+	by, err := ParseDimension(*byName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	percentiles, err := ParsePercentiles(*percentileList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if fs.NArg() > 0 || *top < 0 || *topSections < 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	courses, err := loadDefaultCourses(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	filter := SectionFilter{Subject: *subject, Mode: *mode, College: *college}
+	sections := groupSections(FilterSections(courses, filter))
+
+	var result interface{}
+	if *topSections > 0 {
+		result = TopSections(sections, *topSections)
+	} else {
+		groups := EnrollmentByGroup(sections, by, percentiles)
+		if *top > 0 {
+			groups = groups[:min(*top, len(groups))]
+		}
+		result = groups
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	switch result := result.(type) {
+	case []SectionEnrollment:
+		for i, s := range result {
+			fmt.Printf("%3d. %-4s %-5s %-3s CRN %s %5d  %s\n", i+1, s.Subject, s.CourseNumber, s.Section, s.CRN, s.Enrollment, s.Title)
+		}
+	case []EnrollmentGroup:
+		fmt.Printf("%-24s %8s %10s %8s", by, "sections", "enrollment", "mean")
+		for _, p := range percentiles {
+			fmt.Printf(" %7s", percentileKey(p))
+		}
+		fmt.Println()
+		for _, group := range result {
+			fmt.Printf("%-24s %8d %10d %8.2f", group.Key, group.Sections, group.Enrollment, group.Mean)
+			for _, p := range percentiles {
+				fmt.Printf(" %7.1f", group.Percentiles[percentileKey(p)])
+			}
+			fmt.Println()
+		}
+	}
+	return exitOK
+}
+
+// runDiff compares two schedule snapshots and, with -apply, updates the
+// configured collections to match the newer one.
+func runDiff(fs *flag.FlagSet, args []string) int {
+	asJSON := fs.Bool("json", false, "print the differences as JSON")
+	apply := fs.Bool("apply", false, "update the vector collections incrementally to match NEW")
+	config, code := loadCommandConfig(fs, args)
+	if code >= 0 {
+		return code
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	snapshots := make([][]Course, 2)
+	for i, path := range fs.Args() {
+		snapshot := config
+		snapshot.CSVPath = path
+		metadata, err := openSchedule(snapshot, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return exitFailure
+		}
+		snapshots[i] = metadata.courses
+	}
+	diff := DiffSchedules(snapshots[0], snapshots[1])
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diff); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+	} else {
+		fmt.Print(diff)
+	}
+
+	if !*apply || diff.Empty() {
+		return exitOK
+	}
+	if err := config.RequireAPIKey(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	config, _, err := config.Selected()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	ctx, _, courseCollection, instructorCollection, err := OpenCollections(config, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return storeExitCode(err)
+	}
+	if err := ApplyDiff(ctx, config, diff, snapshots[1], courseCollection, instructorCollection); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to apply changes: %v\n", err)
+		return exitFailure
+	}
+	log.Printf("Applied %d added, %d cancelled and %d changed sections to %s", len(diff.Added), len(diff.Cancelled), len(diff.Changed), courseCollection.Name)
+	return exitOK
+}
+
+// runLint checks a schedule file, by default the selected term's, and exits
+// with exitFailure when it has errors so that scripts can lint before ingest.
+func runLint(fs *flag.FlagSet, args []string) int {
+	asJSON := fs.Bool("json", false, "print the issues as JSON")
+	checkList := fs.String("checks", "", "comma-separated checks to run (default all); see -list")
+	list := fs.Bool("list", false, "list the available checks and exit")
+	strict := fs.Bool("strict", false, "exit non-zero on warnings as well as errors")
+	config, code := loadCommandConfig(fs, args)
+	if code >= 0 {
+		return code
+	}
+	if *list {
+		for _, check := range lintChecks {
+			fmt.Printf("%-18s %-8s %s\n", check.Name, check.Severity, check.Description)
+		}
+		return exitOK
+	}
+	checks, err := ParseLintChecks(*checkList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	if fs.NArg() == 1 {
+		config.CSVPath = fs.Arg(0)
+	} else if config, _, err = config.Selected(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	var mapping *ColumnMapping
+	if config.ColumnMap != "" {
+		if mapping, err = LoadColumnMapping(config.ColumnMap); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+	}
+	reader, err := SourceReaderFor(config.Format, config.CSVPath, mapping)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	file, err := os.Open(config.CSVPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	defer file.Close()
+	report, err := LintSource(file, reader, mapping, checks)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", config.CSVPath, err)
+		return exitFailure
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+	} else {
+		fmt.Print(report)
+	}
+	if report.Failed(*strict) {
+		return exitFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Config holds every endpoint, model, path and limit the chatbot uses.
+// It is loaded by LoadConfig and passed explicitly to the constructors.
+type Config struct {
+	CSVPath              string       `json:"csv_path"`
+	ChromaURL            string       `json:"chroma_url"`
+	CourseCollection     string       `json:"course_collection"`
+	InstructorCollection string       `json:"instructor_collection"`
+	Model                string       `json:"model"`
+	TopK                 int          `json:"top_k"`
+	AddRetries           int          `json:"add_retries"`
+	Fallback             string       `json:"fallback"`
+	APIKeyEnv            string       `json:"api_key_env"`    // Name of the environment variable holding the OpenAI API key.
+	AnswerTimeout        Duration     `json:"answer_timeout"` // Longest the API server spends answering one request.
+	SessionTTL           Duration     `json:"session_ttl"`    // How long an idle conversation session is kept.
+	SessionDir           string       `json:"session_dir"`    // Directory for session files; empty keeps sessions in memory.
+	Format               string       `json:"format"`         // Schedule file format; empty picks it from the file extension.
+	ColumnMap            string       `json:"column_map"`     // JSON column-mapping file for exports in another layout.
+	Terms                []TermSource `json:"terms"`          // Schedule file per term; empty uses CSVPath alone.
+	DefaultTerm          string       `json:"default_term"`   // Term for questions that name none; empty means the latest.
+
+	APIKey string `json:"-"` // Read from the APIKeyEnv variable, never from the config file.
+}
+
+// Environment variables that override config file values.
+const (
+	envConfigFile           = "CATALOG_CONFIG"
+	envCSVPath              = "CATALOG_CSV"
+	envChromaURL            = "CATALOG_CHROMA_URL"
+	envCourseCollection     = "CATALOG_COURSE_COLLECTION"
+	envInstructorCollection = "CATALOG_INSTRUCTOR_COLLECTION"
+	envModel                = "CATALOG_MODEL"
+	envTopK                 = "CATALOG_TOP_K"
+	envAddRetries           = "CATALOG_ADD_RETRIES"
+	envFallback             = "CATALOG_FALLBACK"
+	envAPIKeyEnv            = "CATALOG_API_KEY_ENV"
+	envAnswerTimeout        = "CATALOG_ANSWER_TIMEOUT"
+	envSessionTTL           = "CATALOG_SESSION_TTL"
+	envSessionDir           = "CATALOG_SESSION_DIR"
+	envFormat               = "CATALOG_FORMAT"
+	envColumnMap            = "CATALOG_COLUMN_MAP"
+	envTerms                = "CATALOG_TERMS"
+	envDefaultTerm          = "CATALOG_DEFAULT_TERM"
+)
+
+// Duration is a time.Duration written as a string such as "90s" in config files.
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string like "90s" or "2m".
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string like \"90s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// DefaultConfig returns the configuration used when nothing is overridden.
+func DefaultConfig() Config {
+	return Config{
+		CSVPath:              "Fall 2024 Class Schedule 08082024.csv",
+		ChromaURL:            "http://localhost:8000",
+		CourseCollection:     "courses-collection",
+		InstructorCollection: "instructors-collection",
+		Model:                openai.GPT4oMini,
+		TopK:                 5,
+		AddRetries:           3,
+		Fallback:             FallbackSuggest.String(),
+		APIKeyEnv:            "OPENAI_API_KEY",
+		AnswerTimeout:        Duration(2 * time.Minute),
+		SessionTTL:           Duration(30 * time.Minute),
+	}
+}
+
+// LoadConfig builds a Config from defaults, then a JSON config file, then
+// CATALOG_* environment variables, then command-line flags, each overriding
+// the one before. The config file is named by the -config flag or the
+// CATALOG_CONFIG variable. Flags are registered on fs, so callers can add
+// their own flags before calling. The result is validated.
+func LoadConfig(fs *flag.FlagSet, args []string) (Config, error) {
+	var flags Config
+	configPath := fs.String("config", os.Getenv(envConfigFile), "path to a JSON config file")
+	fs.StringVar(&flags.CSVPath, "csv", "", "schedule CSV file")
+	fs.StringVar(&flags.ChromaURL, "chroma-url", "", "ChromaDB server URL")
+	fs.StringVar(&flags.CourseCollection, "course-collection", "", "ChromaDB collection for courses")
+	fs.StringVar(&flags.InstructorCollection, "instructor-collection", "", "ChromaDB collection for instructors")
+	fs.StringVar(&flags.Model, "model", "", "OpenAI chat model")
+	fs.IntVar(&flags.TopK, "top-k", 0, "number of documents to retrieve per question")
+	fs.IntVar(&flags.AddRetries, "add-retries", 0, "attempts per document when adding to ChromaDB")
+	fs.StringVar(&flags.Fallback, "fallback", "", "policy when nothing matches: refuse, suggest or general")
+	fs.StringVar(&flags.APIKeyEnv, "api-key-env", "", "environment variable holding the OpenAI API key")
+	fs.DurationVar((*time.Duration)(&flags.AnswerTimeout), "answer-timeout", 0, "longest the API server spends answering one request")
+	fs.DurationVar((*time.Duration)(&flags.SessionTTL), "session-ttl", 0, "how long an idle conversation session is kept")
+	fs.StringVar(&flags.SessionDir, "session-dir", "", "directory for session files (default: keep sessions in memory)")
+	fs.StringVar(&flags.Format, "format", "", "schedule file format: csv, tsv, json, jsonl or xlsx (default: from the file extension)")
+	fs.StringVar(&flags.ColumnMap, "column-map", "", "JSON file mapping the schedule's columns onto section fields")
+	terms := fs.String("terms", "", `schedule file per term, e.g. "Fall 2024=fall.csv;Spring 2025=spring.csv"`)
+	fs.StringVar(&flags.DefaultTerm, "term", "", "term for questions that name none (default: the latest)")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	config := DefaultConfig()
+	if *configPath != "" {
+		if err := config.loadFile(*configPath); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := config.loadEnv(); err != nil {
+		return Config{}, err
+	}
+
+	var termsErr error
+	// Only flags given on the command line override the other sources.
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "csv":
+			config.CSVPath = flags.CSVPath
+		case "chroma-url":
+			config.ChromaURL = flags.ChromaURL
+		case "course-collection":
+			config.CourseCollection = flags.CourseCollection
+		case "instructor-collection":
+			config.InstructorCollection = flags.InstructorCollection
+		case "model":
+			config.Model = flags.Model
+		case "top-k":
+			config.TopK = flags.TopK
+		case "add-retries":
+			config.AddRetries = flags.AddRetries
+		case "fallback":
+			config.Fallback = flags.Fallback
+		case "api-key-env":
+			config.APIKeyEnv = flags.APIKeyEnv
+		case "answer-timeout":
+			config.AnswerTimeout = flags.AnswerTimeout
+		case "session-ttl":
+			config.SessionTTL = flags.SessionTTL
+		case "session-dir":
+			config.SessionDir = flags.SessionDir
+		case "format":
+			config.Format = flags.Format
+		case "column-map":
+			config.ColumnMap = flags.ColumnMap
+		case "terms":
+			config.Terms, termsErr = parseTermSources(*terms)
+		case "term":
+			config.DefaultTerm = flags.DefaultTerm
+		}
+	})
+	if termsErr != nil {
+		return Config{}, fmt.Errorf("-terms: %w", termsErr)
+	}
+
+	config.APIKey = os.Getenv(config.APIKeyEnv)
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// loadFile overrides config values with those present in a JSON file.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides config values with those set in CATALOG_* environment variables.
+func (c *Config) loadEnv() error {
+	stringVars := map[string]*string{
+		envCSVPath:              &c.CSVPath,
+		envChromaURL:            &c.ChromaURL,
+		envCourseCollection:     &c.CourseCollection,
+		envInstructorCollection: &c.InstructorCollection,
+		envModel:                &c.Model,
+		envFallback:             &c.Fallback,
+		envAPIKeyEnv:            &c.APIKeyEnv,
+		envSessionDir:           &c.SessionDir,
+		envDefaultTerm:          &c.DefaultTerm,
+		envFormat:               &c.Format,
+		envColumnMap:            &c.ColumnMap,
+	}
+	for name, field := range stringVars {
+		if value, ok := os.LookupEnv(name); ok {
+			*field = value
+		}
+	}
+
+	intVars := map[string]*int{
+		envTopK:       &c.TopK,
+		envAddRetries: &c.AddRetries,
+	}
+	for name, field := range intVars {
+		if value, ok := os.LookupEnv(name); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s must be an integer, got %q", name, value)
+			}
+			*field = n
+		}
+	}
+
+	if value, ok := os.LookupEnv(envTerms); ok {
+		terms, err := parseTermSources(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", envTerms, err)
+		}
+		c.Terms = terms
+	}
+
+	durationVars := map[string]*Duration{
+		envAnswerTimeout: &c.AnswerTimeout,
+		envSessionTTL:    &c.SessionTTL,
+	}
+	for name, field := range durationVars {
+		if value, ok := os.LookupEnv(name); ok {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("%s must be a duration like 90s, got %q", name, value)
+			}
+			*field = Duration(d)
+		}
+	}
+	return nil
+}
+
+// Validate reports every problem with the configuration at once. It does not
+// require an API key; see RequireAPIKey.
+func (c Config) Validate() error {
+	var problems []error
+	if strings.TrimSpace(c.CSVPath) == "" {
+		problems = append(problems, errors.New("csv path is empty"))
+	}
+	if u, err := url.Parse(c.ChromaURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Errorf("chroma url %q is not an http(s) URL", c.ChromaURL))
+	}
+	if c.CourseCollection == "" || c.InstructorCollection == "" {
+		problems = append(problems, errors.New("collection names must not be empty"))
+	} else if c.CourseCollection == c.InstructorCollection {
+		problems = append(problems, errors.New("course and instructor collections must differ"))
+	}
+	if c.Model == "" {
+		problems = append(problems, errors.New("model is empty"))
+	}
+	if c.TopK < 1 || c.TopK > 100 {
+		problems = append(problems, fmt.Errorf("top-k must be between 1 and 100, got %d", c.TopK))
+	}
+	if c.AddRetries < 1 {
+		problems = append(problems, fmt.Errorf("add retries must be at least 1, got %d", c.AddRetries))
+	}
+	if c.AnswerTimeout <= 0 {
+		problems = append(problems, fmt.Errorf("answer timeout must be positive, got %v", time.Duration(c.AnswerTimeout)))
+	}
+	if c.SessionTTL <= 0 {
+		problems = append(problems, fmt.Errorf("session TTL must be positive, got %v", time.Duration(c.SessionTTL)))
+	}
+	if _, err := ParseFallbackPolicy(c.Fallback); err != nil {
+		problems = append(problems, err)
+	}
+	if c.APIKeyEnv == "" {
+		problems = append(problems, errors.New("api key environment variable name is empty"))
+	}
+	if c.Format != "" {
+		if _, err := ParseSourceFormat(c.Format); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if _, err := c.termFiles(); err != nil {
+		problems = append(problems, err)
+	} else if _, err := c.defaultTerm(); err != nil {
+		problems = append(problems, err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(problems...))
+	}
+	return nil
+}
+
+// RequireAPIKey reports an error if no OpenAI API key was found. Commands that
+// call the LLM or embed documents check it in addition to Validate.
+func (c Config) RequireAPIKey() error {
+	if c.APIKey == "" {
+		return fmt.Errorf("API key is missing. Please set %s environment variable", c.APIKeyEnv)
+	}
+	return nil
+}
+
+// FallbackPolicy returns the configured fallback policy. Validate guarantees it parses.
+func (c Config) FallbackPolicy() FallbackPolicy {
+	policy, _ := ParseFallbackPolicy(c.Fallback)
+	return policy
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoadConfigPrecedence verifies that flags override environment variables,
+// which override the config file, which overrides the defaults.
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	file := `{"chroma_url": "http://chroma.internal:8000", "model": "file-model", "top_k": 7, "api_key_env": "TEST_CATALOG_KEY"}`
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_CATALOG_KEY", "secret")
+	t.Setenv(envModel, "env-model")
+	t.Setenv(envTopK, "9")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	config, err := LoadConfig(fs, []string{"-config", path, "-top-k", "3"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.ChromaURL != "http://chroma.internal:8000" {
+		t.Errorf("Expected chroma URL from file, got %q", config.ChromaURL)
+	}
+	if config.Model != "env-model" {
+		t.Errorf("Expected model from environment, got %q", config.Model)
+	}
+	if config.TopK != 3 {
+		t.Errorf("Expected top-k from flag, got %d", config.TopK)
+	}
+	if config.CourseCollection != "courses-collection" || config.AddRetries != 3 {
+		t.Errorf("Expected defaults for unset values, got %+v", config)
+	}
+	if config.APIKey != "secret" {
+		t.Errorf("Expected API key read from TEST_CATALOG_KEY, got %q", config.APIKey)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKey = "secret"
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected default config to be valid, got %v", err)
+	}
+
+	config.ChromaURL = "localhost:8000"
+	config.TopK = 0
+	config.Fallback = "guess"
+	config.APIKey = ""
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{"chroma url", "top-k", "fallback policy"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got %v", want, err)
+		}
+	}
+
+	if err := config.RequireAPIKey(); err == nil || !strings.Contains(err.Error(), "OPENAI_API_KEY") {
+		t.Errorf("Expected RequireAPIKey to name OPENAI_API_KEY, got %v", err)
+	}
+}
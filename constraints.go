@@ -0,0 +1,522 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Parts of the day, in minutes after midnight. A section is in the morning
+// if it starts before noon and in the evening if it starts at 5pm or later.
+const (
+	noon         = 12 * 60
+	eveningStart = 17 * 60
+	endOfDay     = 24 * 60
+)
+
+// TimeWindow is a span of the day in minutes after midnight, To exclusive.
+type TimeWindow struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// ScheduleConstraint is the days and times a question asks for, as in
+// "CS classes after 3pm" or "not on Fridays". Zero fields do not constrain.
+type ScheduleConstraint struct {
+	Days        string       `json:"days,omitempty"`         // Day letters the section meets on, all of them.
+	AnyDays     string       `json:"any_days,omitempty"`     // Day letters the section meets on, at least one.
+	DaySets     []string     `json:"day_sets,omitempty"`     // Sets of day letters; the section meets on all of at least one.
+	NotDays     string       `json:"not_days,omitempty"`     // Day letters the section never meets on.
+	StartFrom   int          `json:"start_from,omitempty"`   // Earliest start, minutes after midnight.
+	StartBefore int          `json:"start_before,omitempty"` // Every meeting starts before this.
+	EndBy       int          `json:"end_by,omitempty"`       // Latest end.
+	NotDuring   []TimeWindow `json:"not_during,omitempty"`   // Windows the section is never in session.
+}
+
+// Empty reports whether the constraint allows every section.
+func (c ScheduleConstraint) Empty() bool {
+	return c.Days == "" && c.AnyDays == "" && len(c.DaySets) == 0 && c.NotDays == "" && !c.timed() && len(c.NotDuring) == 0
+}
+
+// timed reports whether the constraint needs sections to have meeting times.
+func (c ScheduleConstraint) timed() bool {
+	return c.StartFrom > 0 || c.StartBefore > 0 || c.EndBy > 0
+}
+
+func (c ScheduleConstraint) String() string {
+	var parts []string
+	if c.Days != "" {
+		parts = append(parts, "meets on "+joinDayNames(c.Days, "and"))
+	}
+	if c.AnyDays != "" {
+		parts = append(parts, "meets on "+joinDayNames(c.AnyDays, "or"))
+	}
+	if len(c.DaySets) > 0 {
+		sets := make([]string, len(c.DaySets))
+		for i, set := range c.DaySets {
+			sets[i] = joinDayNames(set, "and")
+		}
+		parts = append(parts, "meets on "+strings.Join(sets, " or on "))
+	}
+	if c.NotDays != "" {
+		parts = append(parts, "never meets on "+joinDayNames(c.NotDays, "or"))
+	}
+	if c.StartFrom > 0 {
+		parts = append(parts, "starts at "+formatClock(c.StartFrom)+" or later")
+	}
+	if c.StartBefore > 0 {
+		parts = append(parts, "starts before "+formatClock(c.StartBefore))
+	}
+	if c.EndBy > 0 {
+		parts = append(parts, "ends by "+formatClock(c.EndBy))
+	}
+	for _, window := range c.NotDuring {
+		parts = append(parts, fmt.Sprintf("is never in session between %s and %s", formatClock(window.From), formatClock(window.To)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// dayFullNames are the names of the schedule's day letters.
+var dayFullNames = map[rune]string{'M': "Monday", 'T': "Tuesday", 'W': "Wednesday", 'R': "Thursday", 'F': "Friday", 'S': "Saturday", 'U': "Sunday"}
+
+func joinDayNames(days, conjunction string) string {
+	var names []string
+	for _, day := range days {
+		names = append(names, dayFullNames[day])
+	}
+	return joinList(names, conjunction)
+}
+
+// MatchSection reports whether a section, given all its schedule rows,
+// satisfies the constraint. Day constraints apply to the days of all its
+// meetings together; time constraints apply to each meeting. Sections with
+// no meeting times satisfy only constraints that exclude days or times.
+func (c ScheduleConstraint) MatchSection(rows []Course) bool {
+	var meetings []Meeting
+	days := ""
+	for _, row := range rows {
+		if meeting, ok := parseMeeting(row); ok {
+			meetings = append(meetings, meeting)
+			days += meeting.Days
+		} else {
+			days += normalizeDays(row.MeetDays)
+		}
+	}
+	days = normalizeDays(days)
+
+	if !containsAllDays(days, c.Days) {
+		return false
+	}
+	if c.AnyDays != "" && !strings.ContainsAny(days, c.AnyDays) {
+		return false
+	}
+	if len(c.DaySets) > 0 && !slices.ContainsFunc(c.DaySets, func(set string) bool { return containsAllDays(days, set) }) {
+		return false
+	}
+	if c.NotDays != "" && strings.ContainsAny(days, c.NotDays) {
+		return false
+	}
+	if c.timed() && len(meetings) == 0 {
+		return false
+	}
+	for _, m := range meetings {
+		if c.StartFrom > 0 && m.Start < c.StartFrom ||
+			c.StartBefore > 0 && m.Start >= c.StartBefore ||
+			c.EndBy > 0 && m.End > c.EndBy {
+			return false
+		}
+		for _, window := range c.NotDuring {
+			if m.Start < window.To && window.From < m.End {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// containsAllDays reports whether days includes every day letter of set.
+func containsAllDays(days, set string) bool {
+	for _, day := range set {
+		if !strings.ContainsRune(days, day) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterRows returns the rows of every section that satisfies the
+// constraint, in their original order.
+func (c ScheduleConstraint) FilterRows(courses []Course) []Course {
+	allowed := c.allowedCRNs(courses)
+	matches := []Course{}
+	for _, course := range courses {
+		if allowed[course.CRN] {
+			matches = append(matches, course)
+		}
+	}
+	return matches
+}
+
+// allowedCRNs lists the CRNs of the sections that satisfy the constraint.
+func (c ScheduleConstraint) allowedCRNs(courses []Course) map[string]bool {
+	allowed := make(map[string]bool)
+	for _, section := range groupSections(courses) {
+		if c.MatchSection(section.Rows) {
+			allowed[section.CRN] = true
+		}
+	}
+	return allowed
+}
+
+// filterDocuments keeps the course documents of sections in courses that
+// satisfy the constraint, up to limit. Documents that are not course rows,
+// such as instructor names, are kept as they are.
+func (c ScheduleConstraint) filterDocuments(documents []string, courses []Course, limit int) []string {
+	allowed := c.allowedCRNs(courses)
+	var kept []string
+	for _, doc := range documents {
+		var course Course
+		if err := json.Unmarshal([]byte(doc), &course); err == nil && course.CRN != "" {
+			if len(courses) > 0 && !allowed[course.CRN] || len(courses) == 0 && !c.MatchSection([]Course{course}) {
+				continue
+			}
+		}
+		if kept = append(kept, doc); len(kept) == limit {
+			break
+		}
+	}
+	return kept
+}
+
+// noMatchingSectionsMessage says that a term offers the named courses but
+// none of their sections satisfies the constraint.
+func noMatchingSectionsMessage(codes []CourseCode, constraint ScheduleConstraint, term Term) string {
+	schedule := "the schedule"
+	if !term.IsZero() {
+		schedule = fmt.Sprintf("the %s schedule", term)
+	}
+	return fmt.Sprintf("No section of %s in %s %s.", joinCourseCodes(codes), schedule, constraint)
+}
+
+// constraintTime matches a time of day: "3pm", "3:30 p.m.", "15:30", "noon"
+// or "midnight". Bare numbers such as "3" or "2024" are not times, so "taught
+// by 2 professors" and "after 2024" carry no constraint.
+const constraintTime = `(noon\b|midnight\b|\d{1,2}(?::\d{2})?\s*(?:[ap]m\b|[ap]\.m\.)|\d{1,2}:\d{2}\b)`
+
+// rangeStartTime is constraintTime or a bare hour, which may open a range
+// whose end is a time, as in "between 10 and 2pm".
+const rangeStartTime = `(noon\b|midnight\b|\d{1,2}(?::\d{2})?\s*(?:[ap]m\b|[ap]\.m\.)|\d{1,2}:\d{2}\b|\d{1,2}\b)`
+
+const startWords = `(?:start(?:s|ing)?|begin(?:s|ning)?)`
+
+// Kinds of constraint phrase, in the order they are matched: a phrase that
+// overlaps an earlier match is ignored, so "starting before 10" is not also
+// read as "before 10".
+const (
+	phraseBetween = iota
+	phraseStartBefore
+	phraseAfter
+	phraseBefore
+	phrasePartOfDay
+	phraseDayName
+	phraseDayLetters
+)
+
+var constraintPatterns = []struct {
+	kind    int
+	pattern *regexp.Regexp
+}{
+	{phraseBetween, regexp.MustCompile(`\b(?:between|from)\s+` + rangeStartTime + `\s*(?:and|to|-|until|till)\s*` + constraintTime)},
+	{phraseStartBefore, regexp.MustCompile(`\b` + startWords + `\s+(?:before|by)\s+` + constraintTime)},
+	{phraseAfter, regexp.MustCompile(`\b(?:after|later than|` + startWords + `\s+(?:at|after|from))\s+` + constraintTime)},
+	{phraseBefore, regexp.MustCompile(`\b(?:before|by|until|till|earlier than|(?:end(?:s|ing)?|finish(?:es|ing)?|done|out)\s+(?:by|before))\s+` + constraintTime)},
+	{phrasePartOfDay, regexp.MustCompile(`\b(morning|afternoon|evening|night)s?\b`)},
+	{phraseDayName, regexp.MustCompile(`\b(mon(?:day)?|tue(?:s(?:day)?)?|wed(?:s|nesday)?|thu(?:r(?:s(?:day)?)?)?|fri(?:day)?|sat(?:urday)?|sun(?:day)?)s?\b`)},
+	// Run on the original text: day letters are written in capitals.
+	{phraseDayLetters, regexp.MustCompile(`\b(?:M|Tu|Th|T|W|R|F|Sa|Su|S|U){2,7}\b`)},
+}
+
+// dayNameLetters maps the day names constraintPatterns matches to day letters.
+var dayNameLetters = map[string]string{"mon": "M", "tue": "T", "wed": "W", "thu": "R", "fri": "F", "sat": "S", "sun": "U"}
+
+// notDayLetters are capitalized words that look like day letters but are
+// not, besides subject codes such as "MS".
+var notDayLetters = map[string]bool{"US": true}
+
+var (
+	negationPattern       = regexp.MustCompile(`\b(?:not|no|don't|dont|never|except|excluding|without|avoid|avoiding|nothing|none|isn't|aren't)\b`)
+	clauseBreakPattern    = regexp.MustCompile(`[.;!?]|\bbut\b`)
+	connectorOnly         = regexp.MustCompile(`^(?:\s|,|/|&|\b(?:and|or|nor|on|the)\b)*$`)
+	connectorJoinsPhrases = regexp.MustCompile(`,|/|&|\b(?:and|or|nor)\b`)
+	orPattern             = regexp.MustCompile(`\bor\b`)
+	countNounPattern      = regexp.MustCompile(`^\s*(?:students?|people|seats?|sections?|classes|courses|credits?|units?|times|minutes|hours)\b`)
+)
+
+// constraintPhrase is one day or time phrase found in a question.
+type constraintPhrase struct {
+	kind       int
+	start, end int
+	groups     []string
+	negated    bool
+}
+
+// ParseScheduleConstraint finds day and time constraints in free text, such
+// as "CS classes after 3pm", "morning sections on MWF", "not on Fridays",
+// "Tuesday evenings" or "before noon". It reports false if there are none.
+//
+// Times need "am", "pm", a colon, "noon" or "midnight"; only the start of a
+// range may be a bare hour, and one from 1 to 7 is read as afternoon, so
+// "between 10 and 2pm" means 10am to 2pm.
+// "Before" a time means ending by it; "after" a time means starting at or
+// after it. A negation such as "not", "no" or "except" applies to the phrase
+// that follows it and to phrases joined to that one by "and", "or" or commas.
+func ParseScheduleConstraint(text string) (ScheduleConstraint, bool) {
+	lower := strings.ToLower(text)
+	original := text
+	if len(lower) != len(text) {
+		original = "" // Offsets differ; skip day letters rather than misplace them.
+	}
+
+	var phrases []constraintPhrase
+	for _, p := range constraintPatterns {
+		source := lower
+		if p.kind == phraseDayLetters {
+			source = original
+		}
+		for _, m := range p.pattern.FindAllStringSubmatchIndex(source, -1) {
+			phrase := constraintPhrase{kind: p.kind, start: m[0], end: m[1]}
+			for i := 0; i < len(m); i += 2 {
+				group := ""
+				if m[i] >= 0 {
+					group = source[m[i]:m[i+1]]
+				}
+				phrase.groups = append(phrase.groups, group)
+			}
+			if p.kind == phraseDayLetters {
+				word := phrase.groups[0]
+				if _, subject := subjectNames[word]; subject || notDayLetters[word] {
+					continue
+				}
+			}
+			if p.kind == phraseDayName && !strings.HasSuffix(phrase.groups[1], "day") && !dayAbbreviation(lower, original, phrase) {
+				continue // "I sat in on a class" is not about Saturday.
+			}
+			if p.kind <= phraseBefore && countNounPattern.MatchString(source[phrase.end:]) {
+				continue // "between 10 and 20 students" is not a time.
+			}
+			if !overlapsPhrase(phrases, phrase) {
+				phrases = append(phrases, phrase)
+			}
+		}
+	}
+	if len(phrases) == 0 {
+		return ScheduleConstraint{}, false
+	}
+	sort.Slice(phrases, func(i, j int) bool { return phrases[i].start < phrases[j].start })
+
+	// Mark negated phrases: a negation since the previous phrase in the same
+	// clause, or a negated previous phrase joined to this one by a connector.
+	previousEnd := 0
+	for i := range phrases {
+		gap := lower[previousEnd:phrases[i].start]
+		if breaks := clauseBreakPattern.FindAllStringIndex(gap, -1); len(breaks) > 0 {
+			gap = gap[breaks[len(breaks)-1][1]:]
+		} else if i > 0 && phrases[i-1].negated && connectorOnly.MatchString(gap) && connectorJoinsPhrases.MatchString(gap) {
+			phrases[i].negated = true
+		}
+		if negationPattern.MatchString(gap) {
+			phrases[i].negated = true
+		}
+		previousEnd = phrases[i].end
+	}
+
+	var c ScheduleConstraint
+	found := false
+	for i, phrase := range phrases {
+		// The days of the phrases before "or", as in "MW or TR" or "Monday,
+		// Wednesday or Friday", are alternatives to this phrase's.
+		var alternatives []string
+		if i > 0 && phrase.days() && phrases[i-1].days() && !phrases[i-1].negated && orPattern.MatchString(lower[phrases[i-1].end:phrase.start]) {
+			first := i - 1
+			for first > 0 && phrases[first-1].days() && !phrases[first-1].negated && connectorOnly.MatchString(lower[phrases[first-1].end:phrases[first].start]) {
+				first--
+			}
+			for _, previous := range phrases[first:i] {
+				alternatives = append(alternatives, previous.dayLetters())
+			}
+		}
+		if c.apply(phrase, alternatives) {
+			found = true
+		}
+	}
+	// Alternatives of one day each, as in "Tuesday or Thursday", are simply any of those days.
+	if !slices.ContainsFunc(c.DaySets, func(set string) bool { return len(set) > 1 }) {
+		c.AnyDays = normalizeDays(c.AnyDays + strings.Join(c.DaySets, ""))
+		c.DaySets = nil
+	}
+	return c, found
+}
+
+// dayAbbreviation reports whether a short day name such as "sat" or "wed"
+// is written as one: capitalized, followed by a period or after "on".
+func dayAbbreviation(lower, original string, phrase constraintPhrase) bool {
+	if original != "" && unicode.IsUpper(rune(original[phrase.start])) {
+		return true
+	}
+	if phrase.end < len(lower) && lower[phrase.end] == '.' {
+		return true
+	}
+	return onBeforePattern.MatchString(lower[:phrase.start])
+}
+
+var onBeforePattern = regexp.MustCompile(`\bon\s+$`)
+
+// days reports whether the phrase names days rather than times.
+func (p constraintPhrase) days() bool {
+	return p.kind == phraseDayName || p.kind == phraseDayLetters
+}
+
+// dayLetters returns the day letters a day phrase names.
+func (p constraintPhrase) dayLetters() string {
+	if p.kind == phraseDayName {
+		return dayNameLetters[p.groups[1][:3]]
+	}
+	return dayLetters(p.groups[0])
+}
+
+func overlapsPhrase(phrases []constraintPhrase, phrase constraintPhrase) bool {
+	for _, p := range phrases {
+		if phrase.start < p.end && p.start < phrase.end {
+			return true
+		}
+	}
+	return false
+}
+
+// apply adds one phrase to the constraint, reporting false if the phrase
+// turned out not to be a day or time. alternatives holds the days of the
+// phrases before it when they are joined to it by "or".
+func (c *ScheduleConstraint) apply(phrase constraintPhrase, alternatives []string) bool {
+	switch phrase.kind {
+	case phraseDayName, phraseDayLetters:
+		days := phrase.dayLetters()
+		switch {
+		case phrase.negated:
+			c.NotDays = normalizeDays(c.NotDays + days)
+		case len(alternatives) > 0:
+			// "MW or TR": the days before "or" become alternatives rather than required days.
+			for _, alternative := range alternatives {
+				if slices.Contains(c.DaySets, alternative) {
+					continue
+				}
+				c.Days = strings.Map(func(day rune) rune {
+					if strings.ContainsRune(alternative, day) {
+						return -1
+					}
+					return day
+				}, c.Days)
+				c.DaySets = append(c.DaySets, alternative)
+			}
+			c.DaySets = append(c.DaySets, days)
+		default:
+			c.Days = normalizeDays(c.Days + days)
+		}
+		return true
+
+	case phrasePartOfDay:
+		window := map[string]TimeWindow{"morning": {0, noon}, "afternoon": {noon, eveningStart}, "evening": {eveningStart, endOfDay}, "night": {eveningStart, endOfDay}}[phrase.groups[1]]
+		switch {
+		case phrase.negated:
+			c.NotDuring = append(c.NotDuring, window)
+		case window.From == 0:
+			c.startBefore(window.To)
+		default:
+			c.startFrom(window.From)
+			if window.To < endOfDay {
+				c.startBefore(window.To)
+			}
+		}
+		return true
+	}
+
+	var times []int
+	for _, group := range phrase.groups[1:] {
+		minutes, ok := parseConstraintTime(group)
+		if !ok {
+			return false
+		}
+		times = append(times, minutes)
+	}
+	switch phrase.kind {
+	case phraseBetween:
+		if phrase.negated {
+			c.NotDuring = append(c.NotDuring, TimeWindow{times[0], times[1]})
+		} else {
+			c.startFrom(times[0])
+			c.endBy(times[1])
+		}
+	case phraseAfter:
+		if phrase.negated { // "not after 5pm"
+			c.endBy(times[0])
+		} else {
+			c.startFrom(times[0])
+		}
+	case phraseBefore, phraseStartBefore:
+		switch {
+		case phrase.negated: // "not before 10am"
+			c.startFrom(times[0])
+		case phrase.kind == phraseStartBefore:
+			c.startBefore(times[0])
+		default:
+			c.endBy(times[0])
+		}
+	}
+	return true
+}
+
+// The setters keep the tightest bound when a question gives several.
+
+func (c *ScheduleConstraint) startFrom(minutes int) {
+	c.StartFrom = max(c.StartFrom, minutes)
+}
+
+func (c *ScheduleConstraint) startBefore(minutes int) {
+	if c.StartBefore == 0 || minutes < c.StartBefore {
+		c.StartBefore = minutes
+	}
+}
+
+func (c *ScheduleConstraint) endBy(minutes int) {
+	if c.EndBy == 0 || minutes < c.EndBy {
+		c.EndBy = minutes
+	}
+}
+
+// parseConstraintTime parses a time matched by constraintTime or
+// rangeStartTime into minutes after midnight. A bare hour from 1 to 7 is read
+// as afternoon.
+func parseConstraintTime(s string) (int, bool) {
+	s = strings.NewReplacer(" ", "", ".", "").Replace(s)
+	switch s {
+	case "noon":
+		return noon, true
+	case "midnight":
+		return endOfDay, true
+	}
+	if hour, err := strconv.Atoi(s); err == nil && len(s) <= 2 {
+		switch {
+		case hour >= 1 && hour <= 7:
+			return (hour + 12) * 60, true
+		case hour >= 8 && hour <= 23:
+			return hour * 60, true
+		}
+		return 0, false
+	}
+	minutes, err := parseTimeOfDay(s)
+	return minutes, err == nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseScheduleConstraint(t *testing.T) {
+	tests := []struct {
+		text string
+		want ScheduleConstraint
+	}{
+		{"CS classes after 3pm", ScheduleConstraint{StartFrom: 15 * 60}},
+		{"sections starting after 15:30", ScheduleConstraint{StartFrom: 15*60 + 30}},
+		{"anything before noon?", ScheduleConstraint{EndBy: noon}},
+		{"done by 4:30 p.m.", ScheduleConstraint{EndBy: 16*60 + 30}},
+		{"classes that start before 10am", ScheduleConstraint{StartBefore: 10 * 60}},
+		{"between 10 and 2pm", ScheduleConstraint{StartFrom: 10 * 60, EndBy: 14 * 60}},
+		{"from 09:00 to 13:15", ScheduleConstraint{StartFrom: 9 * 60, EndBy: 13*60 + 15}},
+		{"morning sections on MWF", ScheduleConstraint{Days: "MWF", StartBefore: noon}},
+		{"Tuesday evenings", ScheduleConstraint{Days: "T", StartFrom: eveningStart}},
+		{"afternoon labs", ScheduleConstraint{StartFrom: noon, StartBefore: eveningStart}},
+		{"classes on Tuesdays and Thursdays", ScheduleConstraint{Days: "TR"}},
+		{"a TR section of MATH 201", ScheduleConstraint{Days: "TR"}},
+		{"TTh only", ScheduleConstraint{Days: "TR"}},
+		{"on Tuesday or Thursday", ScheduleConstraint{AnyDays: "TR"}},
+		{"anything on sat", ScheduleConstraint{Days: "S"}},
+		{"Wed. or Fri. labs", ScheduleConstraint{AnyDays: "WF"}},
+		{"I sat in on a class in the morning", ScheduleConstraint{StartBefore: noon}},
+		{"Is CS 272 offered on MW or TR?", ScheduleConstraint{DaySets: []string{"MW", "TR"}}},
+		{"Monday, Wednesday or Friday", ScheduleConstraint{AnyDays: "MWF"}},
+		{"not on Fridays", ScheduleConstraint{NotDays: "F"}},
+		{"no Friday classes", ScheduleConstraint{NotDays: "F"}},
+		{"nothing on Mondays, Wednesdays or Fridays", ScheduleConstraint{NotDays: "MWF"}},
+		{"not on Fridays after 3pm", ScheduleConstraint{NotDays: "F", StartFrom: 15 * 60}},
+		{"Wednesday but not Friday", ScheduleConstraint{Days: "W", NotDays: "F"}},
+		{"not before 10am", ScheduleConstraint{StartFrom: 10 * 60}},
+		{"nothing after 5pm", ScheduleConstraint{EndBy: 17 * 60}},
+		{"no morning classes", ScheduleConstraint{NotDuring: []TimeWindow{{0, noon}}}},
+		{"except between 12 and 1pm", ScheduleConstraint{NotDuring: []TimeWindow{{noon, 13 * 60}}}},
+		{"after 10am and before 2pm on MW", ScheduleConstraint{Days: "MW", StartFrom: 10 * 60, EndBy: 14 * 60}},
+	}
+	for _, test := range tests {
+		got, ok := ParseScheduleConstraint(test.text)
+		if !ok || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseScheduleConstraint(%q) = %+v, %v; want %+v", test.text, got, ok, test.want)
+		}
+	}
+
+	for _, text := range []string{
+		"Who teaches CS 272?",
+		"Which MS courses are online?",
+		"sections with between 10 and 20 students",
+		"top 5 sections by enrollment",
+		"Is it offered in Fall 2024?",
+		"Courses taught by 2 professors",
+		"classes after 2024",
+		"after 1200",
+		"CS classes after 3",
+		"Which seminars wed theory and practice?",
+	} {
+		if got, ok := ParseScheduleConstraint(text); ok {
+			t.Errorf("ParseScheduleConstraint(%q) = %+v; want no constraint", text, got)
+		}
+	}
+}
+
+func TestScheduleConstraintMatch(t *testing.T) {
+	lecture := Course{CRN: "1", MeetDays: "MW", BeginTime: "1530", EndTime: "1715"}
+	lab := Course{CRN: "1", MeetDays: "F", BeginTime: "0900", EndTime: "1050"}
+	online := Course{CRN: "2", InstructionModeDesc: "Online Asynchronous"}
+	evening := Course{CRN: "3", MeetDays: "T", BeginTime: "1800", EndTime: "2045"}
+
+	tests := []struct {
+		text string
+		want []string // CRNs that match.
+	}{
+		{"after 3pm", []string{"3"}},
+		{"on MWF", []string{"1"}},
+		{"on MW or TR", []string{"1"}}, // CRN 3 meets on Tuesday alone.
+		{"not on Fridays", []string{"2", "3"}},
+		{"Tuesday evenings", []string{"3"}},
+		{"no morning classes", []string{"2", "3"}},
+		{"before 6pm", []string{"1"}},
+	}
+	courses := []Course{lecture, lab, online, evening}
+	for _, test := range tests {
+		constraint, ok := ParseScheduleConstraint(test.text)
+		if !ok {
+			t.Fatalf("Expected a constraint in %q", test.text)
+		}
+		var got []string
+		for _, section := range groupSections(constraint.FilterRows(courses)) {
+			got = append(got, section.CRN)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q matched sections %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestChatBotScheduleConstraint(t *testing.T) {
+	courses := []Course{
+		{Subject: "CS", CourseNumber: "272", Section: "01", CRN: "41231", Title: "Software Development", MeetDays: "MWF", BeginTime: "0900", EndTime: "1005"},
+		{Subject: "CS", CourseNumber: "272", Section: "02", CRN: "41232", Title: "Software Development", MeetDays: "TR", BeginTime: "1600", EndTime: "1745"},
+		{Subject: "CS", CourseNumber: "110", Section: "01", CRN: "41300", Title: "Intro to Computer Science I", MeetDays: "MW", BeginTime: "1530", EndTime: "1715"},
+		{Subject: "CS", CourseNumber: "110", Section: "02", CRN: "41301", Title: "Intro to Computer Science I", MeetDays: "F", BeginTime: "1600", EndTime: "1800"},
+	}
+
+	var systemMessages []string
+	config := DefaultConfig()
+	config.CSVPath = "Fall 2024 Class Schedule.csv"
+	bot := NewChatBot(config, newCapturingLLM(t, &systemMessages), &MetadataExtractor{courses: courses}, nil, nil, nil, nil)
+
+	answer, err := bot.Ask("Is there an Intro to Computer Science section after 3pm and not on Fridays?")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if answer.Retrieval != RetrievalLexical || len(answer.Documents) != 1 || answer.Citations()[0].CRN != "41300" {
+		t.Errorf("Expected only CRN 41300 retrieved, got %s with %v", answer.Retrieval, answer.Documents)
+	}
+	if len(systemMessages) != 1 || !strings.Contains(systemMessages[0], "never meets on Friday") {
+		t.Errorf("Expected the constraint described to the LLM, got %q", systemMessages)
+	}
+
+	answer, err = bot.Ask("Is there a morning section of CS 272 on MWF?")
+	if err != nil || answer.Retrieval != RetrievalDirect || len(answer.Documents) != 1 || answer.Citations()[0].CRN != "41231" {
+		t.Errorf("Expected CS 272-01 alone, got %+v, %v", answer.Documents, err)
+	}
+
+	answer, err = bot.Ask("Does CS 272 meet on Saturday?")
+	if err != nil || answer.Text != "No section of CS 272 in the Fall 2024 schedule meets on Saturday." {
+		t.Errorf("Expected no matching sections reported, got %+v, %v", answer, err)
+	}
+	if len(systemMessages) != 2 {
+		t.Errorf("Expected no LLM call when no section matches, got %d calls", len(systemMessages))
+	}
+}
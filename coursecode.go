@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// subjectNames is the subject dictionary: the department name of each
+// subject code in the schedule.
+var subjectNames = map[string]string{
+	"AAS":  "African American Studies",
+	"ADVT": "Advertising",
+	"AEM":  "Academic English for Multilingual Students",
+	"ANST": "Asian Studies",
+	"ANTH": "Anthropology",
+	"ARCH": "Architecture",
+	"ART":  "Art",
+	"ASL":  "American Sign Language",
+	"BAIS": "International Studies",
+	"BAM":  "Business Administration and Management",
+	"BIOL": "Biology",
+	"BSDS": "Data Science",
+	"BSPH": "Public Health",
+	"BTEC": "Biotechnology",
+	"BUS":  "Business",
+	"CDS":  "Critical Diversity Studies",
+	"CEL":  "Catholic Educational Leadership",
+	"CHEM": "Chemistry",
+	"CHIN": "Chinese",
+	"CLS":  "Chicano Latino Studies",
+	"CMPL": "Comparative Literature",
+	"COMS": "Communication Studies",
+	"CPSY": "Counseling Psychology",
+	"CS":   "Computer Science",
+	"DANC": "Dance",
+	"ECON": "Economics",
+	"EMBA": "Executive Business Administration",
+	"ENGL": "English",
+	"ENGR": "Engineering",
+	"ENGY": "Energy Systems Management",
+	"ENVA": "Environmental Studies",
+	"ENVM": "Environmental Management",
+	"ENVS": "Environmental Science",
+	"FILI": "Filipino",
+	"FREN": "French",
+	"GEDU": "Graduate Education",
+	"GERM": "German",
+	"HEBR": "Hebrew",
+	"HIST": "History",
+	"HONC": "Honors College",
+	"HS":   "Health Informatics",
+	"IME":  "International and Multicultural Education",
+	"INTD": "Interdisciplinary Studies",
+	"ITAL": "Italian",
+	"JAPN": "Japanese",
+	"KIN":  "Kinesiology",
+	"L&I":  "Learning and Instruction",
+	"LAS":  "Latin American Studies",
+	"LAW":  "Law",
+	"MATH": "Mathematics",
+	"MBA":  "Business Administration",
+	"MILS": "Military Science",
+	"MIMS": "Migration Studies",
+	"MPH":  "Master of Public Health",
+	"MPL":  "Public Leadership",
+	"MS":   "Media Studies",
+	"MSAS": "Accounting",
+	"MSDS": "Master of Data Science",
+	"MSEI": "Entrepreneurship and Innovation",
+	"MSIS": "Information Systems",
+	"MSMI": "Marketing Intelligence",
+	"MUS":  "Music",
+	"MUSE": "Museum Studies",
+	"NEUR": "Neuroscience",
+	"NURS": "Nursing",
+	"O&L":  "Organization and Leadership",
+	"OD":   "Organization Development",
+	"PA":   "Public Administration",
+	"PASJ": "Performing Arts and Social Justice",
+	"PC":   "Professional Communication",
+	"PHIL": "Philosophy",
+	"PHYS": "Physics",
+	"POLS": "Politics",
+	"PSYC": "Psychology",
+	"PSYD": "Clinical Psychology",
+	"RHET": "Rhetoric",
+	"SII":  "Saint Ignatius Institute",
+	"SM":   "Sport Management",
+	"SOC":  "Sociology",
+	"SPAN": "Spanish",
+	"STU":  "Study Abroad",
+	"TEC":  "Teacher Education",
+	"THRS": "Theology and Religious Studies",
+	"THTR": "Theater",
+	"UPA":  "Urban and Public Affairs",
+	"YPSP": "Philippine Studies",
+}
+
+// subjectAliases are informal department names people use for a subject.
+var subjectAliases = map[string]string{
+	"comp sci":      "CS",
+	"compsci":       "CS",
+	"maths":         "MATH",
+	"bio":           "BIOL",
+	"psych":         "PSYC",
+	"poli sci":      "POLS",
+	"theatre":       "THTR",
+	"communication": "COMS",
+}
+
+// subjectByName maps lower-cased department names and aliases to subject codes.
+var subjectByName = func() map[string]string {
+	names := make(map[string]string, len(subjectNames)+len(subjectAliases))
+	for code, name := range subjectNames {
+		names[strings.ToLower(name)] = code
+	}
+	for alias, code := range subjectAliases {
+		names[alias] = code
+	}
+	return names
+}()
+
+// subjectNameAlternation is a regular expression alternation of the
+// department names and aliases in subjectByName.
+func subjectNameAlternation() string {
+	names := make([]string, 0, len(subjectByName))
+	for name := range subjectByName {
+		names = append(names, strings.ReplaceAll(regexp.QuoteMeta(name), " ", `\s+`))
+	}
+	// Longer names first, so "Clinical Psychology 801" is not read as "Psychology 801".
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	return strings.Join(names, "|")
+}
+
+// courseCodeBody matches a subject, written as a code or a department name,
+// a course number matching number and an optional section: "CS272",
+// "cs 272", "CS-272", "Computer Science 272", "CS 272-01" or "CS 272 section 1".
+func courseCodeBody(number string) string {
+	return `(?i)\b(` + subjectNameAlternation() + `|[a-z&]{2,5})\s*-?\s*(` + number + `[a-z]?)(?:(?:\s*-\s*|\s+sec(?:tion|\.)?\s*)(\d{1,2}[a-z]?))?\b`
+}
+
+// In free text a course number has three or four digits, so that "CS 2"
+// in "CS 2 times a week" is not a course; alone, any number is accepted.
+var (
+	courseCodePattern      = regexp.MustCompile(courseCodeBody(`\d{3,4}`))
+	wholeCourseCodePattern = regexp.MustCompile(`^\s*` + courseCodeBody(`\d+`) + `\s*$`)
+)
+
+// CourseCode identifies a course, and optionally one section of it.
+type CourseCode struct {
+	Subject string `json:"subject"`
+	Number  string `json:"number"`
+	Section string `json:"section,omitempty"`
+}
+
+func (c CourseCode) String() string {
+	if c.Section == "" {
+		return c.Subject + " " + c.Number
+	}
+	return c.Subject + " " + c.Number + "-" + c.Section
+}
+
+// Filter selects the code's course, or its section, from the schedule.
+func (c CourseCode) Filter() SectionFilter {
+	return SectionFilter{Subject: c.Subject, CourseNumber: c.Number, Section: c.Section}
+}
+
+// ParseCourseCode parses a whole string such as "cs272" or "Computer Science
+// 272-01". Any two to five letter subject code is accepted.
+func ParseCourseCode(s string) (CourseCode, error) {
+	m := wholeCourseCodePattern.FindStringSubmatch(s)
+	if m == nil {
+		return CourseCode{}, fmt.Errorf("course %q must look like \"CS 272\"", s)
+	}
+	subject, ok := subjectByName[normalizeSubjectName(m[1])]
+	if !ok {
+		subject = strings.ToUpper(m[1])
+	}
+	return newCourseCode(subject, m[2], m[3]), nil
+}
+
+// findCourseCodes finds the course codes in free text such as a question.
+// Subject codes must be in the subject dictionary or in subjects, so that
+// "fall 2024" or "room LM 140" is not taken for a course.
+func findCourseCodes(text string, subjects []string) []CourseCode {
+	var codes []CourseCode
+	for _, m := range courseCodePattern.FindAllStringSubmatch(text, -1) {
+		subject, ok := subjectByName[normalizeSubjectName(m[1])]
+		if !ok {
+			subject = strings.ToUpper(m[1])
+			_, ok = subjectNames[subject]
+			ok = ok || slices.Contains(subjects, subject)
+		}
+		if !ok {
+			continue
+		}
+		code := newCourseCode(subject, m[2], m[3])
+		if !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+func newCourseCode(subject, number, section string) CourseCode {
+	section = strings.ToUpper(section)
+	if len(section) > 0 && len(strings.TrimRight(section, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")) == 1 {
+		section = "0" + section // "section 1" is section 01.
+	}
+	return CourseCode{Subject: subject, Number: strings.ToUpper(number), Section: section}
+}
+
+// normalizeSubjectName lower-cases a department name and collapses its spaces.
+func normalizeSubjectName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// lookupCourses returns every schedule row of the given courses, or sections,
+// as JSON documents.
+func lookupCourses(courses []Course, codes []CourseCode) []string {
+	var documents []string
+	for _, code := range codes {
+		for _, course := range FilterSections(courses, code.Filter()) {
+			document, err := json.Marshal(course)
+			if err != nil {
+				continue
+			}
+			documents = append(documents, string(document))
+		}
+	}
+	return documents
+}
+
+// notOfferedMessage says that none of the named courses is in a term's schedule.
+func notOfferedMessage(codes []CourseCode, term Term) string {
+	schedule := "the schedule"
+	if !term.IsZero() {
+		schedule = fmt.Sprintf("the %s schedule", term)
+	}
+	return fmt.Sprintf("There are no sections of %s in %s.", joinCourseCodes(codes), schedule)
+}
+
+// joinCourseCodes lists course codes as "CS 272", "CS 272 and MATH 201" or
+// "CS 110, CS 112 and CS 272".
+func joinCourseCodes(codes []CourseCode) string {
+	names := make([]string, len(codes))
+	for i, code := range codes {
+		names[i] = code.String()
+	}
+	return joinList(names, "and")
+}
+
+// joinList joins items as "A", "A and B" or "A, B and C", with the given conjunction.
+func joinList(items []string, conjunction string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " " + conjunction + " " + items[len(items)-1]
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestParseCourseCode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  CourseCode
+	}{
+		{"CS272", CourseCode{Subject: "CS", Number: "272"}},
+		{"cs 272", CourseCode{Subject: "CS", Number: "272"}},
+		{"CS-272", CourseCode{Subject: "CS", Number: "272"}},
+		{"Computer Science 272", CourseCode{Subject: "CS", Number: "272"}},
+		{"computer  science 272L", CourseCode{Subject: "CS", Number: "272L"}},
+		{"CS 272-01", CourseCode{Subject: "CS", Number: "272", Section: "01"}},
+		{"cs 272 section 1", CourseCode{Subject: "CS", Number: "272", Section: "01"}},
+		{"Clinical Psychology 801", CourseCode{Subject: "PSYD", Number: "801"}},
+		{"L&I 620", CourseCode{Subject: "L&I", Number: "620"}},
+		{"ZZZ 1", CourseCode{Subject: "ZZZ", Number: "1"}},
+	}
+	for _, test := range tests {
+		got, err := ParseCourseCode(test.input)
+		if err != nil || got != test.want {
+			t.Errorf("ParseCourseCode(%q) = %+v, %v; want %+v", test.input, got, err, test.want)
+		}
+	}
+	for _, bad := range []string{"272", "CS", "CS 272 273", "Underwater Basket Weaving 101"} {
+		if _, err := ParseCourseCode(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestFindCourseCodes(t *testing.T) {
+	tests := []struct {
+		question string
+		want     []CourseCode
+	}{
+		{"Tell me about CS 272", []CourseCode{{Subject: "CS", Number: "272"}}},
+		{"who teaches cs272-02 this fall?", []CourseCode{{Subject: "CS", Number: "272", Section: "02"}}},
+		{"Compare Computer Science 110 and math 201", []CourseCode{{Subject: "CS", Number: "110"}, {Subject: "MATH", Number: "201"}}},
+		{"Is BAT 101 full?", []CourseCode{{Subject: "BAT", Number: "101"}}},
+		{"What meets in room LM 140 in fall 2024?", nil},
+		{"Which classes meet at 1030 on Mondays?", nil},
+	}
+	for _, test := range tests {
+		if got := findCourseCodes(test.question, []string{"AAS", "BAT"}); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("findCourseCodes(%q) = %+v, want %+v", test.question, got, test.want)
+		}
+	}
+}
+
+// newCapturingLLM returns an LLM client whose completions answer "ok" and
+// record each request's system message.
+func newCapturingLLM(t *testing.T, systemMessages *[]string) *LLMClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		for _, message := range request.Messages {
+			if message.Role == openai.ChatMessageRoleSystem {
+				*systemMessages = append(*systemMessages, message.Content)
+			}
+		}
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	t.Cleanup(server.Close)
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL + "/v1"
+	return &LLMClient{client: openai.NewClientWithConfig(config), model: openai.GPT4oMini}
+}
+
+func TestChatBotCourseLookup(t *testing.T) {
+	var courses []Course
+	for i := 1; i <= 7; i++ {
+		courses = append(courses, Course{Subject: "CS", CourseNumber: "272", Section: fmt.Sprintf("%02d", i), CRN: fmt.Sprint(41230 + i), Title: "Software Development"})
+	}
+	courses = append(courses, Course{Subject: "CS", CourseNumber: "110", Section: "01", CRN: "41300", Title: "Intro to Computer Science I"})
+
+	var systemMessages []string
+	config := DefaultConfig()
+	config.CSVPath = "Fall 2024 Class Schedule.csv"
+	bot := NewChatBot(config, newCapturingLLM(t, &systemMessages), &MetadataExtractor{courses: courses}, nil, nil, nil, nil)
+
+	answer, err := bot.Ask("Tell me about computer science 272")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if answer.Retrieval != RetrievalDirect || len(answer.Documents) != 7 || len(answer.Citations()) != 7 {
+		t.Errorf("Expected all 7 sections looked up directly, got %s with %d documents", answer.Retrieval, len(answer.Documents))
+	}
+	if len(systemMessages) != 1 || !strings.Contains(systemMessages[0], "all 7 schedule rows of CS 272") || strings.Contains(systemMessages[0], "41300") {
+		t.Errorf("Expected only CS 272's sections given to the LLM, got %q", systemMessages)
+	}
+
+	answer, err = bot.Ask("Who teaches CS 272-03?")
+	if err != nil || len(answer.Documents) != 1 || answer.Citations()[0].CRN != "41233" {
+		t.Errorf("Expected section 03 alone, got %+v, %v", answer.Documents, err)
+	}
+
+	answer, err = bot.Ask("Tell me about CS 999")
+	if err != nil || answer.Text != "There are no sections of CS 999 in the Fall 2024 schedule." || !answer.Grounded {
+		t.Errorf("Expected CS 999 reported as not offered, got %+v, %v", answer, err)
+	}
+	if len(systemMessages) != 2 {
+		t.Errorf("Expected no LLM call for a course that is not offered, got %d calls", len(systemMessages))
+	}
+}
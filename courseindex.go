@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	chroma "github.com/amikos-tech/chroma-go"
+)
+
+// The course collection holds documents at two granularities, told apart by
+// the documentLevel metadata key: one per schedule row and one per course.
+const (
+	documentLevel = "level"
+	levelSection  = "section"
+	levelCourse   = "course"
+)
+
+// courseLevel selects course summaries in a vector store query.
+var courseLevel = map[string]interface{}{documentLevel: levelCourse}
+
+// CourseSummary is the course-level document of the vector index. It
+// summarizes every section of one course, so that a course with a dozen
+// sections is one search result rather than a dozen. Its JSON keys differ
+// from Course's, so it never matches a search for a section's "CRN".
+type CourseSummary struct {
+	Subject      string   `json:"subject"`
+	CourseNumber string   `json:"course_number"`
+	Title        string   `json:"title"`
+	Sections     int      `json:"sections"`
+	CRNs         []string `json:"crns"`
+	Instructors  []string `json:"instructors,omitempty"`
+	Meetings     []string `json:"meetings,omitempty"`
+	Modes        []string `json:"instruction_modes,omitempty"`
+}
+
+// Code returns the course's code.
+func (s CourseSummary) Code() CourseCode {
+	return CourseCode{Subject: s.Subject, Number: s.CourseNumber}
+}
+
+// summarizeCourses builds one summary per course, in order of each course's
+// first schedule row.
+func summarizeCourses(courses []Course) []CourseSummary {
+	var summaries []CourseSummary
+	for _, listing := range groupByCourse(groupSections(courses)) {
+		summary := CourseSummary{Subject: listing.Subject, CourseNumber: listing.CourseNumber, Title: listing.Title, Sections: len(listing.Sections)}
+		for _, section := range listing.Sections {
+			summary.CRNs = append(summary.CRNs, section.CRN)
+			summary.Instructors = appendUnique(summary.Instructors, section.Instructor)
+			summary.Modes = appendUnique(summary.Modes, section.InstructionMode)
+			for _, meeting := range section.Meetings {
+				summary.Meetings = appendUnique(summary.Meetings, meeting.String())
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// appendUnique appends s to list unless it is empty or already there.
+func appendUnique(list []string, s string) []string {
+	if s == "" || slices.Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
+
+// courseDocumentID is the vector store ID of a course's summary.
+func courseDocumentID(code CourseCode) string {
+	return fmt.Sprintf("course-%s-%s", code.Subject, code.Number)
+}
+
+// parseCourseSummary decodes a course summary document, reporting false for
+// other documents such as schedule rows.
+func parseCourseSummary(document string) (CourseSummary, bool) {
+	var summary CourseSummary
+	if err := json.Unmarshal([]byte(document), &summary); err != nil || len(summary.CRNs) == 0 {
+		return CourseSummary{}, false
+	}
+	return summary, true
+}
+
+// addCourseSummaries adds a summary document for every course in courses.
+func addCourseSummaries(ctx context.Context, collection *chroma.Collection, retries int, courses []Course) error {
+	for _, summary := range summarizeCourses(courses) {
+		document, err := json.Marshal(summary)
+		if err != nil {
+			return fmt.Errorf("marshal %s: %w", summary.Code(), err)
+		}
+		metadata := []map[string]interface{}{{documentLevel: levelCourse}}
+		if err := addCourseWithRetry(ctx, collection, retries, metadata, []string{string(document)}, []string{courseDocumentID(summary.Code())}); err != nil {
+			return fmt.Errorf("add %s: %w", summary.Code(), err)
+		}
+	}
+	return nil
+}
+
+// ensureCourseSummaries adds the course summaries to a collection that has
+// none, such as one loaded before summaries existed. It reports whether it
+// added them.
+func ensureCourseSummaries(ctx context.Context, collection *chroma.Collection, retries int, courses []Course) (bool, error) {
+	existing, err := collection.Get(ctx, courseLevel, nil, nil, nil)
+	if err != nil {
+		return false, fmt.Errorf("get course summaries: %w", err)
+	}
+	if len(existing.Ids) > 0 {
+		return false, nil
+	}
+	if err := addCourseSummaries(ctx, collection, retries, courses); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// refreshCourseSummaries rewrites the summaries of the given courses from the
+// new snapshot, deleting those of courses it no longer has.
+func refreshCourseSummaries(ctx context.Context, collection *chroma.Collection, codes []CourseCode, newCourses []Course) error {
+	for _, code := range codes {
+		summaries := summarizeCourses(FilterSections(newCourses, code.Filter()))
+		if len(summaries) == 0 {
+			if _, err := collection.Delete(ctx, []string{courseDocumentID(code)}, nil, nil); err != nil {
+				return fmt.Errorf("delete %s: %w", code, err)
+			}
+			continue
+		}
+		document, err := json.Marshal(summaries[0])
+		if err != nil {
+			return fmt.Errorf("marshal %s: %w", code, err)
+		}
+		metadata := []map[string]interface{}{{documentLevel: levelCourse}}
+		if _, err := collection.Upsert(ctx, nil, metadata, []string{string(document)}, []string{courseDocumentID(code)}); err != nil {
+			return fmt.Errorf("upsert %s: %w", code, err)
+		}
+	}
+	return nil
+}
+
+// expandCourseSummaries replaces course summaries found by a search with the
+// schedule rows of up to limit of those courses, keeping only sections that
+// satisfy the constraint and skipping courses left with none. Without loaded
+// courses to expand them from, the summaries are returned as they are.
+func expandCourseSummaries(documents []string, courses []Course, constraint ScheduleConstraint, limit int) []string {
+	if len(courses) == 0 {
+		return documents[:min(limit, len(documents))]
+	}
+	if !constraint.Empty() {
+		courses = constraint.FilterRows(courses)
+	}
+	var expanded []string
+	found := 0
+	for _, document := range documents {
+		summary, ok := parseCourseSummary(document)
+		if !ok {
+			continue
+		}
+		rows := lookupCourses(courses, []CourseCode{summary.Code()})
+		if len(rows) == 0 {
+			continue
+		}
+		expanded = append(expanded, rows...)
+		if found++; found == limit {
+			break
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	chroma "github.com/amikos-tech/chroma-go"
+	"github.com/amikos-tech/chroma-go/types"
+)
+
+var summaryTestCourses = []Course{
+	{Subject: "CS", CourseNumber: "272", Section: "01", CRN: "40644", Title: "Software Development", MeetDays: "MWF", BeginTime: "0915", EndTime: "1020", Building: "LS", Room: "G12", InstructorFirstName: "Philip", InstructorLastName: "Peterson", InstructionModeDesc: "In-Person"},
+	{Subject: "CS", CourseNumber: "272", Section: "02", CRN: "40645", Title: "Software Development", MeetDays: "TR", BeginTime: "1440", EndTime: "1625", Building: "LS", Room: "G12", InstructorFirstName: "Philip", InstructorLastName: "Peterson", InstructionModeDesc: "In-Person"},
+	{Subject: "CS", CourseNumber: "272", Section: "02", CRN: "40645", Title: "Software Development", MeetDays: "F", BeginTime: "1440", EndTime: "1545", Building: "LS", Room: "G12", InstructorFirstName: "Philip", InstructorLastName: "Peterson", InstructionModeDesc: "In-Person"},
+	{Subject: "MATH", CourseNumber: "201", Section: "01", CRN: "41000", Title: "Discrete Mathematics", InstructionModeDesc: "Online Asynchronous"},
+}
+
+func TestSummarizeCourses(t *testing.T) {
+	want := []CourseSummary{
+		{
+			Subject: "CS", CourseNumber: "272", Title: "Software Development", Sections: 2,
+			CRNs:        []string{"40644", "40645"},
+			Instructors: []string{"Philip Peterson"},
+			Meetings:    []string{"MWF 9:15am-10:20am in LS G12", "TR 2:40pm-4:25pm in LS G12", "F 2:40pm-3:45pm in LS G12"},
+			Modes:       []string{"In-Person"},
+		},
+		{Subject: "MATH", CourseNumber: "201", Title: "Discrete Mathematics", Sections: 1, CRNs: []string{"41000"}, Modes: []string{"Online Asynchronous"}},
+	}
+	if got := summarizeCourses(summaryTestCourses); !reflect.DeepEqual(got, want) {
+		t.Errorf("summarizeCourses() = %+v, want %+v", got, want)
+	}
+}
+
+func TestExpandCourseSummaries(t *testing.T) {
+	var summaries []string
+	for _, summary := range summarizeCourses(summaryTestCourses) {
+		document, _ := json.Marshal(summary)
+		summaries = append(summaries, string(document))
+	}
+	if _, ok := parseCourseSummary(summaries[0]); !ok {
+		t.Errorf("Expected %s to parse as a course summary", summaries[0])
+	}
+	row, _ := json.Marshal(summaryTestCourses[0])
+	if _, ok := parseCourseSummary(string(row)); ok {
+		t.Errorf("Expected a schedule row not to parse as a course summary")
+	}
+	if citations := (Answer{Documents: summaries}).Citations(); len(citations) != 0 {
+		t.Errorf("Expected no citations for course summaries, got %+v", citations)
+	}
+
+	crns := func(documents []string) []string {
+		var got []string
+		for _, citation := range (Answer{Documents: documents}).Citations() {
+			got = append(got, citation.CRN)
+		}
+		return got
+	}
+	if got := crns(expandCourseSummaries(summaries, summaryTestCourses, ScheduleConstraint{}, 5)); !reflect.DeepEqual(got, []string{"40644", "40645", "40645", "41000"}) {
+		t.Errorf("Expected every row of both courses, got %v", got)
+	}
+	if got := crns(expandCourseSummaries(summaries, summaryTestCourses, ScheduleConstraint{}, 1)); !reflect.DeepEqual(got, []string{"40644", "40645", "40645"}) {
+		t.Errorf("Expected only the first course's rows, got %v", got)
+	}
+	// Constrained to Tuesdays, the MATH course has no sections left and is skipped.
+	if got := crns(expandCourseSummaries(summaries, summaryTestCourses, ScheduleConstraint{Days: "T"}, 5)); !reflect.DeepEqual(got, []string{"40645", "40645"}) {
+		t.Errorf("Expected only the Tuesday section, got %v", got)
+	}
+	if got := expandCourseSummaries(summaries, nil, ScheduleConstraint{}, 1); !reflect.DeepEqual(got, summaries[:1]) {
+		t.Errorf("Expected the summaries themselves without loaded courses, got %v", got)
+	}
+}
+
+func TestScheduleDiffCourses(t *testing.T) {
+	diff := ScheduleDiff{
+		Added:     []SectionRef{{CRN: "1", Subject: "CS", CourseNumber: "272"}},
+		Cancelled: []SectionRef{{CRN: "2", Subject: "MATH", CourseNumber: "201"}, {CRN: "3", Subject: "CS", CourseNumber: "272"}},
+		Changed:   []SectionChange{{SectionRef: SectionRef{CRN: "4", Subject: "CS", CourseNumber: "110"}}},
+	}
+	want := []CourseCode{{Subject: "CS", Number: "272"}, {Subject: "MATH", Number: "201"}, {Subject: "CS", Number: "110"}}
+	if got := diff.courses(); !reflect.DeepEqual(got, want) {
+		t.Errorf("courses() = %v, want %v", got, want)
+	}
+}
+
+func TestEnsureCourseSummaries(t *testing.T) {
+	store, collection := newFakeCollection(t)
+	ctx := context.Background()
+	// A collection loaded before course summaries existed has schedule rows only.
+	for i, course := range summaryTestCourses {
+		metadata := []map[string]interface{}{{documentLevel: levelSection}}
+		if _, err := collection.Add(ctx, nil, metadata, []string{courseDocument(t, course)}, []string{fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	added, err := ensureCourseSummaries(ctx, collection, 1, summaryTestCourses)
+	if err != nil || !added {
+		t.Fatalf("Expected the missing summaries added, got %v, %v", added, err)
+	}
+	if got := store.ids(); !reflect.DeepEqual(got, []string{"0", "1", "2", "3", "course-CS-272", "course-MATH-201"}) {
+		t.Errorf("Expected the rows and one summary per course, got %v", got)
+	}
+
+	added, err = ensureCourseSummaries(ctx, collection, 1, summaryTestCourses)
+	if err != nil || added {
+		t.Errorf("Expected nothing added when summaries exist, got %v, %v", added, err)
+	}
+}
+
+// fakeChroma is an in-memory stand-in for one ChromaDB collection. It
+// supports adding, upserting, counting, getting and deleting documents, with
+// equality where filters and $contains document filters.
+type fakeChroma struct {
+	mu        sync.Mutex
+	documents map[string]string
+	metadatas map[string]map[string]interface{}
+}
+
+// newFakeCollection serves a fakeChroma and returns a collection backed by it.
+func newFakeCollection(t *testing.T) (*fakeChroma, *chroma.Collection) {
+	t.Helper()
+	store := &fakeChroma{documents: map[string]string{}, metadatas: map[string]map[string]interface{}{}}
+	server := httptest.NewServer(store)
+	t.Cleanup(server.Close)
+	client, err := chroma.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection := chroma.NewCollection(client.ApiClient, "fake", "fake", nil, types.NewConsistentHashEmbeddingFunction(), types.DefaultTenant, types.DefaultDatabase)
+	return store, collection
+}
+
+// ids returns the stored document IDs in order.
+func (f *fakeChroma) ids() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sortedIDs()
+}
+
+func (f *fakeChroma) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs           []string                 `json:"ids"`
+		Documents     []string                 `json:"documents"`
+		Metadatas     []map[string]interface{} `json:"metadatas"`
+		Where         map[string]interface{}   `json:"where"`
+		WhereDocument map[string]interface{}   `json:"where_document"`
+	}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var response interface{}
+	switch operation := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]; operation {
+	case "add", "upsert":
+		for i, id := range req.IDs {
+			if _, ok := f.documents[id]; ok && operation == "add" {
+				continue // ChromaDB ignores adds of existing IDs.
+			}
+			f.documents[id] = req.Documents[i]
+			f.metadatas[id] = nil
+			if i < len(req.Metadatas) {
+				f.metadatas[id] = req.Metadatas[i]
+			}
+		}
+		response = true
+	case "count":
+		response = len(f.documents)
+	case "get", "delete":
+		result := struct {
+			IDs       []string                 `json:"ids"`
+			Documents []string                 `json:"documents"`
+			Metadatas []map[string]interface{} `json:"metadatas"`
+		}{IDs: []string{}, Documents: []string{}, Metadatas: []map[string]interface{}{}}
+		for _, id := range f.sortedIDs() {
+			if !f.matches(id, req.IDs, req.Where, req.WhereDocument) {
+				continue
+			}
+			result.IDs = append(result.IDs, id)
+			result.Documents = append(result.Documents, f.documents[id])
+			result.Metadatas = append(result.Metadatas, f.metadatas[id])
+		}
+		response = result
+		if operation == "delete" {
+			for _, id := range result.IDs {
+				delete(f.documents, id)
+				delete(f.metadatas, id)
+			}
+			response = result.IDs
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeChroma) sortedIDs() []string {
+	ids := make([]string, 0, len(f.documents))
+	for id := range f.documents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (f *fakeChroma) matches(id string, ids []string, where, whereDocument map[string]interface{}) bool {
+	if len(ids) > 0 && !slices.Contains(ids, id) {
+		return false
+	}
+	for key, value := range where {
+		if f.metadatas[id][key] != value {
+			return false
+		}
+	}
+	if contains, ok := whereDocument["$contains"].(string); ok && !strings.Contains(f.documents[id], contains) {
+		return false
+	}
+	return true
+}
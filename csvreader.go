@@ -1,13 +1,5 @@
 package main
 
-import (
-    "io"
-    "github.com/gocarina/gocsv"
-    "log"
-    "encoding/csv"
-)
-
-
 // Course represents a record in the CSV file with csv tags for each field
 type Course struct {
     Subject                   string `csv:"SUBJ"`
@@ -31,24 +23,6 @@ type Course struct {
     InstructorLastName        string `csv:"Primary Instructor Last Name"`
     InstructorEmail           string `csv:"Primary Instructor Email"`
     College                   string `csv:"College"`
-}
-
-func ReadCSV(file io.Reader) ([]Course, error) {
-    // Set up gocsv to use a custom CSV reader with LazyQuotes enabled
-    gocsv.SetCSVReader(func(in io.Reader) gocsv.CSVReader {
-        reader := csv.NewReader(in)
-        reader.Comma = '\t'          // Set to tab-delimited
-        reader.LazyQuotes = true     // Enable lazy quotes to handle unescaped quotes
-        reader.FieldsPerRecord = -1  // Allow variable field counts if needed
-        return reader
-    })
-
-    var courses []Course
-    if err := gocsv.Unmarshal(file, &courses); err != nil {
-        log.Printf("Failed to unmarshal CSV file: %v", err)
-        return nil, err
-    }
-
-    log.Printf("Successfully read %d records from CSV.", len(courses))
-    return courses, nil
+    Term                      string `csv:"-"` // Set when the schedule is loaded as one of several terms
+    Extra                     map[string]string `csv:"-" json:",omitempty"` // Columns a column mapping keeps beyond the fields above, by name
 }
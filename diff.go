@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	chroma "github.com/amikos-tech/chroma-go"
+)
+
+// Fields compared between two schedule snapshots.
+const (
+	diffInstructor = "instructor"
+	diffRoom       = "room"
+	diffTime       = "time"
+	diffEnrollment = "enrollment"
+)
+
+// SectionRef identifies a section in a schedule diff.
+type SectionRef struct {
+	CRN          string `json:"crn"`
+	Subject      string `json:"subject"`
+	CourseNumber string `json:"course_number"`
+	Section      string `json:"section"`
+	Title        string `json:"title"`
+}
+
+func (r SectionRef) String() string {
+	return fmt.Sprintf("CRN %s %s %s-%s %s", r.CRN, r.Subject, r.CourseNumber, r.Section, r.Title)
+}
+
+// FieldChange is one field of a section that differs between snapshots.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// SectionChange lists what changed about a section present in both snapshots.
+type SectionChange struct {
+	SectionRef
+	Changes []FieldChange `json:"changes"`
+}
+
+// ScheduleDiff is the difference between two schedule snapshots, by CRN.
+type ScheduleDiff struct {
+	Added     []SectionRef    `json:"added"`
+	Cancelled []SectionRef    `json:"cancelled"`
+	Changed   []SectionChange `json:"changed"`
+}
+
+// Empty reports whether the snapshots have no differences.
+func (d ScheduleDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Cancelled) == 0 && len(d.Changed) == 0
+}
+
+// courses lists the courses that have added, cancelled or changed sections.
+func (d ScheduleDiff) courses() []CourseCode {
+	refs := append(append([]SectionRef{}, d.Added...), d.Cancelled...)
+	for _, change := range d.Changed {
+		refs = append(refs, change.SectionRef)
+	}
+	var codes []CourseCode
+	for _, ref := range refs {
+		if code := (CourseCode{Subject: ref.Subject, Number: ref.CourseNumber}); !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// DiffSchedules compares two schedule snapshots by CRN. Results are sorted by CRN.
+func DiffSchedules(oldCourses, newCourses []Course) ScheduleDiff {
+	oldSections := indexSections(oldCourses)
+	newSections := indexSections(newCourses)
+	diff := ScheduleDiff{Added: []SectionRef{}, Cancelled: []SectionRef{}, Changed: []SectionChange{}}
+
+	for crn, section := range newSections {
+		old, ok := oldSections[crn]
+		if !ok {
+			diff.Added = append(diff.Added, sectionRef(section))
+			continue
+		}
+		if changes := compareSections(old, section); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, SectionChange{SectionRef: sectionRef(section), Changes: changes})
+		}
+	}
+	for crn, section := range oldSections {
+		if _, ok := newSections[crn]; !ok {
+			diff.Cancelled = append(diff.Cancelled, sectionRef(section))
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].CRN < diff.Added[j].CRN })
+	sort.Slice(diff.Cancelled, func(i, j int) bool { return diff.Cancelled[i].CRN < diff.Cancelled[j].CRN })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].CRN < diff.Changed[j].CRN })
+	return diff
+}
+
+// indexSections groups schedule rows into sections keyed by CRN.
+func indexSections(courses []Course) map[string]ClassSection {
+	sections := make(map[string]ClassSection)
+	for _, section := range groupSections(courses) {
+		sections[section.CRN] = section
+	}
+	return sections
+}
+
+func sectionRef(section ClassSection) SectionRef {
+	return SectionRef{
+		CRN:          section.CRN,
+		Subject:      section.Subject,
+		CourseNumber: section.CourseNumber,
+		Section:      section.Section,
+		Title:        section.Title,
+	}
+}
+
+// compareSections lists the instructor, room, time and enrollment changes
+// between two snapshots of a section.
+func compareSections(old, current ClassSection) []FieldChange {
+	var changes []FieldChange
+	for _, field := range []struct {
+		name string
+		get  func(ClassSection) string
+	}{
+		{diffInstructor, func(s ClassSection) string { return s.Instructor }},
+		{diffRoom, sectionRooms},
+		{diffTime, sectionTimes},
+		{diffEnrollment, func(s ClassSection) string { return strconv.Itoa(sectionEnrollment(s)) }},
+	} {
+		if before, after := field.get(old), field.get(current); before != after {
+			changes = append(changes, FieldChange{Field: field.name, Old: before, New: after})
+		}
+	}
+	return changes
+}
+
+// sectionRooms lists a section's distinct rooms, e.g. "HR 430, LM 140".
+func sectionRooms(section ClassSection) string {
+	var rooms []string
+	for _, row := range section.Rows {
+		if room := newRoomKey(row.Building, row.Room).String(); room != "" && !slices.Contains(rooms, room) {
+			rooms = append(rooms, room)
+		}
+	}
+	sort.Strings(rooms)
+	return strings.Join(rooms, ", ")
+}
+
+// sectionTimes lists a section's distinct meeting times, e.g. "MW 4:45pm-6:25pm".
+func sectionTimes(section ClassSection) string {
+	var times []string
+	for _, m := range section.Meetings {
+		t := fmt.Sprintf("%s %s-%s", m.Days, formatClock(m.Start), formatClock(m.End))
+		if !slices.Contains(times, t) {
+			times = append(times, t)
+		}
+	}
+	sort.Strings(times)
+	return strings.Join(times, ", ")
+}
+
+// String renders the diff for people to read.
+func (d ScheduleDiff) String() string {
+	if d.Empty() {
+		return "No changes.\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d added, %d cancelled, %d changed\n", len(d.Added), len(d.Cancelled), len(d.Changed))
+	if len(d.Added) > 0 {
+		b.WriteString("\nAdded:\n")
+		for _, ref := range d.Added {
+			fmt.Fprintf(&b, "  + %s\n", ref)
+		}
+	}
+	if len(d.Cancelled) > 0 {
+		b.WriteString("\nCancelled:\n")
+		for _, ref := range d.Cancelled {
+			fmt.Fprintf(&b, "  - %s\n", ref)
+		}
+	}
+	if len(d.Changed) > 0 {
+		b.WriteString("\nChanged:\n")
+		for _, change := range d.Changed {
+			fmt.Fprintf(&b, "  ~ %s\n", change.SectionRef)
+			for _, field := range change.Changes {
+				fmt.Fprintf(&b, "      %s: %q -> %q\n", field.Field, field.Old, field.New)
+			}
+		}
+	}
+	return b.String()
+}
+
+// ApplyDiff updates the course collection to match the new snapshot without
+// reloading it: documents of cancelled, added and changed sections are
+// deleted, and the new snapshot's rows for added and changed sections are
+// upserted, so applying the same diff twice leaves the same collection. The
+// summaries of the courses those sections belong to are rewritten, and the
+// instructor collection's profiles are brought in line with the new snapshot.
+func ApplyDiff(ctx context.Context, config Config, diff ScheduleDiff, newCourses []Course, courseCollection, instructorCollection *chroma.Collection) error {
+	remove := make([]string, 0, len(diff.Cancelled)+len(diff.Added)+len(diff.Changed))
+	for _, ref := range diff.Cancelled {
+		remove = append(remove, ref.CRN)
+	}
+	update := make(map[string]bool)
+	for _, ref := range diff.Added {
+		// An earlier run may have added the section already, with more rows than it has now.
+		remove = append(remove, ref.CRN)
+		update[ref.CRN] = true
+	}
+	for _, change := range diff.Changed {
+		remove = append(remove, change.CRN)
+		update[change.CRN] = true
+	}
+
+	for _, crn := range remove {
+		// Course documents are the JSON encoding of Course, so the CRN appears as "CRN":"<crn>".
+		// This deletes both the row-numbered documents of a full load and the crn-<CRN>-<n> ones.
+		contains := map[string]interface{}{"$contains": fmt.Sprintf(`"CRN":%q`, crn)}
+		if _, err := courseCollection.Delete(ctx, nil, nil, contains); err != nil {
+			return fmt.Errorf("delete CRN %s: %w", crn, err)
+		}
+	}
+
+	instructors := InitializeInstructors()
+	rowsAdded := make(map[string]int)
+	for _, course := range newCourses {
+		if !update[course.CRN] {
+			continue
+		}
+		name := courseInstructor(course, instructors)
+
+		document, err := json.Marshal(course)
+		if err != nil {
+			return fmt.Errorf("marshal CRN %s: %w", course.CRN, err)
+		}
+		// IDs from a full load are row numbers, which shift between snapshots,
+		// so incremental documents are keyed by CRN and row within the section.
+		id := fmt.Sprintf("crn-%s-%d", course.CRN, rowsAdded[course.CRN])
+		rowsAdded[course.CRN]++
+		metadata := []map[string]interface{}{{"instructor_canonical_name": name, documentLevel: levelSection}}
+		if err := upsertCourseWithRetry(ctx, courseCollection, config.AddRetries, metadata, []string{string(document)}, []string{id}); err != nil {
+			return fmt.Errorf("upsert CRN %s: %w", course.CRN, err)
+		}
+	}
+
+	if err := refreshCourseSummaries(ctx, courseCollection, diff.courses(), newCourses); err != nil {
+		return err
+	}
+
+	return syncInstructorProfiles(ctx, instructorCollection, newCourses)
+}
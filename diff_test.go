@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffSchedules(t *testing.T) {
+	oldCourses := []Course{testCourses[0], testCourses[1], plannerCourses[0]}
+
+	moved := testCourses[0]
+	moved.Building, moved.Room = "KA", "311"
+	moved.BeginTime, moved.EndTime = "1800", "1940"
+	moved.ActualEnrollment = "32"
+	newInstructor := testCourses[1]
+	newInstructor.InstructorFirstName, newInstructor.InstructorLastName = "Greg", "Benson"
+	newCourses := []Course{moved, newInstructor, plannerCourses[1]}
+
+	diff := DiffSchedules(oldCourses, newCourses)
+	if len(diff.Added) != 1 || diff.Added[0].CRN != "1002" {
+		t.Errorf("Expected CRN 1002 to be added, got %v", diff.Added)
+	}
+	if len(diff.Cancelled) != 1 || diff.Cancelled[0].CRN != "1001" {
+		t.Errorf("Expected CRN 1001 to be cancelled, got %v", diff.Cancelled)
+	}
+	if len(diff.Changed) != 2 {
+		t.Fatalf("Expected 2 changed sections, got %v", diff.Changed)
+	}
+
+	fields := map[string]FieldChange{}
+	for _, change := range diff.Changed[0].Changes {
+		fields[change.Field] = change
+	}
+	if diff.Changed[0].CRN != "42180" || len(fields) != 3 {
+		t.Fatalf("Expected room, time and enrollment changes to CRN 42180, got %+v", diff.Changed[0])
+	}
+	if fields[diffRoom].Old != "LM 140" || fields[diffRoom].New != "KA 311" {
+		t.Errorf("Unexpected room change %+v", fields[diffRoom])
+	}
+	if fields[diffTime].New != "MW 6:00pm-7:40pm" || fields[diffEnrollment].New != "32" {
+		t.Errorf("Unexpected time or enrollment change %+v", fields)
+	}
+	if change := diff.Changed[1].Changes; len(change) != 1 || change[0].Field != diffInstructor || change[0].New != "Gregory Benson" {
+		t.Errorf("Expected the instructor of CRN 99999 to change to Gregory Benson, got %+v", change)
+	}
+
+	text := diff.String()
+	for _, want := range []string{"1 added, 1 cancelled, 2 changed", "+ CRN 1002", "- CRN 1001", `room: "LM 140" -> "KA 311"`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in:\n%s", want, text)
+		}
+	}
+}
+
+func TestDiffSchedulesUnchanged(t *testing.T) {
+	diff := DiffSchedules(testCourses, testCourses)
+	if !diff.Empty() || diff.String() != "No changes.\n" {
+		t.Errorf("Expected no changes, got %+v", diff)
+	}
+}
+
+func TestApplyDiffIdempotent(t *testing.T) {
+	oldCourses := []Course{testCourses[0], testCourses[1]}
+	moved := testCourses[0]
+	moved.Building, moved.Room = "KA", "311"
+	newCourses := []Course{moved, plannerCourses[1]}
+	diff := DiffSchedules(oldCourses, newCourses)
+
+	store, courseCollection := newFakeCollection(t)
+	_, instructorCollection := newFakeCollection(t)
+	ctx := context.Background()
+	for i, course := range oldCourses {
+		metadata := []map[string]interface{}{{documentLevel: levelSection}}
+		if _, err := courseCollection.Add(ctx, nil, metadata, []string{courseDocument(t, course)}, []string{fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A row an earlier run added for the new section, which it no longer has.
+	added := plannerCourses[1]
+	if _, err := courseCollection.Add(ctx, nil, nil, []string{courseDocument(t, added)}, []string{"crn-" + added.CRN + "-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var first []string
+	for run := 0; run < 2; run++ {
+		if err := ApplyDiff(ctx, DefaultConfig(), diff, newCourses, courseCollection, instructorCollection); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		ids := store.ids()
+		for _, id := range ids {
+			if document := store.documents[id]; strings.Contains(document, `"CRN":"`+testCourses[1].CRN+`"`) || id == "crn-"+added.CRN+"-1" {
+				t.Errorf("Run %d: expected %s removed, got %s", run+1, id, document)
+			}
+		}
+		if !strings.Contains(store.documents["crn-"+moved.CRN+"-0"], `"Room":"311"`) {
+			t.Errorf("Run %d: expected the moved section's new row, got %v", run+1, ids)
+		}
+		if run == 0 {
+			first = ids
+		} else if !reflect.DeepEqual(ids, first) {
+			t.Errorf("Expected applying the diff again to change nothing, got %v then %v", first, ids)
+		}
+	}
+}
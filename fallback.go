@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FallbackPolicy controls how the chatbot responds when retrieval finds no
+// catalog documents for a question.
+type FallbackPolicy int
+
+const (
+	// FallbackRefuse declines to answer and asks the user to rephrase.
+	FallbackRefuse FallbackPolicy = iota
+	// FallbackSuggest declines to answer but lists close catalog matches
+	// found by fuzzy title and subject search.
+	FallbackSuggest
+	// FallbackGeneral answers from the model's general knowledge with a
+	// disclaimer that the answer is not grounded in the catalog.
+	FallbackGeneral
+)
+
+// maxSuggestions caps how many close matches FallbackSuggest lists.
+const maxSuggestions = 5
+
+// String returns the name used for the policy in configuration and answers.
+func (p FallbackPolicy) String() string {
+	switch p {
+	case FallbackRefuse:
+		return "refuse"
+	case FallbackSuggest:
+		return "suggest"
+	case FallbackGeneral:
+		return "general"
+	}
+	return fmt.Sprintf("FallbackPolicy(%d)", int(p))
+}
+
+// ParseFallbackPolicy converts a policy name such as "suggest" into a FallbackPolicy.
+func ParseFallbackPolicy(name string) (FallbackPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "refuse":
+		return FallbackRefuse, nil
+	case "suggest":
+		return FallbackSuggest, nil
+	case "general":
+		return FallbackGeneral, nil
+	}
+	return FallbackRefuse, fmt.Errorf("unknown fallback policy %q (want refuse, suggest or general)", name)
+}
+
+// Answer is the result of answering a question, including whether it was
+// grounded in retrieved catalog documents or produced by a fallback policy.
+type Answer struct {
+	Text      string
+	Grounded  bool
+	Policy    FallbackPolicy // Only meaningful when Grounded is false.
+	Documents []string       // Catalog documents the answer was grounded in.
+	Retrieval string         // RetrievalVector, RetrievalLexical, RetrievalAnalytics, RetrievalDirect or RetrievalListing.
+	Usage     Usage          // Tokens spent by the LLM producing the answer.
+}
+
+// Retrieval methods reported in Answer.Retrieval.
+const (
+	RetrievalVector    = "vector"
+	RetrievalLexical   = "lexical"
+	RetrievalAnalytics = "analytics" // Computed from enrollment numbers rather than retrieved.
+	RetrievalDirect    = "direct"    // Every section of the courses a question names, looked up by code.
+	RetrievalListing   = "listing"   // Every section matching an enumeration question, listed from the schedule.
+)
+
+// generalKnowledgeDisclaimer prefixes answers produced by FallbackGeneral.
+const generalKnowledgeDisclaimer = "Note: nothing in the course catalog matched this question, so this answer comes from general knowledge and may not reflect actual course offerings."
+
+// policyNote tells the user which fallback policy produced an answer.
+func policyNote(policy FallbackPolicy) string {
+	return fmt.Sprintf("(fallback policy: %s)", policy)
+}
+
+// refusalMessage is the answer given by FallbackRefuse.
+func refusalMessage() string {
+	return "I couldn't find anything in the course catalog that matches your question. " +
+		"Try including a course title, subject code (for example CS or PHIL), or instructor name.\n" +
+		policyNote(FallbackRefuse)
+}
+
+// suggestionMessage is the answer given by FallbackSuggest.
+func suggestionMessage(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return "I couldn't find anything in the course catalog that matches your question, " +
+			"and no similar course titles or subjects turned up either.\n" + policyNote(FallbackSuggest)
+	}
+
+	var b strings.Builder
+	b.WriteString("I couldn't find an exact match in the course catalog. Did you mean one of these?\n")
+	for _, s := range suggestions {
+		b.WriteString(fmt.Sprintf("- %s\n", s))
+	}
+	b.WriteString(policyNote(FallbackSuggest))
+	return b.String()
+}
+
+// suggestCourses returns up to limit course descriptions whose title or
+// subject closely matches words in the question.
+func suggestCourses(question string, courses []Course, limit int) []string {
+	words := significantWords(question)
+	if len(words) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		label string
+		score int
+	}
+	best := make(map[string]int)
+	for _, course := range courses {
+		score := 0
+		for _, w := range words {
+			if strings.EqualFold(w, course.Subject) {
+				score += 3
+			}
+			for _, t := range significantWords(course.Title) {
+				if fuzzyWordMatch(w, t) {
+					score += 2
+				}
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		label := fmt.Sprintf("%s %s %s", course.Subject, course.CourseNumber, strings.TrimSpace(course.Title))
+		if score > best[label] {
+			best[label] = score
+		}
+	}
+
+	ranked := make([]scored, 0, len(best))
+	for label, score := range best {
+		ranked = append(ranked, scored{label, score})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].label < ranked[j].label
+	})
+
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	suggestions := make([]string, len(ranked))
+	for i, r := range ranked {
+		suggestions[i] = r.label
+	}
+	return suggestions
+}
+
+// stopWords are common question words that carry no catalog meaning.
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "what": true, "which": true,
+	"who": true, "where": true, "when": true, "can": true, "take": true, "this": true,
+	"that": true, "with": true, "any": true, "there": true, "course": true, "courses": true,
+	"class": true, "classes": true, "semester": true, "offered": true, "does": true,
+	"how": true, "about": true, "learn": true, "want": true, "would": true, "like": true,
+}
+
+// significantWords lowercases text and returns its words, minus punctuation,
+// stop words and very short words.
+func significantWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	words := fields[:0]
+	for _, f := range fields {
+		if len(f) >= 2 && !stopWords[f] {
+			words = append(words, f)
+		}
+	}
+	return words
+}
+
+// fuzzyWordMatch reports whether two lowercase words are the same modulo a
+// shared prefix (e.g. "philosophy" and "philosoph") or a small typo.
+func fuzzyWordMatch(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if len(a) < 4 || len(b) < 4 {
+		return false
+	}
+	if strings.HasPrefix(a, b) || strings.HasPrefix(b, a) {
+		return true
+	}
+	maxDist := 1
+	if len(a) >= 8 {
+		maxDist = 2
+	}
+	return levenshtein(a, b) <= maxDist
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
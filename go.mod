@@ -4,7 +4,6 @@ go 1.22.3
 
 require (
 	github.com/amikos-tech/chroma-go v0.1.4
-	github.com/gocarina/gocsv v0.0.0-20240520201108-78e41c74b4b1
 	github.com/sashabaranov/go-openai v1.35.6
 )
 
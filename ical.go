@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	_ "time/tzdata" // The calendar's time zone must load on hosts without zoneinfo.
+)
+
+// calendarTimeZone is the time zone schedule times are given in.
+const calendarTimeZone = "America/Los_Angeles"
+
+// calendarVTimeZone describes calendarTimeZone for calendar clients, using
+// the US daylight saving rules in effect since 2007.
+const calendarVTimeZone = `BEGIN:VTIMEZONE
+TZID:America/Los_Angeles
+X-LIC-LOCATION:America/Los_Angeles
+BEGIN:DAYLIGHT
+TZOFFSETFROM:-0800
+TZOFFSETTO:-0700
+TZNAME:PDT
+DTSTART:19700308T020000
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU
+END:DAYLIGHT
+BEGIN:STANDARD
+TZOFFSETFROM:-0700
+TZOFFSETTO:-0800
+TZNAME:PST
+DTSTART:19701101T020000
+RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU
+END:STANDARD
+END:VTIMEZONE`
+
+// calendarDays maps schedule day letters to iCalendar BYDAY codes.
+var calendarDays = map[rune]string{
+	'M': "MO", 'T': "TU", 'W': "WE", 'R': "TH", 'F': "FR", 'S': "SA", 'U': "SU",
+}
+
+// dayLetter returns the schedule day letter for a weekday.
+func dayLetter(day time.Weekday) rune {
+	return rune("UMTWRFS"[day])
+}
+
+// WriteICalendar writes the sections as an RFC 5545 calendar, one weekly
+// recurring event per meeting. Meetings without term dates cannot be placed
+// on a calendar and are left out. It returns the number of events written.
+func WriteICalendar(w io.Writer, sections []ClassSection, now time.Time) (int, error) {
+	location, err := time.LoadLocation(calendarTimeZone)
+	if err != nil {
+		return 0, fmt.Errorf("load time zone: %w", err)
+	}
+
+	out := bufio.NewWriter(w)
+	line := func(s string) {
+		out.WriteString(foldICalLine(s))
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//course-catalog//schedule export//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	for _, l := range strings.Split(calendarVTimeZone, "\n") {
+		line(l)
+	}
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	events := 0
+	for _, section := range sections {
+		for i, meeting := range section.Meetings {
+			first, ok := firstMeetingDay(meeting)
+			if !ok {
+				continue
+			}
+			events++
+			line("BEGIN:VEVENT")
+			line(fmt.Sprintf("UID:%s-%d@course-catalog", section.CRN, i+1))
+			line("DTSTAMP:" + stamp)
+			line(fmt.Sprintf("DTSTART;TZID=%s:%s", calendarTimeZone, icalLocalTime(first, meeting.Start)))
+			line(fmt.Sprintf("DTEND;TZID=%s:%s", calendarTimeZone, icalLocalTime(first, meeting.End)))
+			line("RRULE:" + recurrenceRule(meeting, location))
+			line("SUMMARY:" + escapeICalText(fmt.Sprintf("%s %s-%s %s", section.Subject, section.CourseNumber, section.Section, section.Title)))
+			if where := strings.TrimSpace(meeting.Building + " " + meeting.Room); where != "" {
+				line("LOCATION:" + escapeICalText(where))
+			}
+			line("DESCRIPTION:" + escapeICalText(sectionDescription(section)))
+			line("END:VEVENT")
+		}
+	}
+	line("END:VCALENDAR")
+	return events, out.Flush()
+}
+
+// firstMeetingDay returns the first date on or after the meeting's start
+// date that falls on one of its days.
+func firstMeetingDay(meeting Meeting) (time.Time, bool) {
+	if meeting.FirstDay.IsZero() || meeting.Days == "" {
+		return time.Time{}, false
+	}
+	day := meeting.FirstDay
+	for i := 0; i < 7; i++ {
+		if strings.ContainsRune(meeting.Days, dayLetter(day.Weekday())) {
+			return day, true
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}, false
+}
+
+// recurrenceRule builds the weekly RRULE for a meeting. UNTIL must be in UTC
+// when DTSTART has a time zone, so the end of the last day is converted.
+func recurrenceRule(meeting Meeting, location *time.Location) string {
+	days := make([]string, 0, len(meeting.Days))
+	for _, day := range meeting.Days {
+		days = append(days, calendarDays[day])
+	}
+	rule := "FREQ=WEEKLY;BYDAY=" + strings.Join(days, ",")
+	if !meeting.LastDay.IsZero() {
+		last := meeting.LastDay
+		until := time.Date(last.Year(), last.Month(), last.Day(), 23, 59, 59, 0, location)
+		rule += ";UNTIL=" + until.UTC().Format("20060102T150405Z")
+	}
+	return rule
+}
+
+// icalLocalTime formats a date and minutes after midnight as a local
+// iCalendar date-time, e.g. "20240820T164500".
+func icalLocalTime(day time.Time, minutes int) string {
+	return fmt.Sprintf("%sT%02d%02d00", day.Format("20060102"), minutes/60, minutes%60)
+}
+
+// sectionDescription lists the CRN, instructor and instruction mode of a section.
+func sectionDescription(section ClassSection) string {
+	lines := []string{"CRN " + section.CRN}
+	if section.Instructor != "" {
+		lines = append(lines, "Instructor: "+section.Instructor)
+	}
+	if section.InstructionMode != "" {
+		lines = append(lines, "Instruction mode: "+section.InstructionMode)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeICalText escapes a TEXT value as RFC 5545 section 3.3.11 requires.
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICalLine ends a content line with CRLF, folding it so no line is
+// longer than 75 octets without splitting a UTF-8 character.
+func foldICalLine(s string) string {
+	const limit = 75
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// sectionsByCRN returns the sections with the given CRNs, in the order given,
+// and the CRNs that match no section.
+func sectionsByCRN(courses []Course, crns []string) ([]ClassSection, []string) {
+	all := groupSections(courses)
+	var found []ClassSection
+	var missing []string
+	for _, crn := range crns {
+		crn = strings.TrimSpace(crn)
+		matched := false
+		for _, section := range all {
+			if section.CRN == crn {
+				found = append(found, section)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			missing = append(missing, crn)
+		}
+	}
+	return found, missing
+}
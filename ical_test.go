@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteICalendar(t *testing.T) {
+	sections := groupSections(testCourses[:1])
+	sections = append(sections, groupSections(plannerCourses[6:7])...) // Asynchronous, so no event.
+
+	var b strings.Builder
+	events, err := WriteICalendar(&b, sections, time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("WriteICalendar failed: %v", err)
+	}
+	if events != 1 {
+		t.Errorf("Expected 1 event, got %d", events)
+	}
+
+	calendar := b.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"TZID:America/Los_Angeles\r\n",
+		"DTSTAMP:20240801T120000Z\r\n",
+		// 8/20/24 is a Tuesday, so the first MW meeting is Wednesday 8/21.
+		"DTSTART;TZID=America/Los_Angeles:20240821T164500\r\n",
+		"DTEND;TZID=America/Los_Angeles:20240821T182500\r\n",
+		// The last day ends at midnight Pacific standard time, 07:59:59 UTC.
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20241205T075959Z\r\n",
+		"SUMMARY:AAS 100-01 Black Activists & Visionaries\r\n",
+		"LOCATION:LM 140\r\n",
+		`DESCRIPTION:CRN 42180\nInstructor: Sheryl Davis\n`,
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(calendar, want) {
+			t.Errorf("Expected calendar to contain %q, got:\n%s", want, calendar)
+		}
+	}
+}
+
+func TestICalTextEscapingAndFolding(t *testing.T) {
+	if got := escapeICalText("a,b;c\\d\ne"); got != `a\,b\;c\\d\ne` {
+		t.Errorf("Unexpected escaping %q", got)
+	}
+	folded := foldICalLine("DESCRIPTION:" + strings.Repeat("é", 80))
+	for _, line := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Expected lines of at most 75 octets, got %d", len(line))
+		}
+	}
+}
+
+func TestServerCalendar(t *testing.T) {
+	server := newTestServer()
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/calendar?crn=42180,99999", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("Expected a text/calendar response, got %q", ct)
+	}
+	if n := strings.Count(rec.Body.String(), "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("Expected 2 events, got %d", n)
+	}
+
+	getJSON(t, server, http.MethodGet, "/calendar?crn=12345", "", http.StatusNotFound)
+	getJSON(t, server, http.MethodGet, "/calendar", "", http.StatusBadRequest)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	chroma "github.com/amikos-tech/chroma-go"
+)
+
+// InstructorProfile is the instructors collection's document for one
+// instructor: what, when and where they teach in the term. Its JSON keys are
+// lower case, so it never matches a search for a section's "CRN".
+type InstructorProfile struct {
+	Name        string           `json:"name"`
+	Email       string           `json:"email,omitempty"`
+	Departments []string         `json:"departments"`
+	Colleges    []string         `json:"colleges"`
+	Courses     []string         `json:"courses"`
+	Sections    []ProfileSection `json:"sections"`
+	Buildings   []string         `json:"buildings"`
+	Enrollment  int              `json:"total_enrollment"`
+}
+
+// ProfileSection is one section an instructor teaches.
+type ProfileSection struct {
+	CRN        string   `json:"crn"`
+	Course     string   `json:"course"` // Subject, number and section, e.g. "CS 272-03".
+	Title      string   `json:"title"`
+	Meetings   []string `json:"meetings,omitempty"`
+	Mode       string   `json:"instruction_mode,omitempty"`
+	Enrollment int      `json:"enrollment"`
+}
+
+// instructorProfiles builds a profile for every instructor in courses,
+// sorted by canonical name. Sections without an instructor are left out.
+func instructorProfiles(courses []Course) []InstructorProfile {
+	instructors := InitializeInstructors()
+	rows := make(map[string][]Course)
+	var names []string
+	for _, course := range courses {
+		name := courseInstructor(course, instructors)
+		if name == "" {
+			continue
+		}
+		if _, ok := rows[name]; !ok {
+			names = append(names, name)
+		}
+		rows[name] = append(rows[name], course)
+	}
+	sort.Strings(names)
+
+	profiles := make([]InstructorProfile, 0, len(names))
+	for _, name := range names {
+		profile := InstructorProfile{Name: name, Departments: []string{}, Colleges: []string{}, Courses: []string{}, Sections: []ProfileSection{}, Buildings: []string{}}
+		for _, course := range rows[name] {
+			if profile.Email == "" {
+				profile.Email = strings.TrimSpace(course.InstructorEmail)
+			}
+			department := course.Subject
+			if subjectName, ok := subjectNames[course.Subject]; ok {
+				department = subjectName
+			}
+			profile.Departments = appendUnique(profile.Departments, department)
+			profile.Colleges = appendUnique(profile.Colleges, strings.TrimSpace(course.College))
+			profile.Buildings = appendUnique(profile.Buildings, strings.TrimSpace(course.Building))
+		}
+		for _, section := range groupSections(rows[name]) {
+			code := CourseCode{Subject: section.Subject, Number: section.CourseNumber}
+			profile.Courses = appendUnique(profile.Courses, code.String()+" "+section.Title)
+			entry := ProfileSection{
+				CRN:        section.CRN,
+				Course:     CourseCode{Subject: section.Subject, Number: section.CourseNumber, Section: section.Section}.String(),
+				Title:      section.Title,
+				Mode:       section.InstructionMode,
+				Enrollment: sectionEnrollment(section),
+			}
+			for _, meeting := range section.Meetings {
+				entry.Meetings = append(entry.Meetings, meeting.String())
+			}
+			profile.Sections = append(profile.Sections, entry)
+			profile.Enrollment += entry.Enrollment
+		}
+		sort.Strings(profile.Departments)
+		sort.Strings(profile.Colleges)
+		sort.Strings(profile.Buildings)
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
+
+// syncInstructorProfiles makes the instructors collection hold exactly the
+// profiles of the instructors in courses, keyed by name. Only profiles that
+// changed are re-embedded, and instructors no longer teaching are removed.
+func syncInstructorProfiles(ctx context.Context, collection *chroma.Collection, courses []Course) error {
+	existing, err := collection.Get(ctx, nil, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("get instructor profiles: %w", err)
+	}
+	stored := make(map[string]string, len(existing.Ids))
+	for i, id := range existing.Ids {
+		if i < len(existing.Documents) {
+			stored[id] = existing.Documents[i]
+		}
+	}
+
+	current := make(map[string]bool)
+	for _, profile := range instructorProfiles(courses) {
+		current[profile.Name] = true
+		document, err := json.Marshal(profile)
+		if err != nil {
+			return fmt.Errorf("marshal profile of %s: %w", profile.Name, err)
+		}
+		if stored[profile.Name] == string(document) {
+			continue
+		}
+		if _, err := collection.Upsert(ctx, nil, nil, []string{string(document)}, []string{profile.Name}); err != nil {
+			return fmt.Errorf("upsert profile of %s: %w", profile.Name, err)
+		}
+	}
+
+	var stale []string
+	for id := range stored {
+		if !current[id] {
+			stale = append(stale, id)
+		}
+	}
+	if len(stale) > 0 {
+		sort.Strings(stale)
+		if _, err := collection.Delete(ctx, stale, nil, nil); err != nil {
+			return fmt.Errorf("delete %d stale profiles: %w", len(stale), err)
+		}
+	}
+	return nil
+}
+
+// teachingPattern marks questions about who teaches what, such as "who
+// teaches Software Development?" or "what does Philip Peterson teach?".
+var teachingPattern = regexp.MustCompile(`(?i)\b(?:teach(?:es|ing)?|taught|professors?|lecturers?|faculty)\b`)
+
+// namedInstructorProfiles returns the profiles of the instructors a question
+// names in full, as in "what does Philip Peterson teach?". instructors lists
+// the canonical names of the instructors in courses.
+func namedInstructorProfiles(question string, courses []Course, instructors []string) []InstructorProfile {
+	lower := strings.ToLower(question)
+	var named []string
+	for _, name := range instructors {
+		if strings.TrimSpace(name) != "" && strings.Contains(lower, strings.ToLower(name)) {
+			named = append(named, name)
+		}
+	}
+	if len(named) == 0 {
+		return nil
+	}
+
+	aliases := InitializeInstructors()
+	var rows []Course
+	for _, course := range courses {
+		if slices.Contains(named, courseInstructor(course, aliases)) {
+			rows = append(rows, course)
+		}
+	}
+	return instructorProfiles(rows)
+}
+
+// profileDocuments returns each profile as a JSON document followed by the
+// schedule rows of its sections, which answers cite.
+func profileDocuments(profiles []InstructorProfile, courses []Course) []string {
+	var documents []string
+	for _, profile := range profiles {
+		document, err := json.Marshal(profile)
+		if err != nil {
+			continue
+		}
+		documents = append(documents, string(document))
+		for _, section := range profile.Sections {
+			for _, row := range FilterSections(courses, SectionFilter{CRN: section.CRN}) {
+				if document, err := json.Marshal(row); err == nil {
+					documents = append(documents, string(document))
+				}
+			}
+		}
+	}
+	return documents
+}
+
+// joinInstructorNames lists the profiles' names as "A", "A and B" or "A, B and C".
+func joinInstructorNames(profiles []InstructorProfile) string {
+	names := make([]string, len(profiles))
+	for i, profile := range profiles {
+		names[i] = profile.Name
+	}
+	return joinList(names, "and")
+}
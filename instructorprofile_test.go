@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var profileTestCourses = []Course{
+	{Subject: "CS", CourseNumber: "272", Section: "03", CRN: "40646", Title: "Software Development", MeetDays: "TR", BeginTime: "1440", EndTime: "1625", Building: "LS", Room: "G12", InstructorFirstName: "Phil", InstructorLastName: "Peterson", InstructorEmail: "peterson@example.edu", College: "AS", ActualEnrollment: "38", InstructionModeDesc: "In-Person"},
+	{Subject: "CS", CourseNumber: "272L", Section: "01", CRN: "42343", Title: "Software Development Lab", MeetDays: "W", BeginTime: "1300", EndTime: "1430", Building: "MH", Room: "122", InstructorFirstName: "Phil", InstructorLastName: "Peterson", College: "AS", ActualEnrollment: "20", InstructionModeDesc: "In-Person"},
+	{Subject: "MATH", CourseNumber: "201", Section: "01", CRN: "41000", Title: "Discrete Mathematics", MeetDays: "MWF", BeginTime: "0915", EndTime: "1020", Building: "KA", Room: "111", InstructorFirstName: "Ada", InstructorLastName: "Lovelace", College: "AS", ActualEnrollment: "30", InstructionModeDesc: "In-Person"},
+	{Subject: "MATH", CourseNumber: "201", Section: "02", CRN: "41001", Title: "Discrete Mathematics", InstructionModeDesc: "Online Asynchronous"},
+}
+
+func TestInstructorProfiles(t *testing.T) {
+	profiles := instructorProfiles(profileTestCourses)
+	if len(profiles) != 2 || profiles[0].Name != "Ada Lovelace" || profiles[1].Name != "Philip Peterson" {
+		t.Fatalf("Expected profiles of Ada Lovelace and Philip Peterson, got %+v", profiles)
+	}
+	want := InstructorProfile{
+		Name:        "Philip Peterson",
+		Email:       "peterson@example.edu",
+		Departments: []string{"Computer Science"},
+		Colleges:    []string{"AS"},
+		Courses:     []string{"CS 272 Software Development", "CS 272L Software Development Lab"},
+		Sections: []ProfileSection{
+			{CRN: "40646", Course: "CS 272-03", Title: "Software Development", Meetings: []string{"TR 2:40pm-4:25pm in LS G12"}, Mode: "In-Person", Enrollment: 38},
+			{CRN: "42343", Course: "CS 272L-01", Title: "Software Development Lab", Meetings: []string{"W 1:00pm-2:30pm in MH 122"}, Mode: "In-Person", Enrollment: 20},
+		},
+		Buildings:  []string{"LS", "MH"},
+		Enrollment: 58,
+	}
+	if !reflect.DeepEqual(profiles[1], want) {
+		t.Errorf("Profile = %+v, want %+v", profiles[1], want)
+	}
+
+	// Profiles must not be mistaken for sections by citations or by CRN lookups.
+	document, _ := json.Marshal(profiles[1])
+	if strings.Contains(string(document), `"CRN":`) || len((Answer{Documents: []string{string(document)}}).Citations()) != 0 {
+		t.Errorf("Expected a profile not to look like a section document: %s", document)
+	}
+}
+
+func TestNamedInstructorProfiles(t *testing.T) {
+	// Listing the instructors runs over every row, so it must not log per row.
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	instructors := uniqueInstructors(profileTestCourses)
+	log.SetOutput(os.Stderr)
+	if logged.Len() > 0 {
+		t.Errorf("Expected no logging while listing instructors, got %q", logged.String())
+	}
+
+	profiles := namedInstructorProfiles("What does Philip Peterson teach?", profileTestCourses, instructors)
+	if len(profiles) != 1 || profiles[0].Name != "Philip Peterson" {
+		t.Errorf("Expected Philip Peterson's profile, got %+v", profiles)
+	}
+	if profiles := namedInstructorProfiles("Who teaches Discrete Mathematics?", profileTestCourses, instructors); len(profiles) != 0 {
+		t.Errorf("Expected no instructor named, got %+v", profiles)
+	}
+
+	documents := profileDocuments(profiles, profileTestCourses)
+	if citations := (Answer{Documents: documents}).Citations(); len(documents) != 3 || len(citations) != 2 || citations[1].CRN != "42343" {
+		t.Errorf("Expected the profile followed by its two sections, got %v", documents)
+	}
+}
+
+func TestChatBotInstructorProfile(t *testing.T) {
+	var systemMessages []string
+	config := DefaultConfig()
+	config.CSVPath = "Fall 2024 Class Schedule.csv"
+	bot := NewChatBot(config, newCapturingLLM(t, &systemMessages), &MetadataExtractor{courses: profileTestCourses}, nil, nil, nil, nil)
+
+	answer, err := bot.Ask("What does Phil Peterson teach?")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if answer.Retrieval != RetrievalDirect || len(answer.Citations()) != 2 {
+		t.Errorf("Expected Philip Peterson's two sections looked up directly, got %s with %v", answer.Retrieval, answer.Documents)
+	}
+	if len(systemMessages) != 1 || !strings.Contains(systemMessages[0], "the teaching profile of Philip Peterson") || !strings.Contains(systemMessages[0], `"total_enrollment":58`) {
+		t.Errorf("Expected the profile given to the LLM, got %q", systemMessages)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// lexicalSearch ranks courses by how many significant words of the query
+// appear in their title, subject, instructor or location fields, and returns
+// the top limit courses as JSON documents in the same shape Add stores in
+// ChromaDB. It is the retrieval path used when the vector store is unavailable.
+func lexicalSearch(query string, courses []Course, limit int) []string {
+	words := significantWords(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		index int
+		score int
+	}
+	var ranked []scored
+	for i, course := range courses {
+		text := []string{
+			course.Subject, course.CourseNumber, course.Title,
+			course.InstructorFirstName, course.InstructorLastName,
+			course.Building, course.Room, course.InstructionModeDesc,
+		}
+		for _, value := range course.Extra {
+			text = append(text, value)
+		}
+		fields := significantWords(strings.Join(text, " "))
+
+		score := 0
+		for _, w := range words {
+			for _, f := range fields {
+				if fuzzyWordMatch(w, f) {
+					score++
+					break
+				}
+			}
+		}
+		if score > 0 {
+			ranked = append(ranked, scored{i, score})
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	documents := make([]string, 0, len(ranked))
+	for _, r := range ranked {
+		jsonData, err := json.Marshal(courses[r.index])
+		if err != nil {
+			continue
+		}
+		documents = append(documents, string(jsonData))
+	}
+	return documents
+}
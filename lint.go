@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Severity is how serious a lint issue is. Errors should stop ingestion;
+// warnings are worth a look but the schedule is still usable.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// LintRow is one schedule row with its row number in the source.
+type LintRow struct {
+	Row    int
+	Course Course
+}
+
+// LintIssue is one problem a check found in one row.
+type LintIssue struct {
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	Row      int      `json:"row"`
+	CRN      string   `json:"crn,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	crn := ""
+	if i.CRN != "" {
+		crn = "CRN " + i.CRN + ": "
+	}
+	return fmt.Sprintf("row %d: %s [%s] %s%s", i.Row, i.Severity, i.Check, crn, i.Message)
+}
+
+// LintCheck is a named check over a whole schedule, so that checks can
+// compare rows with each other.
+type LintCheck struct {
+	Name        string
+	Severity    Severity
+	Description string
+	check       func(rows []LintRow) []LintIssue
+}
+
+// lintChecks lists every check in the order lint runs them.
+var lintChecks = []LintCheck{
+	{"unreadable-row", SeverityError, "rows the reader had to skip, such as rows without a CRN", nil},
+	{"shifted-columns", SeverityError, "values in the wrong shape for their column, usually from a title with an unquoted comma", checkShiftedColumns},
+	{"conflicting-crn", SeverityError, "rows with the same CRN that disagree about the section", checkConflictingCRN},
+	{"end-before-begin", SeverityError, "meetings that end before they begin, by time or by date", checkEndBeforeBegin},
+	{"empty-instructor", SeverityWarning, "rows with no primary instructor", checkEmptyInstructor},
+	{"tba-room", SeverityWarning, "scheduled meetings whose building or room is TBA", checkTBARoom},
+}
+
+// ParseLintChecks selects checks from a comma-separated list of names. An
+// empty list selects every check.
+func ParseLintChecks(s string) ([]LintCheck, error) {
+	if strings.TrimSpace(s) == "" {
+		return lintChecks, nil
+	}
+	var checks []LintCheck
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, check := range lintChecks {
+			if strings.EqualFold(name, check.Name) {
+				checks = append(checks, check)
+				found = true
+				break
+			}
+		}
+		if !found {
+			names := make([]string, len(lintChecks))
+			for i, check := range lintChecks {
+				names[i] = check.Name
+			}
+			return nil, fmt.Errorf("unknown check %q (want one of %s)", name, strings.Join(names, ", "))
+		}
+	}
+	return checks, nil
+}
+
+// LintReport is the issues found in a schedule, ordered by row.
+type LintReport struct {
+	Rows     int         `json:"rows"`
+	Errors   int         `json:"errors"`
+	Warnings int         `json:"warnings"`
+	Issues   []LintIssue `json:"issues"`
+}
+
+// Failed reports whether the schedule has errors, or any issue at all when
+// strict.
+func (r LintReport) Failed(strict bool) bool {
+	return r.Errors > 0 || strict && r.Warnings > 0
+}
+
+func (r LintReport) String() string {
+	var b strings.Builder
+	for _, issue := range r.Issues {
+		b.WriteString(issue.String())
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "%d rows checked: %d errors, %d warnings\n", r.Rows, r.Errors, r.Warnings)
+	return b.String()
+}
+
+// Lint runs checks over schedule rows.
+func Lint(rows []LintRow, checks []LintCheck) LintReport {
+	report := LintReport{Rows: len(rows), Issues: []LintIssue{}}
+	for _, check := range checks {
+		if check.check == nil {
+			continue
+		}
+		for _, issue := range check.check(rows) {
+			issue.Check, issue.Severity = check.Name, check.Severity
+			report.add(issue)
+		}
+	}
+	report.sort()
+	return report
+}
+
+func (r *LintReport) add(issue LintIssue) {
+	r.Issues = append(r.Issues, issue)
+	if issue.Severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+func (r *LintReport) sort() {
+	sort.SliceStable(r.Issues, func(i, j int) bool {
+		return r.Issues[i].Row < r.Issues[j].Row
+	})
+}
+
+// LintSource reads a schedule source and runs checks over its rows. Rows the
+// reader skips are reported by the unreadable-row check rather than as an
+// error; an error is returned only when the source cannot be read at all.
+func LintSource(r io.Reader, reader SourceReader, mapping *ColumnMapping, checks []LintCheck) (LintReport, error) {
+	records, err := NewCourseIterator(r, reader, mapping)
+	if err != nil {
+		return LintReport{}, err
+	}
+	var rows []LintRow
+	for records.Next() {
+		rows = append(rows, LintRow{Row: records.Row(), Course: records.Course()})
+	}
+	if records.err != nil {
+		return LintReport{}, records.err
+	}
+
+	report := Lint(rows, checks)
+	for _, check := range checks {
+		if check.Name != "unreadable-row" {
+			continue
+		}
+		for _, problem := range records.problems {
+			issue := LintIssue{Check: check.Name, Severity: check.Severity, Message: problem.Error()}
+			var sourceErr *SourceError
+			if errors.As(problem, &sourceErr) {
+				issue.Row, issue.Message = sourceErr.Row, sourceErr.Err.Error()
+			}
+			report.add(issue)
+		}
+		report.Rows += len(records.problems)
+		report.sort()
+	}
+	return report, nil
+}
+
+// checkShiftedColumns reports rows whose typed columns hold values of the
+// wrong shape. Missing values are fine; online sections have no times.
+func checkShiftedColumns(rows []LintRow) []LintIssue {
+	var issues []LintIssue
+	for _, row := range rows {
+		course := row.Course
+		var wrong []string
+		if !allDigits(course.CRN) {
+			wrong = append(wrong, fmt.Sprintf("CRN %q", course.CRN))
+		}
+		for _, field := range []struct{ name, value string }{{"begin time", course.BeginTime}, {"end time", course.EndTime}} {
+			if _, ok := parseClock(field.value); strings.TrimSpace(field.value) != "" && !ok {
+				wrong = append(wrong, fmt.Sprintf("%s %q", field.name, field.value))
+			}
+		}
+		if days := strings.TrimSpace(course.MeetDays); days != "" && strings.Trim(strings.ToUpper(days), weekDays+" ") != "" {
+			wrong = append(wrong, fmt.Sprintf("meet days %q", days))
+		}
+		for _, field := range []struct{ name, value string }{{"meet start", course.MeetStart}, {"meet end", course.MeetEnd}} {
+			if _, ok := parseMeetDate(field.value); strings.TrimSpace(field.value) != "" && !ok {
+				wrong = append(wrong, fmt.Sprintf("%s %q", field.name, field.value))
+			}
+		}
+		if enrollment := strings.TrimSpace(course.ActualEnrollment); enrollment != "" && !allDigits(enrollment) {
+			wrong = append(wrong, fmt.Sprintf("enrollment %q", enrollment))
+		}
+		if email := strings.TrimSpace(course.InstructorEmail); email != "" && !strings.Contains(email, "@") {
+			wrong = append(wrong, fmt.Sprintf("instructor email %q", email))
+		}
+		if len(wrong) > 0 {
+			issues = append(issues, LintIssue{Row: row.Row, CRN: course.CRN, Message: fmt.Sprintf("unexpected %s; is the title %q cut short by a comma?", strings.Join(wrong, ", "), strings.TrimSpace(course.Title))})
+		}
+	}
+	return issues
+}
+
+func allDigits(s string) bool {
+	s = strings.TrimSpace(s)
+	_, err := strconv.ParseUint(s, 10, 64)
+	return s != "" && err == nil
+}
+
+// sectionFields are the fields every row of a section must agree on. Meeting
+// fields may differ: a section can meet at several times and places.
+var sectionFields = []struct {
+	name  string
+	value func(Course) string
+}{
+	{"subject", func(c Course) string { return c.Subject }},
+	{"course number", func(c Course) string { return c.CourseNumber }},
+	{"section", func(c Course) string { return c.Section }},
+	{"title", func(c Course) string { return c.Title }},
+	{"schedule type", func(c Course) string { return c.ScheduleTypeCode }},
+	{"campus", func(c Course) string { return c.CampusCode }},
+	{"instruction mode", func(c Course) string { return c.InstructionModeDesc }},
+	{"enrollment", func(c Course) string { return c.ActualEnrollment }},
+	{"instructor", func(c Course) string { return strings.TrimSpace(c.InstructorFirstName + " " + c.InstructorLastName) }},
+	{"college", func(c Course) string { return c.College }},
+}
+
+// checkConflictingCRN compares each row with the first row of its CRN.
+func checkConflictingCRN(rows []LintRow) []LintIssue {
+	first := make(map[string]LintRow)
+	var issues []LintIssue
+	for _, row := range rows {
+		crn := strings.TrimSpace(row.Course.CRN)
+		earlier, ok := first[crn]
+		if !ok {
+			first[crn] = row
+			continue
+		}
+		var conflicts []string
+		for _, field := range sectionFields {
+			a, b := strings.TrimSpace(field.value(earlier.Course)), strings.TrimSpace(field.value(row.Course))
+			if a != b {
+				conflicts = append(conflicts, fmt.Sprintf("%s %q (row %d has %q)", field.name, b, earlier.Row, a))
+			}
+		}
+		if len(conflicts) > 0 {
+			issues = append(issues, LintIssue{Row: row.Row, CRN: crn, Message: "conflicting " + strings.Join(conflicts, ", ")})
+		}
+	}
+	return issues
+}
+
+// checkEndBeforeBegin reports meetings whose end time is not after their
+// begin time, or whose last date is before their first.
+func checkEndBeforeBegin(rows []LintRow) []LintIssue {
+	var issues []LintIssue
+	for _, row := range rows {
+		course := row.Course
+		begin, okBegin := parseClock(course.BeginTime)
+		end, okEnd := parseClock(course.EndTime)
+		if okBegin && okEnd && end <= begin {
+			issues = append(issues, LintIssue{Row: row.Row, CRN: course.CRN, Message: fmt.Sprintf("ends at %s, not after it begins at %s", formatClock(end), formatClock(begin))})
+		}
+		first, okFirst := parseMeetDate(course.MeetStart)
+		last, okLast := parseMeetDate(course.MeetEnd)
+		if okFirst && okLast && last.Before(first) {
+			issues = append(issues, LintIssue{Row: row.Row, CRN: course.CRN, Message: fmt.Sprintf("meets until %s, before its first date %s", last.Format("Jan 2, 2006"), first.Format("Jan 2, 2006"))})
+		}
+	}
+	return issues
+}
+
+// checkEmptyInstructor reports rows without a primary instructor's name.
+func checkEmptyInstructor(rows []LintRow) []LintIssue {
+	var issues []LintIssue
+	for _, row := range rows {
+		if strings.TrimSpace(row.Course.InstructorFirstName+row.Course.InstructorLastName) == "" {
+			issues = append(issues, LintIssue{Row: row.Row, CRN: row.Course.CRN, Message: "no primary instructor"})
+		}
+	}
+	return issues
+}
+
+// checkTBARoom reports rows with a meeting time but a TBA building or room.
+// Online and unscheduled rows are not expected to have a room.
+func checkTBARoom(rows []LintRow) []LintIssue {
+	var issues []LintIssue
+	for _, row := range rows {
+		course := row.Course
+		meeting, timed := parseMeeting(course)
+		if !timed {
+			continue
+		}
+		if strings.EqualFold(meeting.Building, buildingTBA) || strings.EqualFold(meeting.Room, buildingTBA) {
+			issues = append(issues, LintIssue{Row: row.Row, CRN: course.CRN, Message: "meets " + Meeting{Days: meeting.Days, Start: meeting.Start, End: meeting.End}.String() + " but its room is TBA"})
+		}
+	}
+	return issues
+}
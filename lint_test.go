@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintChecks(t *testing.T) {
+	cs := Course{Subject: "CS", CourseNumber: "272", Section: "01", CRN: "41234", Title: "Software Development", ActualEnrollment: "38",
+		MeetDays: "MW", BeginTime: "0955", EndTime: "1140", MeetStart: "8/20/24", MeetEnd: "12/13/24", Building: "LS", Room: "G12",
+		InstructorFirstName: "Philip", InstructorLastName: "Peterson"}
+	lab := cs
+	lab.MeetDays, lab.BeginTime, lab.EndTime, lab.ActualEnrollment = "F", "1300", "1445", "40"
+	tba := cs
+	tba.CRN, tba.Building, tba.Room = "41235", "TBA", "TBA"
+	online := cs
+	online.CRN, online.MeetDays, online.BeginTime, online.EndTime, online.Building, online.Room = "41236", "", "", "", "ONL", "TBA"
+	backwards := cs
+	backwards.CRN, backwards.BeginTime, backwards.EndTime, backwards.MeetEnd = "41237", "1140", "0955", "8/1/24"
+	unstaffed := cs
+	unstaffed.CRN, unstaffed.InstructorFirstName, unstaffed.InstructorLastName = "41238", "", " "
+
+	rows := []LintRow{{2, cs}, {3, lab}, {4, tba}, {5, online}, {6, backwards}, {7, unstaffed}}
+	report := Lint(rows, lintChecks)
+	want := []string{
+		`row 3: error [conflicting-crn] CRN 41234: conflicting enrollment "40" (row 2 has "38")`,
+		"row 4: warning [tba-room] CRN 41235: meets MW 9:55am-11:40am but its room is TBA",
+		"row 6: error [end-before-begin] CRN 41237: ends at 9:55am, not after it begins at 11:40am",
+		"row 6: error [end-before-begin] CRN 41237: meets until Aug 1, 2024, before its first date Aug 20, 2024",
+		"row 7: warning [empty-instructor] CRN 41238: no primary instructor",
+	}
+	var got []string
+	for _, issue := range report.Issues {
+		got = append(got, issue.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected issues\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if report.Errors != 3 || report.Warnings != 2 || !report.Failed(false) {
+		t.Errorf("Expected 3 errors and 2 warnings to fail, got %+v", report)
+	}
+
+	warnings := Lint(rows, lintChecks[4:])
+	if warnings.Failed(false) || !warnings.Failed(true) {
+		t.Errorf("Expected warnings to fail only when strict, got %+v", warnings)
+	}
+	if _, err := ParseLintChecks("tba-room, nope"); err == nil || !strings.Contains(err.Error(), `unknown check "nope"`) {
+		t.Errorf("Expected an unknown check to be rejected, got %v", err)
+	}
+}
+
+func TestLintSource(t *testing.T) {
+	input := sourceCSV +
+		"CS,273,01,41240,Intro, Lab,MW,0955,1140,8/20/24,Peterson\n" +
+		"CS,274,01,,Systems,TR,0800,0945,8/20/24,Benson\n"
+	checks, err := ParseLintChecks("unreadable-row,shifted-columns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := LintSource(strings.NewReader(input), delimitedReader{format: FormatCSV, comma: ','}, sourceMapping(), checks)
+	if err != nil {
+		t.Fatalf("Expected skipped rows reported as issues, got %v", err)
+	}
+	if report.Rows != 4 || report.Errors != 2 || len(report.Issues) != 2 {
+		t.Fatalf("Expected 2 errors in 4 rows, got %+v", report)
+	}
+	shifted, missing := report.Issues[0], report.Issues[1]
+	if shifted.Row != 4 || shifted.Check != "shifted-columns" || !strings.Contains(shifted.Message, `begin time "MW"`) || !strings.Contains(shifted.Message, `title "Intro"`) {
+		t.Errorf("Expected the comma in row 4's title to be blamed, got %+v", shifted)
+	}
+	if missing.Row != 5 || missing.Check != "unreadable-row" || missing.Message != "missing CRN" {
+		t.Errorf("Expected row 5's missing CRN, got %+v", missing)
+	}
+}
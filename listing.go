@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A listing shows every section when there are few enough, and otherwise
+// one line per course. Either way it shows listingPageSize courses a page.
+const (
+	maxDetailedSections = 40
+	listingPageSize     = 25
+)
+
+// enumerationPatterns mark questions asking for every matching section, such
+// as "which philosophy courses are offered?" or "list all CS classes".
+var enumerationPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(?:list|enumerate)\b`),
+	regexp.MustCompile(`\b(?:all|every)\s+(?:of\s+)?(?:the\s+)?(?:[\w&-]+\s+){0,3}(?:courses|classes|sections|offerings)\b`),
+	regexp.MustCompile(`\b(?:which|what)\b[^.?!]*\b(?:courses|classes|sections)\b`),
+}
+
+// Department names such as "art" and "law" are also ordinary words, so a
+// one-word name only counts next to a course noun, as in "art classes",
+// "history and music courses" or "courses in Law". Names of two or more
+// words, such as "computer science", count anywhere.
+var (
+	subjectNamePattern = regexp.MustCompile(`(?i)\b(?:` + subjectNameAlternation() + `)\b`)
+	subjectListPattern = func() *regexp.Regexp {
+		name := `\b(?:` + subjectNameAlternation() + `)\b`
+		names := name + `(?:\s*(?:,|/|&|\band\b|\bor\b)\s*` + name + `)*`
+		nouns := `\b(?:courses?|class(?:es)?|sections?|offerings|electives)\b`
+		return regexp.MustCompile(`(?i)` + names + `\s+` + nouns + `|` + nouns + `\s+(?:in|from)\s+(?:the\s+)?` + names)
+	}()
+	listingPagePattern = regexp.MustCompile(`\bpage\s+(\d+)\b`)
+)
+
+// listingScope is the structured filter of an enumeration question. Empty
+// fields match every section.
+type listingScope struct {
+	Subjects   []string
+	Mode       string
+	Instructor string
+	Constraint ScheduleConstraint
+}
+
+// questionListingScope reads the subjects, instruction mode, instructor and
+// days and times a question names, given the canonical names of the
+// instructors in courses. It reports false if the question names no subject,
+// mode or instructor, since listing the whole schedule would not help.
+func questionListingScope(question string, courses []Course, instructors []string) (listingScope, bool) {
+	scope := listingScope{Subjects: questionSubjects(question, courses)}
+
+	lower := strings.ToLower(question)
+	for _, m := range analyticsModes {
+		if strings.Contains(lower, m.phrase) {
+			scope.Mode = m.mode
+			break
+		}
+	}
+	for _, name := range instructors {
+		if strings.TrimSpace(name) != "" && strings.Contains(lower, strings.ToLower(name)) {
+			scope.Instructor = name
+			break
+		}
+	}
+	scope.Constraint, _ = ParseScheduleConstraint(question)
+	return scope, len(scope.Subjects) > 0 || scope.Mode != "" || scope.Instructor != ""
+}
+
+// questionSubjects returns the subject codes a question names, by department
+// name or as a code written in capitals, in the order it names them.
+func questionSubjects(question string, courses []Course) []string {
+	var subjects []string
+	lists := subjectListPattern.FindAllStringIndex(question, -1)
+	for _, loc := range subjectNamePattern.FindAllStringIndex(question, -1) {
+		name := question[loc[0]:loc[1]]
+		listed := strings.ContainsFunc(name, unicode.IsSpace)
+		for _, list := range lists {
+			listed = listed || list[0] <= loc[0] && loc[1] <= list[1]
+		}
+		if subject := subjectByName[normalizeSubjectName(name)]; listed && !slices.Contains(subjects, subject) {
+			subjects = append(subjects, subject)
+		}
+	}
+	known := uniqueSubjects(courses)
+	for _, word := range strings.FieldsFunc(question, func(r rune) bool { return !('A' <= r && r <= 'Z' || r == '&') }) {
+		// Subject codes are written in capitals; "art" is a word, "ART" a subject.
+		if slices.Contains(known, word) && !slices.Contains(subjects, word) {
+			subjects = append(subjects, word)
+		}
+	}
+	return subjects
+}
+
+// match returns the rows of every section in scope, in their original order.
+func (s listingScope) match(courses []Course) []Course {
+	filter := SectionFilter{Mode: s.Mode, Instructor: s.Instructor}
+	var matches []Course
+	for _, course := range FilterSections(courses, filter) {
+		if len(s.Subjects) == 0 || slices.Contains(s.Subjects, course.Subject) {
+			matches = append(matches, course)
+		}
+	}
+	if !s.Constraint.Empty() {
+		matches = s.Constraint.FilterRows(matches)
+	}
+	return matches
+}
+
+// describe names sections in scope, e.g. "online CS sections taught by
+// Philip Peterson", given how many there are.
+func (s listingScope) describe(n int) string {
+	var words []string
+	if s.Mode != "" {
+		words = append(words, strings.ToLower(s.Mode))
+	}
+	if len(s.Subjects) > 0 {
+		words = append(words, strings.Join(s.Subjects, "/"))
+	}
+	words = append(words, "section")
+	if n != 1 {
+		words[len(words)-1] = "sections"
+	}
+	description := strings.Join(words, " ")
+	if s.Instructor != "" {
+		description += " taught by " + s.Instructor
+	}
+	return description
+}
+
+// courseListing is one course in a listing with its matching sections.
+type courseListing struct {
+	Subject      string
+	CourseNumber string
+	Title        string
+	Sections     []ClassSection
+}
+
+// groupByCourse groups sections by course, in order of each course's first section.
+func groupByCourse(sections []ClassSection) []courseListing {
+	index := make(map[string]int)
+	var listings []courseListing
+	for _, section := range sections {
+		key := section.Subject + " " + section.CourseNumber
+		i, ok := index[key]
+		if !ok {
+			i = len(listings)
+			index[key] = i
+			listings = append(listings, courseListing{Subject: section.Subject, CourseNumber: section.CourseNumber, Title: section.Title})
+		}
+		listings[i].Sections = append(listings[i].Sections, section)
+	}
+	return listings
+}
+
+// listingAnswer answers enumeration questions such as "which philosophy
+// courses are offered this semester?" with every matching section from the
+// schedule, grouped by course, rather than the few closest matches that
+// retrieval finds. It returns false for other questions.
+func listingAnswer(question string, courses []Course, instructors []string, term Term) (Answer, bool) {
+	lower := strings.ToLower(question)
+	enumeration := false
+	for _, pattern := range enumerationPatterns {
+		enumeration = enumeration || pattern.MatchString(lower)
+	}
+	if !enumeration {
+		return Answer{}, false
+	}
+	scope, ok := questionListingScope(question, courses, instructors)
+	if !ok {
+		return Answer{}, false
+	}
+
+	schedule := "the schedule"
+	if !term.IsZero() {
+		schedule = fmt.Sprintf("the %s schedule", term)
+	}
+	sections := groupSections(scope.match(courses))
+	if len(sections) == 0 {
+		text := fmt.Sprintf("There are no %s in %s.", scope.describe(0), schedule)
+		if !scope.Constraint.Empty() {
+			text = fmt.Sprintf("No %s in %s %s.", scope.describe(1), schedule, scope.Constraint)
+		}
+		return Answer{Text: text, Grounded: true, Retrieval: RetrievalListing}, true
+	}
+
+	listings := groupByCourse(sections)
+	pages := (len(listings) + listingPageSize - 1) / listingPageSize
+	page := 1
+	if m := listingPagePattern.FindStringSubmatch(lower); m != nil {
+		page, _ = strconv.Atoi(m[1])
+		page = min(max(page, 1), pages)
+	}
+	first := (page - 1) * listingPageSize
+	shown := listings[first:min(first+listingPageSize, len(listings))]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s%s has %d %s in %d %s", strings.ToUpper(schedule[:1]), schedule[1:],
+		len(sections), scope.describe(len(sections)), len(listings), plural(len(listings), "course", "courses"))
+	if !scope.Constraint.Empty() {
+		fmt.Fprintf(&b, " (each section %s)", scope.Constraint)
+	}
+	b.WriteString(":\n")
+
+	var documents []string
+	for _, listing := range shown {
+		fmt.Fprintf(&b, "%s %s %s", listing.Subject, listing.CourseNumber, listing.Title)
+		if len(sections) > maxDetailedSections {
+			fmt.Fprintf(&b, ": %d %s\n", len(listing.Sections), plural(len(listing.Sections), "section", "sections"))
+		} else {
+			b.WriteString("\n")
+			for _, section := range listing.Sections {
+				fmt.Fprintf(&b, "  - %s\n", describeListedSection(section))
+			}
+		}
+		for _, section := range listing.Sections {
+			for _, row := range section.Rows {
+				if document, err := json.Marshal(row); err == nil {
+					documents = append(documents, string(document))
+				}
+			}
+		}
+	}
+	if pages > 1 {
+		fmt.Fprintf(&b, "Showing courses %d-%d of %d.", first+1, first+len(shown), len(listings))
+		if page < pages {
+			fmt.Fprintf(&b, " Ask again with \"page %d\" to see more.", page+1)
+		}
+	}
+	return Answer{Text: strings.TrimRight(b.String(), "\n"), Grounded: true, Documents: documents, Retrieval: RetrievalListing}, true
+}
+
+// describeListedSection describes a section on one line of a listing, e.g.
+// "01 (CRN 41231): MWF 9:15am-10:20am in KA 311, Philip Peterson".
+func describeListedSection(section ClassSection) string {
+	var details []string
+	for _, meeting := range section.Meetings {
+		details = append(details, meeting.String())
+	}
+	if len(section.Meetings) == 0 && section.InstructionMode != "" {
+		details = append(details, section.InstructionMode)
+	}
+	if section.Instructor != "" {
+		details = append(details, section.Instructor)
+	}
+	if len(details) == 0 {
+		return fmt.Sprintf("%s (CRN %s)", section.Section, section.CRN)
+	}
+	return fmt.Sprintf("%s (CRN %s): %s", section.Section, section.CRN, strings.Join(details, ", "))
+}
+
+// plural returns one or many depending on n.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestListingAnswer(t *testing.T) {
+	courses := []Course{
+		{Subject: "PHIL", CourseNumber: "110", Section: "01", CRN: "41163", Title: "Great Philosophical Questions", MeetDays: "MWF", BeginTime: "0915", EndTime: "1020", Building: "KA", Room: "211", InstructorFirstName: "Deena", InstructorLastName: "Lin"},
+		{Subject: "PHIL", CourseNumber: "240", Section: "02", CRN: "41182", Title: "Ethics", MeetDays: "TR", BeginTime: "1440", EndTime: "1625"},
+		{Subject: "PHIL", CourseNumber: "110", Section: "02", CRN: "41164", Title: "Great Philosophical Questions", InstructionModeDesc: "Online Asynchronous"},
+		{Subject: "CS", CourseNumber: "272", Section: "01", CRN: "40644", Title: "Software Development", MeetDays: "TR", BeginTime: "0800", EndTime: "0945"},
+	}
+
+	answer, ok := listingAnswer("Which philosophy courses are offered this semester?", courses, uniqueInstructors(courses), Term{Season: "Fall", Year: 2024})
+	want := `The Fall 2024 schedule has 3 PHIL sections in 2 courses:
+PHIL 110 Great Philosophical Questions
+  - 01 (CRN 41163): MWF 9:15am-10:20am in KA 211, Deena Lin
+  - 02 (CRN 41164): Online Asynchronous
+PHIL 240 Ethics
+  - 02 (CRN 41182): TR 2:40pm-4:25pm`
+	if !ok || answer.Text != want || answer.Retrieval != RetrievalListing || len(answer.Documents) != 3 {
+		t.Errorf("Expected every PHIL section grouped by course, got %v:\n%s", ok, answer.Text)
+	}
+
+	answer, ok = listingAnswer("List all PHIL classes in the afternoon", courses, uniqueInstructors(courses), Term{})
+	if !ok || !strings.HasPrefix(answer.Text, "The schedule has 1 PHIL section in 1 course (each section starts at 12:00pm or later") || len(answer.Documents) != 1 {
+		t.Errorf("Expected the afternoon PHIL section alone, got %v:\n%s", ok, answer.Text)
+	}
+
+	answer, ok = listingAnswer("Which CS classes meet on Fridays?", courses, uniqueInstructors(courses), Term{})
+	if !ok || answer.Text != "No CS section in the schedule meets on Friday." {
+		t.Errorf("Expected no matching CS sections, got %v: %q", ok, answer.Text)
+	}
+
+	answer, ok = listingAnswer("List all classes taught by Deena Lin", courses, uniqueInstructors(courses), Term{})
+	if !ok || !strings.HasPrefix(answer.Text, "The schedule has 1 section taught by Deena Lin in 1 course") {
+		t.Errorf("Expected Deena Lin's section alone, got %v:\n%s", ok, answer.Text)
+	}
+
+	answer, ok = listingAnswer("List the online courses in philosophy", courses, uniqueInstructors(courses), Term{})
+	if !ok || !strings.HasPrefix(answer.Text, "The schedule has 1 online PHIL section in 1 course") {
+		t.Errorf("Expected the online PHIL section alone, got %v:\n%s", ok, answer.Text)
+	}
+
+	for _, question := range []string{"Where does Ethics meet?", "Which courses are good for beginners?", "Who teaches PHIL 240?", "list the classes that fit the art of scheduling"} {
+		if _, ok := listingAnswer(question, courses, uniqueInstructors(courses), Term{}); ok {
+			t.Errorf("Expected %q not to be answered with a listing", question)
+		}
+	}
+	// Department names used as ordinary words name no subject.
+	for _, question := range []string{"list the classes that fit the art of scheduling", "law-related electives in every department"} {
+		if scope, _ := questionListingScope(question, courses, uniqueInstructors(courses)); len(scope.Subjects) > 0 {
+			t.Errorf("Expected %q to name no subject, got %v", question, scope.Subjects)
+		}
+	}
+}
+
+func TestListingAnswerPages(t *testing.T) {
+	var courses []Course
+	for i := 0; i < 60; i++ {
+		course := Course{Subject: "BIOL", CourseNumber: fmt.Sprint(100 + i/2), Section: fmt.Sprintf("%02d", i%2+1), CRN: fmt.Sprint(50000 + i), Title: "Biology"}
+		courses = append(courses, course)
+	}
+
+	answer, ok := listingAnswer("list all BIOL sections", courses, uniqueInstructors(courses), Term{})
+	lines := strings.Split(answer.Text, "\n")
+	if !ok || lines[0] != "The schedule has 60 BIOL sections in 30 courses:" || lines[1] != "BIOL 100 Biology: 2 sections" || len(lines) != listingPageSize+2 {
+		t.Fatalf("Expected the first page summarized by course, got:\n%s", answer.Text)
+	}
+	if last := lines[len(lines)-1]; last != `Showing courses 1-25 of 30. Ask again with "page 2" to see more.` {
+		t.Errorf("Expected a pointer to the next page, got %q", last)
+	}
+
+	answer, _ = listingAnswer("list all BIOL sections, page 2", courses, uniqueInstructors(courses), Term{})
+	lines = strings.Split(answer.Text, "\n")
+	if lines[1] != "BIOL 125 Biology: 2 sections" || lines[len(lines)-1] != "Showing courses 26-30 of 30." || len(answer.Documents) != 10 {
+		t.Errorf("Expected the last five courses on page 2, got:\n%s", answer.Text)
+	}
+}
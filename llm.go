@@ -2,7 +2,9 @@ package main
 
 import (
     "context"
+    "errors"
     "fmt"
+    "io"
     "strings"
 
     openai "github.com/sashabaranov/go-openai"
@@ -12,16 +14,106 @@ import (
 // It encapsulates the OpenAI client to allow easy integration and interaction with OpenAI's LLM services.
 type LLMClient struct {
     client *openai.Client // The underlying OpenAI client instance used to interact with the API.
+    model  string         // The chat model used for completions.
 }
 
 
-func NewLLMClient(apiKey string) *LLMClient {
-    client := openai.NewClient(apiKey) // Create a new OpenAI client instance using the provided API key.
-    return &LLMClient{client: client} // Return an LLMClient with the created OpenAI client.
+func NewLLMClient(config Config) *LLMClient {
+    client := openai.NewClient(config.APIKey) // Create a new OpenAI client instance using the configured API key.
+    return &LLMClient{client: client, model: config.Model} // Return an LLMClient with the created OpenAI client.
+}
+
+
+// Usage counts the tokens consumed by one or more completions.
+type Usage struct {
+    PromptTokens     int `json:"prompt_tokens"`
+    CompletionTokens int `json:"completion_tokens"`
+    TotalTokens      int `json:"total_tokens"`
+}
+
+// Add returns the sum of two usage counts.
+func (u Usage) Add(other Usage) Usage {
+    return Usage{
+        PromptTokens:     u.PromptTokens + other.PromptTokens,
+        CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+        TotalTokens:      u.TotalTokens + other.TotalTokens,
+    }
+}
+
+// usageFrom converts the OpenAI usage record.
+func usageFrom(usage openai.Usage) Usage {
+    return Usage{
+        PromptTokens:     usage.PromptTokens,
+        CompletionTokens: usage.CompletionTokens,
+        TotalTokens:      usage.TotalTokens,
+    }
 }
 
 
 func (llm *LLMClient) ChatCompletion(question, systemMessage string) (string, error) {
+    response, _, err := llm.ChatCompletionContext(context.Background(), question, systemMessage)
+    return response, err
+}
+
+// ChatCompletionContext works like ChatCompletion but stops waiting for the response when ctx is cancelled,
+// and also reports the tokens used.
+func (llm *LLMClient) ChatCompletionContext(ctx context.Context, question, systemMessage string) (string, Usage, error) {
+    req := llm.chatRequest(question, systemMessage)
+
+    // Call the OpenAI API to get a chat completion.
+    resp, err := llm.client.CreateChatCompletion(ctx, req)
+    if err != nil {
+        // Return a wrapped error to provide more context about the failure.
+        return "", Usage{}, fmt.Errorf("CreateChatCompletion failed: %w", err)
+    }
+
+    // Return the content of the LLM's response message.
+    return resp.Choices[0].Message.Content, usageFrom(resp.Usage), nil
+}
+
+// ChatCompletionStream works like ChatCompletionContext but passes each token to onToken as it is generated.
+// It returns the full response once the stream ends, or ctx.Err() if ctx is cancelled first.
+func (llm *LLMClient) ChatCompletionStream(ctx context.Context, question, systemMessage string, onToken func(string)) (string, Usage, error) {
+    req := llm.chatRequest(question, systemMessage)
+    req.StreamOptions = &openai.StreamOptions{IncludeUsage: true} // The final chunk then carries token usage.
+
+    stream, err := llm.client.CreateChatCompletionStream(ctx, req)
+    if err != nil {
+        return "", Usage{}, fmt.Errorf("CreateChatCompletionStream failed: %w", err)
+    }
+    defer stream.Close()
+
+    var response strings.Builder
+    var usage Usage
+    for {
+        chunk, err := stream.Recv()
+        if errors.Is(err, io.EOF) {
+            return response.String(), usage, nil
+        }
+        if err != nil {
+            // Report cancellation as such rather than as whatever error the aborted request produced.
+            if ctx.Err() != nil {
+                return response.String(), usage, ctx.Err()
+            }
+            return response.String(), usage, fmt.Errorf("stream receive failed: %w", err)
+        }
+        if chunk.Usage != nil {
+            usage = usageFrom(*chunk.Usage)
+        }
+        if len(chunk.Choices) == 0 {
+            continue
+        }
+
+        token := chunk.Choices[0].Delta.Content
+        response.WriteString(token)
+        if onToken != nil && token != "" {
+            onToken(token)
+        }
+    }
+}
+
+// chatRequest replaces instructor aliases in the question and builds the chat completion request.
+func (llm *LLMClient) chatRequest(question, systemMessage string) openai.ChatCompletionRequest {
     instructors := InitializeInstructors() // Initialize the list of instructors with aliases and canonical names.
     
     // Replace all instructor aliases in the question with their canonical names.
@@ -35,8 +127,8 @@ func (llm *LLMClient) ChatCompletion(question, systemMessage string) (string, er
     }
 
     // Create a chat completion request with the specified system message and question.
-    req := openai.ChatCompletionRequest{
-        Model: openai.GPT4oMini, // Specify the model to use for the completion.
+    return openai.ChatCompletionRequest{
+        Model: llm.model, // Specify the model to use for the completion.
         Messages: []openai.ChatCompletionMessage{
             {
                 Role:    openai.ChatMessageRoleSystem, // The system message to guide the LLM.
@@ -48,14 +140,4 @@ func (llm *LLMClient) ChatCompletion(question, systemMessage string) (string, er
             },
         },
     }
-
-    // Call the OpenAI API to get a chat completion.
-    resp, err := llm.client.CreateChatCompletion(context.Background(), req)
-    if err != nil {
-        // Return a wrapped error to provide more context about the failure.
-        return "", fmt.Errorf("CreateChatCompletion failed: %w", err)
-    }
-
-    // Return the content of the LLM's response message.
-    return resp.Choices[0].Message.Content, nil
 }
@@ -3,43 +3,58 @@ package main
 import (
     "bufio"
     "context"
+    "errors"
     "fmt"
-    "log"
     "os"
-
-    chroma "github.com/amikos-tech/chroma-go"
+    "os/signal"
+    "strings"
 )
 
-var chatbot *ChatBot
-
 func main() {
-    apiKey := os.Getenv("OPENAI_API_KEY")
-    if apiKey == "" {
-        log.Fatal("API key is missing. Please set OPENAI_API_KEY environment variable.")
-    }
-    csvFilePath := "Fall 2024 Class Schedule 08082024.csv"
-
-    llmClient := NewLLMClient(apiKey)
+    os.Exit(run(os.Args[1:]))
+}
 
-    // Initialize metadata extractor
-    metadataExtractor, err := NewMetadataExtractor(csvFilePath, llmClient)
-    if err != nil {
-        log.Fatalf("Failed to initialize MetadataExtractor: %v", err)
+// run dispatches to the subcommand named by the first argument and returns its exit code.
+// With no subcommand, or only flags, it starts the REPL.
+func run(args []string) int {
+    name := "repl"
+    if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+        name, args = args[0], args[1:]
     }
 
-    // Add courses and instructors to ChromaDB
-    chromaCtx, chromaClient, courseCollection, instructorCollection := Add(metadataExtractor.courses)
+    for _, cmd := range commands {
+        if cmd.name == name {
+            return cmd.run(newFlagSet(cmd), args)
+        }
+    }
 
-    // Initialize chatbot with collections
-    chatbot = NewChatBot(llmClient, metadataExtractor, chromaCtx, chromaClient, courseCollection, instructorCollection)
+    if name != "help" {
+        fmt.Fprintf(os.Stderr, "Unknown command %q.\n\n", name)
+    }
+    printUsage()
+    if name == "help" {
+        return exitOK
+    }
+    return exitUsage
+}
 
-    fmt.Println("Courses and instructors added to collections.")
-    fmt.Println("Entering interactive mode. Type your questions below:")
-    runInteractiveMode(chromaCtx, chromaClient, courseCollection)
+// printUsage lists the subcommands.
+func printUsage() {
+    fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] [args]\n\nCommands:\n", os.Args[0])
+    for _, cmd := range commands {
+        fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+    }
+    fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command.\n", os.Args[0])
 }
 
-// runInteractiveMode handles user queries interactively.
-func runInteractiveMode(ctx context.Context, client *chroma.Client, collection *chroma.Collection) {
+// runInteractiveMode handles user queries interactively until standard input is closed.
+// The whole run is one session, so follow-up questions can refer to earlier answers.
+func runInteractiveMode(bot *ChatBot, sessions *SessionManager) error {
+    session, err := sessions.Get("")
+    if err != nil {
+        return err
+    }
+
     scanner := bufio.NewScanner(os.Stdin)
     fmt.Print("\nCatalog search> ")
     for scanner.Scan() {
@@ -49,19 +64,48 @@ func runInteractiveMode(ctx context.Context, client *chroma.Client, collection *
             continue
         }
 
-        // Use the chatbot's AnswerQuestion method
-        answer, err := chatbot.AnswerQuestion(question)
+        // Stream the chatbot's answer as it is generated
+        answer, err := streamAnswer(bot, session.Contextualize(question))
+        if err == nil {
+            session, err = sessions.Record(session.ID, question, answer)
+        }
         if err != nil {
-            fmt.Printf("Error processing your question: %v\n", err)
-            continue
+            if errors.Is(err, context.Canceled) {
+                fmt.Println("\n(answer cancelled)")
+            } else {
+                fmt.Printf("Error processing your question: %v\n", err)
+            }
         }
-
-        fmt.Println(answer) // Print the chatbot's answer
         fmt.Print("\nCatalog search> ")
     }
 
-    if err := scanner.Err(); err != nil {
-        log.Println("Error reading input:", err)
-    }
+    return scanner.Err()
 }
 
+// streamAnswer prints the chatbot's answer to question as it is generated.
+// Ctrl-C while the answer is printing cancels that answer only.
+func streamAnswer(bot *ChatBot, question string) (Answer, error) {
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    // Catch interrupts only while answering so Ctrl-C at the prompt still exits.
+    interrupts := make(chan os.Signal, 1)
+    signal.Notify(interrupts, os.Interrupt)
+    defer signal.Stop(interrupts)
+    go func() {
+        select {
+        case <-interrupts:
+            cancel()
+        case <-ctx.Done():
+        }
+    }()
+
+    answer, err := bot.AskStream(ctx, question, func(token string) {
+        fmt.Print(token)
+    })
+    if err != nil {
+        return Answer{}, err
+    }
+    fmt.Println()
+    return answer, nil
+}
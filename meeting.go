@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// weekDays are the schedule's day letters in week order, Monday first.
+const weekDays = "MTWRFSU"
+
+// Meeting is when and where a section meets, parsed from one schedule row.
+type Meeting struct {
+	Days     string    `json:"days"`      // Day letters, e.g. "MWF"; R is Thursday, U Sunday.
+	Start    int       `json:"start"`     // Minutes after midnight.
+	End      int       `json:"end"`       // Minutes after midnight.
+	FirstDay time.Time `json:"first_day"` // First date of the term the meeting runs.
+	LastDay  time.Time `json:"last_day"`  // Last date, inclusive.
+	Building string    `json:"building,omitempty"`
+	Room     string    `json:"room,omitempty"`
+}
+
+// parseMeeting reads the meeting from a schedule row. It returns false for
+// rows with no scheduled days or times, such as TBA and asynchronous online
+// sections. Rows without meeting dates meet for an unbounded term.
+func parseMeeting(course Course) (Meeting, bool) {
+	days := normalizeDays(course.MeetDays)
+	start, okStart := parseClock(course.BeginTime)
+	end, okEnd := parseClock(course.EndTime)
+	if days == "" || !okStart || !okEnd {
+		return Meeting{}, false
+	}
+
+	meeting := Meeting{
+		Days:     days,
+		Start:    start,
+		End:      end,
+		Building: strings.TrimSpace(course.Building),
+		Room:     strings.TrimSpace(course.Room),
+	}
+	meeting.FirstDay, _ = parseMeetDate(course.MeetStart)
+	meeting.LastDay, _ = parseMeetDate(course.MeetEnd)
+	return meeting, true
+}
+
+// normalizeDays keeps only schedule day letters, upper-cased, in week order.
+func normalizeDays(days string) string {
+	days = strings.ToUpper(days)
+	var b strings.Builder
+	for _, day := range weekDays {
+		if strings.ContainsRune(days, day) {
+			b.WriteRune(day)
+		}
+	}
+	return b.String()
+}
+
+// parseClock converts the schedule's 24-hour "HHMM" time, e.g. "1645", to
+// minutes after midnight.
+func parseClock(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) == 3 {
+		s = "0" + s
+	}
+	if len(s) != 4 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	hours, minutes := n/100, n%100
+	if hours > 23 || minutes > 59 {
+		return 0, false
+	}
+	return hours*60 + minutes, true
+}
+
+// formatClock formats minutes after midnight as a 12-hour time like "4:45pm".
+func formatClock(minutes int) string {
+	hours, mins := minutes/60, minutes%60
+	suffix := "am"
+	if hours >= 12 {
+		suffix = "pm"
+	}
+	if hours%12 == 0 {
+		hours = 12
+	} else {
+		hours %= 12
+	}
+	return fmt.Sprintf("%d:%02d%s", hours, mins, suffix)
+}
+
+// parseMeetDate parses the schedule's "M/D/YY" dates, e.g. "8/20/24".
+func parseMeetDate(s string) (time.Time, bool) {
+	t, err := time.Parse("1/2/06", strings.TrimSpace(s))
+	return t, err == nil
+}
+
+// sharesDay reports whether two meetings have a day letter in common.
+func (m Meeting) sharesDay(other Meeting) bool {
+	return strings.ContainsAny(m.Days, other.Days)
+}
+
+// datesOverlap reports whether the two meetings' terms overlap. A missing
+// date leaves that side of the range open.
+func (m Meeting) datesOverlap(other Meeting) bool {
+	if !m.LastDay.IsZero() && !other.FirstDay.IsZero() && m.LastDay.Before(other.FirstDay) {
+		return false
+	}
+	if !other.LastDay.IsZero() && !m.FirstDay.IsZero() && other.LastDay.Before(m.FirstDay) {
+		return false
+	}
+	return true
+}
+
+// Conflicts reports whether two meetings are ever in session at the same time.
+func (m Meeting) Conflicts(other Meeting) bool {
+	return m.sharesDay(other) && m.Start < other.End && other.Start < m.End && m.datesOverlap(other)
+}
+
+// String describes the meeting, e.g. "MW 4:45pm-6:25pm in LM 140".
+func (m Meeting) String() string {
+	s := fmt.Sprintf("%s %s-%s", m.Days, formatClock(m.Start), formatClock(m.End))
+	if m.Building != "" {
+		s += " in " + strings.TrimSpace(m.Building+" "+m.Room)
+	}
+	return s
+}
@@ -2,8 +2,7 @@ package main
 
 import (
     "fmt"
-    "os"
-    "bufio"
+    "log"
     "strings"
 )
 
@@ -21,37 +20,30 @@ type Instructor struct {
     Aliases       []string
 }
 
-// NewMetadataExtractor reads course data and the header from the CSV file if needed.
+// NewMetadataExtractor reads course data and the header from a schedule file in the standard layout.
+// The file's format comes from its extension.
 func NewMetadataExtractor(csvFilePath string, client *LLMClient) (*MetadataExtractor, error) {
-    // Open the CSV file containing course data.
-    file, err := os.Open(csvFilePath)
-    if err != nil {
-        return nil, fmt.Errorf("Error opening file: %w", err)
-    }
-    defer file.Close()
-
-    // Read the first line to capture the header row
-    scanner := bufio.NewScanner(file)
-    if !scanner.Scan() {
-        return nil, fmt.Errorf("Error reading header row: %w", scanner.Err())
-    }
-    header := scanner.Text()
+    return NewMetadataExtractorFromSource(csvFilePath, "", nil, client)
+}
 
-    // Read the rest of the CSV data into course records.
-    courses, err := ReadCSV(file)
+// NewMetadataExtractorFromSource reads course data from a schedule file in the given format, or the one its
+// extension names, with columns described by mapping. A nil mapping reads the standard layout.
+func NewMetadataExtractorFromSource(path string, format string, mapping *ColumnMapping, client *LLMClient) (*MetadataExtractor, error) {
+    courses, header, err := ReadSourceFile(path, format, mapping)
     if err != nil {
-        return nil, fmt.Errorf("Error reading CSV: %w", err)
+        return nil, fmt.Errorf("Error reading %s: %w", path, err)
     }
+    return newMetadataExtractor(courses, strings.Join(header, ",")), nil
+}
 
-    instructors := uniqueInstructors(courses)
-    departments := uniqueSubjects(courses)
-
+// newMetadataExtractor collects the instructors and departments of loaded course records.
+func newMetadataExtractor(courses []Course, header string) *MetadataExtractor {
     return &MetadataExtractor{
-        Instructors: instructors,
-        Departments: departments,
+        Instructors: uniqueInstructors(courses),
+        Departments: uniqueSubjects(courses),
         courses:     courses,
         header:      header,
-    }, nil
+    }
 }
 
 // InitializeInstructors creates a list of instructors with canonical names
@@ -69,27 +61,43 @@ func findCanonicalName(inputName string, instructors []Instructor) string {
         return inputName // Return immediately if the input is empty
     }
 
+    name, found := canonicalName(inputName, instructors)
+    if found {
+        log.Printf("Substituting alias '%s' with canonical name '%s'", inputName, name)
+    } else {
+        log.Printf("No canonical substitution found for '%s'. Using original name.", inputName)
+    }
+    return name
+}
+
+// canonicalName is findCanonicalName without logging. It reports whether an alias matched.
+func canonicalName(inputName string, instructors []Instructor) (string, bool) {
+    inputName = strings.TrimSpace(inputName)
     for _, instructor := range instructors {
         for _, alias := range instructor.Aliases {
             if strings.EqualFold(inputName, alias) {
-                fmt.Printf("Substituting alias '%s' with canonical name '%s'\n", inputName, instructor.CanonicalName)
-                return instructor.CanonicalName
+                return instructor.CanonicalName, true
             }
         }
     }
-    fmt.Printf("No canonical substitution found for '%s'. Using original name.\n", inputName)
-    return inputName // Return the original name if no match is found
+    return inputName, false // Return the original name if no match is found
+}
+
+// courseInstructor returns the canonical name of a course's primary instructor, or "" if none is listed.
+func courseInstructor(course Course, instructors []Instructor) string {
+    name, _ := canonicalName(course.InstructorFirstName+" "+course.InstructorLastName, instructors)
+    return name
 }
 
 // uniqueInstructors creates a list of unique instructor canonical names from the courses.
+// It does not log, as it runs over every row of the schedule.
 func uniqueInstructors(courses []Course) []string {
     instructors := InitializeInstructors() // Load the list of instructors with canonical names
     instructorSet := make(map[string]bool)
     uniqueInstructors := []string{}
 
     for _, course := range courses {
-        fullName := course.InstructorFirstName + " " + course.InstructorLastName
-        canonicalName := findCanonicalName(fullName, instructors)
+        canonicalName := courseInstructor(course, instructors)
         if !instructorSet[canonicalName] {
             instructorSet[canonicalName] = true
             uniqueInstructors = append(uniqueInstructors, canonicalName)
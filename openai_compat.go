@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// catalogModelID is the model name the OpenAI-compatible endpoints advertise.
+// Requests may name any model; the catalog pipeline answers them all.
+const catalogModelID = "course-catalog"
+
+// handleModels lists the single catalog model, as GET /v1/models does on OpenAI.
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, openai.ModelsList{
+		Models: []openai.Model{{
+			ID:      catalogModelID,
+			Object:  "model",
+			OwnedBy: "course-catalog",
+		}},
+	})
+}
+
+// handleChatCompletions answers an OpenAI-style chat completion request with
+// the catalog-grounded pipeline. The last user message is the question, with
+// follow-ups resolved against the earlier messages.
+// With "stream": true the answer is sent as chat.completion.chunk events
+// followed by "data: [DONE]", like the OpenAI streaming API.
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req openai.ChatCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	question := conversationQuestion(req.Messages)
+	if question == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "messages must include a non-empty user message")
+		return
+	}
+	model := req.Model
+	if model == "" {
+		model = catalogModelID
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.answerTimeout)
+	defer cancel()
+
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		s.streamChatCompletion(ctx, cancel, w, question, model, includeUsage)
+		return
+	}
+
+	answer, err := s.bot.AskStream(ctx, question, nil)
+	if err != nil {
+		if r.Context().Err() != nil {
+			return
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			writeOpenAIError(w, http.StatusGatewayTimeout, "answering the question took too long")
+			return
+		}
+		log.Printf("Error answering chat completion %q: %v", question, err)
+		writeOpenAIError(w, http.StatusBadGateway, "failed to answer the question")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, openai.ChatCompletionResponse{
+		ID:      newCompletionID(),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []openai.ChatCompletionChoice{{
+			Index:        0,
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: answer.Text},
+			FinishReason: openai.FinishReasonStop,
+		}},
+		Usage: openai.Usage{
+			PromptTokens:     answer.Usage.PromptTokens,
+			CompletionTokens: answer.Usage.CompletionTokens,
+			TotalTokens:      answer.Usage.TotalTokens,
+		},
+	})
+}
+
+// streamChatCompletion sends the answer as OpenAI chat.completion.chunk events.
+func (s *Server) streamChatCompletion(ctx context.Context, cancel context.CancelFunc, w http.ResponseWriter, question, model string, includeUsage bool) {
+	id := newCompletionID()
+	created := time.Now().Unix()
+	chunk := func(delta openai.ChatCompletionStreamChoiceDelta, finish openai.FinishReason) openai.ChatCompletionStreamResponse {
+		return openai.ChatCompletionStreamResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []openai.ChatCompletionStreamChoice{{Index: 0, Delta: delta, FinishReason: finish}},
+		}
+	}
+
+	events := newSSEWriter(w)
+	send := func(payload interface{}) error {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(events.w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		return events.controller.Flush()
+	}
+
+	if err := send(chunk(openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant}, "")); err != nil {
+		return
+	}
+	answer, err := s.bot.AskStream(ctx, question, func(token string) {
+		if err := send(chunk(openai.ChatCompletionStreamChoiceDelta{Content: token}, "")); err != nil {
+			cancel() // The client can no longer receive the answer.
+		}
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error streaming chat completion %q: %v", question, err)
+		}
+		// Headers are already sent, so report the failure in-band as OpenAI does.
+		send(map[string]interface{}{"error": openAIError("failed to answer the question")})
+		return
+	}
+
+	if err := send(chunk(openai.ChatCompletionStreamChoiceDelta{}, openai.FinishReasonStop)); err != nil {
+		return
+	}
+	if includeUsage {
+		usage := openai.ChatCompletionStreamResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []openai.ChatCompletionStreamChoice{},
+			Usage: &openai.Usage{
+				PromptTokens:     answer.Usage.PromptTokens,
+				CompletionTokens: answer.Usage.CompletionTokens,
+				TotalTokens:      answer.Usage.TotalTokens,
+			},
+		}
+		if err := send(usage); err != nil {
+			return
+		}
+	}
+	fmt.Fprint(events.w, "data: [DONE]\n\n")
+	events.controller.Flush()
+}
+
+// conversationQuestion returns the last non-empty user message, resolving
+// words like "it" or "she" against the earlier user and assistant messages
+// the same way Session.Contextualize does for the session API.
+func conversationQuestion(messages []openai.ChatCompletionMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != openai.ChatMessageRoleUser {
+			continue
+		}
+		question := messageText(messages[i])
+		if question == "" {
+			continue
+		}
+		session := Session{Entities: make(map[string]string)}
+		for _, message := range messages[:i] {
+			if message.Role != openai.ChatMessageRoleUser && message.Role != openai.ChatMessageRoleAssistant {
+				continue
+			}
+			for key, value := range textEntities(messageText(message)) {
+				session.Entities[key] = value
+			}
+		}
+		return session.Contextualize(question)
+	}
+	return ""
+}
+
+// messageText returns a message's trimmed text.
+func messageText(message openai.ChatCompletionMessage) string {
+	text := message.Content
+	if text == "" {
+		// Multi-part messages carry their text in parts.
+		var parts []string
+		for _, part := range message.MultiContent {
+			if part.Type == openai.ChatMessagePartTypeText {
+				parts = append(parts, part.Text)
+			}
+		}
+		text = strings.Join(parts, "\n")
+	}
+	return strings.TrimSpace(text)
+}
+
+// textEntities finds the instructor, course and CRN a message is about. Like
+// citations, a message only sets the course or CRN when it names just one.
+func textEntities(text string) map[string]string {
+	entities := resolveEntities(text, Answer{})
+	if codes := findCourseCodes(text, nil); len(codes) == 1 {
+		entities[entityCourse] = codes[0].Subject + " " + codes[0].Number
+	}
+	var crns []string
+	for _, crn := range messageCRNPattern.FindAllString(text, -1) {
+		crns = appendUnique(crns, crn)
+	}
+	if len(crns) == 1 {
+		entities[entityCRN] = crns[0]
+	}
+	return entities
+}
+
+// messageCRNPattern matches the five-digit CRNs in a message.
+var messageCRNPattern = regexp.MustCompile(`\b\d{5}\b`)
+
+// newCompletionID returns a random ID in OpenAI's "chatcmpl-..." form.
+func newCompletionID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+	}
+	return "chatcmpl-" + hex.EncodeToString(b)
+}
+
+// openAIError builds the error object OpenAI clients expect.
+func openAIError(message string) map[string]interface{} {
+	return map[string]interface{}{
+		"message": message,
+		"type":    "invalid_request_error",
+		"code":    nil,
+	}
+}
+
+// writeOpenAIError writes an error response in OpenAI's shape.
+func writeOpenAIError(w http.ResponseWriter, status int, message string) {
+	body := openAIError(message)
+	if status >= http.StatusInternalServerError {
+		body["type"] = "server_error"
+	}
+	writeJSON(w, status, map[string]interface{}{"error": body})
+}
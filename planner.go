@@ -0,0 +1,303 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxSchedules bounds how many conflict-free combinations PlanSchedules will enumerate.
+const maxSchedules = 5000
+
+// ErrTooManySchedules is returned when the requested courses have more than
+// maxSchedules conflict-free combinations.
+var ErrTooManySchedules = fmt.Errorf("more than %d possible schedules; request fewer courses or name instructors", maxSchedules)
+
+// Penalties used to rank schedules; lower totals rank first.
+const (
+	penaltyEarlyClass    = 3 // Per meeting day starting before the preferred time.
+	penaltyDayOff        = 3 // Per meeting on a day the student wants off.
+	penaltyCampusDay     = 5 // Per campus day beyond the preferred maximum.
+	penaltyOtherTeacher  = 2 // Per section not taught by a preferred instructor.
+	penaltyUnknownTiming = 1 // Per section with no scheduled meeting time.
+)
+
+// ClassSection is one section (CRN) with all of its meeting rows.
+type ClassSection struct {
+	CRN             string    `json:"crn"`
+	Subject         string    `json:"subject"`
+	CourseNumber    string    `json:"course_number"`
+	Section         string    `json:"section"`
+	Title           string    `json:"title"`
+	Instructor      string    `json:"instructor,omitempty"`
+	InstructionMode string    `json:"instruction_mode"`
+	Meetings        []Meeting `json:"meetings"`
+	Rows            []Course  `json:"-"`
+}
+
+// online reports whether the section is taught online rather than on campus.
+func (s ClassSection) online() bool {
+	return containsFold(s.InstructionMode, "online")
+}
+
+// groupSections combines schedule rows that share a CRN, keeping file order.
+func groupSections(courses []Course) []ClassSection {
+	instructors := InitializeInstructors()
+	index := make(map[string]int)
+	var sections []ClassSection
+	for _, course := range courses {
+		i, ok := index[course.CRN]
+		if !ok {
+			i = len(sections)
+			index[course.CRN] = i
+			sections = append(sections, ClassSection{
+				CRN:             course.CRN,
+				Subject:         course.Subject,
+				CourseNumber:    course.CourseNumber,
+				Section:         course.Section,
+				Title:           strings.TrimSpace(course.Title),
+				Instructor:      courseInstructor(course, instructors),
+				InstructionMode: course.InstructionModeDesc,
+				Meetings:        []Meeting{},
+			})
+		}
+		sections[i].Rows = append(sections[i].Rows, course)
+		if meeting, ok := parseMeeting(course); ok {
+			sections[i].Meetings = append(sections[i].Meetings, meeting)
+		}
+	}
+	return sections
+}
+
+// sectionsConflict reports whether any meetings of two sections overlap.
+func sectionsConflict(a, b ClassSection) bool {
+	for _, ma := range a.Meetings {
+		for _, mb := range b.Meetings {
+			if ma.Conflicts(mb) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CourseRequest names a course a student wants to take.
+type CourseRequest struct {
+	Subject     string   `json:"subject"`
+	Number      string   `json:"number"`
+	Instructors []string `json:"instructors,omitempty"` // Preferred instructors, matched by name substring.
+}
+
+func (r CourseRequest) String() string {
+	return r.Subject + " " + r.Number
+}
+
+// ParseCourseRequest parses a course code such as "CS 272", "cs272" or
+// "Computer Science 272", optionally followed by "@" and comma-separated
+// preferred instructors, as in "CS 272@Peterson,Benson".
+func ParseCourseRequest(s string) (CourseRequest, error) {
+	course, instructors, _ := strings.Cut(s, "@")
+	course = strings.TrimSpace(course)
+
+	code, err := ParseCourseCode(course)
+	if err != nil {
+		return CourseRequest{}, fmt.Errorf("course %q must look like \"CS 272\"", s)
+	}
+	if code.Section != "" {
+		return CourseRequest{}, fmt.Errorf("course %q names a section; the planner chooses sections itself", s)
+	}
+	request := CourseRequest{Subject: code.Subject, Number: code.Number}
+	for _, name := range strings.Split(instructors, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			request.Instructors = append(request.Instructors, name)
+		}
+	}
+	return request, nil
+}
+
+// parseTimeOfDay parses a preferred time such as "10am", "1:30pm", "13:30"
+// or "1330" into minutes after midnight.
+func parseTimeOfDay(s string) (int, error) {
+	text := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	for _, layout := range []string{"3pm", "3:04pm", "15:04", "1504"} {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t.Hour()*60 + t.Minute(), nil
+		}
+	}
+	return 0, fmt.Errorf("time %q must look like \"10am\", \"1:30pm\" or \"13:30\"", s)
+}
+
+// Preferences are soft constraints used to rank schedules. Zero values mean no preference.
+type Preferences struct {
+	NotBefore     int    `json:"not_before,omitempty"`      // Minutes after midnight classes should not start before.
+	DaysOff       string `json:"days_off,omitempty"`        // Day letters the student wants free, e.g. "F".
+	MaxCampusDays int    `json:"max_campus_days,omitempty"` // Most days per week with in-person classes.
+}
+
+// Schedule is one conflict-free choice of a section for every requested course.
+type Schedule struct {
+	Sections   []ClassSection `json:"sections"`
+	CampusDays string         `json:"campus_days"`
+	Penalty    int            `json:"penalty"`
+	Unmet      []string       `json:"unmet_preferences"`
+}
+
+// PlanSchedules lists every combination of sections, one per requested course,
+// whose meetings never overlap, ranked so schedules that best fit prefs come first.
+func PlanSchedules(courses []Course, requests []CourseRequest, prefs Preferences) ([]Schedule, error) {
+	if len(requests) == 0 {
+		return nil, errors.New("no courses requested")
+	}
+
+	sections := groupSections(courses)
+	options := make([][]ClassSection, len(requests))
+	for i, request := range requests {
+		for _, section := range sections {
+			if strings.EqualFold(section.Subject, request.Subject) && strings.EqualFold(section.CourseNumber, request.Number) {
+				options[i] = append(options[i], section)
+			}
+		}
+		if len(options[i]) == 0 {
+			return nil, fmt.Errorf("no sections of %s in the schedule", request)
+		}
+	}
+
+	var schedules []Schedule
+	chosen := make([]ClassSection, 0, len(requests))
+	var search func(i int) error
+	search = func(i int) error {
+		if i == len(options) {
+			if len(schedules) == maxSchedules {
+				return ErrTooManySchedules
+			}
+			schedules = append(schedules, scoreSchedule(append([]ClassSection(nil), chosen...), requests, prefs))
+			return nil
+		}
+		for _, option := range options[i] {
+			conflict := false
+			for _, picked := range chosen {
+				if sectionsConflict(option, picked) {
+					conflict = true
+					break
+				}
+			}
+			if conflict {
+				continue
+			}
+			chosen = append(chosen, option)
+			if err := search(i + 1); err != nil {
+				return err
+			}
+			chosen = chosen[:len(chosen)-1]
+		}
+		return nil
+	}
+	if err := search(0); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(schedules, func(i, j int) bool {
+		if schedules[i].Penalty != schedules[j].Penalty {
+			return schedules[i].Penalty < schedules[j].Penalty
+		}
+		return len(schedules[i].CampusDays) < len(schedules[j].CampusDays)
+	})
+	return schedules, nil
+}
+
+// scoreSchedule works out a schedule's campus days and how well it fits prefs.
+func scoreSchedule(sections []ClassSection, requests []CourseRequest, prefs Preferences) Schedule {
+	schedule := Schedule{Sections: sections, Unmet: []string{}}
+
+	var campusDays strings.Builder
+	for _, section := range sections {
+		if !section.online() {
+			for _, m := range section.Meetings {
+				campusDays.WriteString(m.Days)
+			}
+		}
+	}
+	schedule.CampusDays = normalizeDays(campusDays.String())
+
+	for i, section := range sections {
+		label := fmt.Sprintf("%s %s-%s", section.Subject, section.CourseNumber, section.Section)
+		if len(section.Meetings) == 0 {
+			schedule.Penalty += penaltyUnknownTiming
+		}
+		for _, m := range section.Meetings {
+			if prefs.NotBefore > 0 && m.Start < prefs.NotBefore {
+				schedule.Penalty += penaltyEarlyClass * len(m.Days)
+				schedule.Unmet = append(schedule.Unmet, fmt.Sprintf("%s starts at %s", label, formatClock(m.Start)))
+			}
+			if days := daysIn(m.Days, prefs.DaysOff); days != "" {
+				schedule.Penalty += penaltyDayOff * len(days)
+				schedule.Unmet = append(schedule.Unmet, fmt.Sprintf("%s meets on %s", label, days))
+			}
+		}
+		if preferred := requests[i].Instructors; len(preferred) > 0 && !taughtByAny(section, preferred) {
+			schedule.Penalty += penaltyOtherTeacher
+			schedule.Unmet = append(schedule.Unmet, fmt.Sprintf("%s is not taught by %s", label, strings.Join(preferred, " or ")))
+		}
+	}
+
+	if prefs.MaxCampusDays > 0 && len(schedule.CampusDays) > prefs.MaxCampusDays {
+		extra := len(schedule.CampusDays) - prefs.MaxCampusDays
+		schedule.Penalty += penaltyCampusDay * extra
+		schedule.Unmet = append(schedule.Unmet, fmt.Sprintf("on campus %d days (%s)", len(schedule.CampusDays), schedule.CampusDays))
+	}
+	return schedule
+}
+
+// daysIn returns the day letters of days that also appear in set.
+func daysIn(days, set string) string {
+	set = strings.ToUpper(set)
+	var b strings.Builder
+	for _, day := range days {
+		if strings.ContainsRune(set, day) {
+			b.WriteRune(day)
+		}
+	}
+	return b.String()
+}
+
+// taughtByAny reports whether a section's instructor matches any of the names.
+func taughtByAny(section ClassSection, names []string) bool {
+	for _, name := range names {
+		if section.Instructor != "" && containsFold(section.Instructor, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSchedules renders schedules as text, numbering them from 1.
+func formatSchedules(schedules []Schedule) string {
+	var b strings.Builder
+	for i, schedule := range schedules {
+		fit := "meets all preferences"
+		if len(schedule.Unmet) > 0 {
+			fit = strings.Join(schedule.Unmet, "; ")
+		}
+		campus := schedule.CampusDays
+		if campus == "" {
+			campus = "none"
+		}
+		fmt.Fprintf(&b, "Schedule %d (campus days: %s; %s)\n", i+1, campus, fit)
+		for _, section := range schedule.Sections {
+			fmt.Fprintf(&b, "  %s %s-%s CRN %s %s", section.Subject, section.CourseNumber, section.Section, section.CRN, section.Title)
+			if section.Instructor != "" {
+				fmt.Fprintf(&b, " (%s)", section.Instructor)
+			}
+			b.WriteString("\n")
+			if len(section.Meetings) == 0 {
+				fmt.Fprintf(&b, "      no scheduled meeting time (%s)\n", section.InstructionMode)
+			}
+			for _, m := range section.Meetings {
+				fmt.Fprintf(&b, "      %s\n", m)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// plannerSection builds a one-row section for planner tests.
+func plannerSection(subject, number, section, crn, days, begin, end, mode, first, last string) Course {
+	return Course{
+		Subject:             subject,
+		CourseNumber:        number,
+		Section:             section,
+		CRN:                 crn,
+		Title:               subject + " " + number,
+		InstructionModeDesc: mode,
+		MeetDays:            days,
+		BeginTime:           begin,
+		EndTime:             end,
+		MeetStart:           first,
+		MeetEnd:             last,
+		InstructorFirstName: "Test",
+		InstructorLastName:  "Teacher" + section,
+	}
+}
+
+var plannerCourses = []Course{
+	plannerSection("CS", "110", "01", "1001", "MWF", "0800", "0905", "In-Person", "8/20/24", "12/4/24"),
+	plannerSection("CS", "110", "02", "1002", "TR", "1030", "1215", "In-Person", "8/20/24", "12/4/24"),
+	plannerSection("MATH", "201", "01", "2001", "MW", "0830", "1015", "In-Person", "8/20/24", "12/4/24"),
+	plannerSection("MATH", "201", "02", "2002", "TR", "1400", "1545", "In-Person", "8/20/24", "12/4/24"),
+	plannerSection("RHET", "110", "01", "3001", "TR", "1030", "1215", "In-Person", "10/15/24", "12/4/24"),
+	// Same times as RHET 110-01 but in the first half of the term, so no conflict.
+	plannerSection("LAB", "10", "01", "4001", "TR", "1030", "1215", "In-Person", "8/20/24", "10/11/24"),
+	plannerSection("RHET", "110", "02", "3002", "", "", "", "Online Asynchronous", "", ""),
+}
+
+func TestMeetingConflicts(t *testing.T) {
+	meeting := func(c Course) Meeting {
+		m, ok := parseMeeting(c)
+		if !ok {
+			t.Fatalf("Expected CRN %s to have a meeting", c.CRN)
+		}
+		return m
+	}
+	if !meeting(plannerCourses[0]).Conflicts(meeting(plannerCourses[2])) {
+		t.Error("Expected MWF 8:00 and MW 8:30 to conflict")
+	}
+	if meeting(plannerCourses[1]).Conflicts(meeting(plannerCourses[3])) {
+		t.Error("Expected TR 10:30 and TR 2:00 not to conflict")
+	}
+	if meeting(plannerCourses[0]).Conflicts(meeting(plannerCourses[1])) {
+		t.Error("Expected MWF and TR meetings not to conflict")
+	}
+	if _, ok := parseMeeting(plannerCourses[6]); ok {
+		t.Error("Expected an asynchronous section to have no meeting")
+	}
+}
+
+func TestPlanSchedules(t *testing.T) {
+	requests := []CourseRequest{{Subject: "CS", Number: "110"}, {Subject: "MATH", Number: "201"}}
+	schedules, err := PlanSchedules(plannerCourses, requests, Preferences{})
+	if err != nil {
+		t.Fatalf("PlanSchedules failed: %v", err)
+	}
+	// CS 110-01 and MATH 201-01 overlap on MW mornings; the other three pairs fit.
+	if len(schedules) != 3 {
+		t.Fatalf("Expected 3 schedules, got %d", len(schedules))
+	}
+	for _, schedule := range schedules {
+		if schedule.Sections[0].CRN == "1001" && schedule.Sections[1].CRN == "2001" {
+			t.Error("Expected the conflicting pair to be left out")
+		}
+	}
+
+	// Preferring no classes before 10am and Fridays off ranks the TR-only schedule first.
+	prefs := Preferences{NotBefore: 10 * 60, DaysOff: "F", MaxCampusDays: 2}
+	ranked, err := PlanSchedules(plannerCourses, requests, prefs)
+	if err != nil {
+		t.Fatalf("PlanSchedules failed: %v", err)
+	}
+	best := ranked[0]
+	if best.Sections[0].CRN != "1002" || best.Sections[1].CRN != "2002" {
+		t.Errorf("Expected CRNs 1002 and 2002 first, got %s and %s", best.Sections[0].CRN, best.Sections[1].CRN)
+	}
+	if best.Penalty != 0 || len(best.Unmet) != 0 || best.CampusDays != "TR" {
+		t.Errorf("Expected a perfect TR schedule, got %+v", best)
+	}
+	if last := ranked[len(ranked)-1]; last.Penalty == 0 || len(last.Unmet) == 0 {
+		t.Errorf("Expected the last schedule to miss preferences, got %+v", last)
+	}
+}
+
+func TestPlanSchedulesTermDatesAndOnline(t *testing.T) {
+	requests := []CourseRequest{{Subject: "CS", Number: "110"}, {Subject: "RHET", Number: "110"}}
+	schedules, err := PlanSchedules(plannerCourses, requests, Preferences{})
+	if err != nil {
+		t.Fatalf("PlanSchedules failed: %v", err)
+	}
+	// CS 110-02 and RHET 110-01 overlap from October on.
+	if len(schedules) != 3 {
+		t.Fatalf("Expected 3 schedules, got %d", len(schedules))
+	}
+
+	requests = []CourseRequest{{Subject: "LAB", Number: "10"}, {Subject: "RHET", Number: "110"}}
+	schedules, err = PlanSchedules(plannerCourses, requests, Preferences{})
+	if err != nil {
+		t.Fatalf("PlanSchedules failed: %v", err)
+	}
+	if len(schedules) != 2 {
+		t.Fatalf("Expected both combinations to fit, got %d", len(schedules))
+	}
+
+	// The online section adds no campus days.
+	schedules, err = PlanSchedules(plannerCourses, []CourseRequest{{Subject: "RHET", Number: "110"}}, Preferences{MaxCampusDays: 1})
+	if err != nil {
+		t.Fatalf("PlanSchedules failed: %v", err)
+	}
+	if schedules[0].Sections[0].CRN != "3002" {
+		t.Errorf("Expected the online section first, got CRN %s", schedules[0].Sections[0].CRN)
+	}
+}
+
+func TestPlanSchedulesPreferredInstructor(t *testing.T) {
+	requests := []CourseRequest{{Subject: "CS", Number: "110", Instructors: []string{"Teacher02"}}}
+	schedules, err := PlanSchedules(plannerCourses, requests, Preferences{})
+	if err != nil {
+		t.Fatalf("PlanSchedules failed: %v", err)
+	}
+	if schedules[0].Sections[0].CRN != "1002" {
+		t.Errorf("Expected the preferred instructor's section first, got CRN %s", schedules[0].Sections[0].CRN)
+	}
+}
+
+func TestPlanSchedulesErrors(t *testing.T) {
+	_, err := PlanSchedules(plannerCourses, []CourseRequest{{Subject: "CS", Number: "999"}}, Preferences{})
+	if err == nil {
+		t.Error("Expected an error for a course with no sections")
+	}
+
+	var many []Course
+	var requests []CourseRequest
+	for _, subject := range []string{"A", "B", "C", "D", "E"} {
+		for _, section := range []string{"1", "2", "3", "4", "5", "6"} {
+			many = append(many, plannerSection(subject, "100", section, subject+section, "", "", "", "Online Asynchronous", "", ""))
+		}
+		requests = append(requests, CourseRequest{Subject: subject, Number: "100"})
+	}
+	if _, err := PlanSchedules(many, requests, Preferences{}); !errors.Is(err, ErrTooManySchedules) {
+		t.Errorf("Expected ErrTooManySchedules, got %v", err)
+	}
+}
+
+func TestParseCourseRequest(t *testing.T) {
+	request, err := ParseCourseRequest("cs272@Peterson, Benson")
+	if err != nil {
+		t.Fatalf("ParseCourseRequest failed: %v", err)
+	}
+	if request.Subject != "CS" || request.Number != "272" || len(request.Instructors) != 2 || request.Instructors[1] != "Benson" {
+		t.Errorf("Unexpected request %+v", request)
+	}
+	for _, bad := range []string{"272", "CS", "CS 272 273"} {
+		if _, err := ParseCourseRequest(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+	if minutes, err := parseTimeOfDay("1:30pm"); err != nil || minutes != 13*60+30 {
+		t.Errorf("Expected 1:30pm to be 810 minutes, got %d, %v", minutes, err)
+	}
+}
+
+func TestServerSchedules(t *testing.T) {
+	server := newTestServer()
+	body := `{"courses": ["AAS 100", "BAT 101"], "not_before": "10am"}`
+	response := getJSON(t, server, http.MethodPost, "/schedules", body, http.StatusOK)
+	if response["total"] != float64(1) {
+		t.Errorf("Expected 1 schedule, got %v", response["total"])
+	}
+	getJSON(t, server, http.MethodPost, "/schedules", `{"courses": ["ZZZ 1"]}`, http.StatusNotFound)
+	getJSON(t, server, http.MethodPost, "/schedules", `{"courses": []}`, http.StatusBadRequest)
+}
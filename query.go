@@ -4,68 +4,209 @@ package main
 import(
 	"context"
 	"encoding/json"
+	"errors"
 	"strconv"
-	"os"
 	"log"
 	"fmt"
 	"time"
 	
 	chroma "github.com/amikos-tech/chroma-go"
 	"github.com/amikos-tech/chroma-go/openai"
+	"github.com/amikos-tech/chroma-go/types"
 )
 
+// Kinds of vector store failure. Every error returned by Add and Query is a *VectorStoreError
+// that matches exactly one of these with errors.Is.
+var (
+    ErrMissingAPIKey     = errors.New("OpenAI API key not set")
+    ErrConnection        = errors.New("cannot connect to ChromaDB")
+    ErrCollectionMissing = errors.New("collection not found")
+    ErrEmbedding         = errors.New("embedding failed")
+    ErrQueryFailed       = errors.New("query failed")
+    ErrAddFailed         = errors.New("adding documents failed")
+)
+
+// VectorStoreError records which vector store operation failed, what kind of failure it was,
+// and the underlying error.
+type VectorStoreError struct {
+    Op   string // Operation that failed, e.g. "query courses-collection".
+    Kind error  // One of the Err* kinds above.
+    Err  error  // Underlying error, if any.
+}
+
+func (e *VectorStoreError) Error() string {
+    if e.Err == nil {
+        return fmt.Sprintf("%s: %v", e.Op, e.Kind)
+    }
+    return fmt.Sprintf("%s: %v: %v", e.Op, e.Kind, e.Err)
+}
+
+// Unwrap lets errors.Is match both the failure kind and the underlying error.
+func (e *VectorStoreError) Unwrap() []error {
+    if e.Err == nil {
+        return []error{e.Kind}
+    }
+    return []error{e.Kind, e.Err}
+}
+
+// embeddingError marks errors that came from the embedding function rather than from ChromaDB.
+type embeddingError struct {
+    err error
+}
+
+func (e *embeddingError) Error() string { return e.err.Error() }
+func (e *embeddingError) Unwrap() error { return e.err }
+
+// taggedEmbeddingFunction wraps an embedding function so its failures surface as embeddingErrors.
+type taggedEmbeddingFunction struct {
+    types.EmbeddingFunction
+}
+
+func (f taggedEmbeddingFunction) EmbedDocuments(ctx context.Context, texts []string) ([]*types.Embedding, error) {
+    embeddings, err := f.EmbeddingFunction.EmbedDocuments(ctx, texts)
+    if err != nil {
+        return nil, &embeddingError{err}
+    }
+    return embeddings, nil
+}
 
+func (f taggedEmbeddingFunction) EmbedQuery(ctx context.Context, text string) (*types.Embedding, error) {
+    embedding, err := f.EmbeddingFunction.EmbedQuery(ctx, text)
+    if err != nil {
+        return nil, &embeddingError{err}
+    }
+    return embedding, nil
+}
 
-// Add adds a list of Course objects to the ChromaDB collection
-func Add(courses []Course) (context.Context, *chroma.Client, *chroma.Collection, *chroma.Collection) {
-    openaikey := os.Getenv("OPENAI_API_KEY")
-    if openaikey == "" {
-        log.Fatalf("OPENAI_PROJECT_KEY not set in environment variables")
+// classifyStoreError wraps err from op in a VectorStoreError of the kind it most likely represents,
+// checking the server heartbeat to tell connection failures apart from other failures.
+func classifyStoreError(ctx context.Context, client *chroma.Client, op string, fallbackKind error, err error) error {
+    var embedErr *embeddingError
+    if errors.As(err, &embedErr) {
+        return &VectorStoreError{Op: op, Kind: ErrEmbedding, Err: embedErr.err}
+    }
+    if client != nil && ctx.Err() == nil {
+        if _, hbErr := client.Heartbeat(ctx); hbErr != nil {
+            return &VectorStoreError{Op: op, Kind: ErrConnection, Err: err}
+        }
     }
+    return &VectorStoreError{Op: op, Kind: fallbackKind, Err: err}
+}
 
+// OpenCollections connects to ChromaDB and opens the course and instructor collections named in config.
+// When create is true, missing collections are created; otherwise they are reported as ErrCollectionMissing.
+// Without an API key the collections are opened with no embedding function, which is enough to count and
+// fetch documents but not to query or add them.
+func OpenCollections(config Config, create bool) (context.Context, *chroma.Client, *chroma.Collection, *chroma.Collection, error) {
     ctx := context.TODO()
-    client, err := chroma.NewClient("http://localhost:8000")
+    client, err := chroma.NewClient(config.ChromaURL)
     if err != nil {
-        log.Fatalf("Failed to create client: %v", err)
+        return nil, nil, nil, nil, &VectorStoreError{Op: "create client", Kind: ErrConnection, Err: err}
+    }
+    if _, err := client.Heartbeat(ctx); err != nil {
+        return nil, nil, nil, nil, &VectorStoreError{Op: "heartbeat", Kind: ErrConnection, Err: err}
+    }
+
+    var embeddingFunction types.EmbeddingFunction
+    if config.APIKey != "" {
+        openaiEf, err := openai.NewOpenAIEmbeddingFunction(config.APIKey)
+        if err != nil {
+            return nil, nil, nil, nil, &VectorStoreError{Op: "create embedding function", Kind: ErrEmbedding, Err: err}
+        }
+        embeddingFunction = taggedEmbeddingFunction{openaiEf}
+    }
+
+    openCollection := func(name string) (*chroma.Collection, error) {
+        if create {
+            collection, err := client.CreateCollection(ctx, name, nil, true, embeddingFunction, types.L2)
+            if err != nil {
+                return nil, classifyStoreError(ctx, client, "create "+name, ErrAddFailed, err)
+            }
+            return collection, nil
+        }
+        collection, err := client.GetCollection(ctx, name, embeddingFunction)
+        if err != nil {
+            return nil, classifyStoreError(ctx, client, "get "+name, ErrCollectionMissing, err)
+        }
+        return collection, nil
     }
 
-    openaiEf, err := openai.NewOpenAIEmbeddingFunction(openaikey)
+    coursesCollection, err := openCollection(config.CourseCollection)
+    if err != nil {
+        return nil, nil, nil, nil, err
+    }
+    instructorsCollection, err := openCollection(config.InstructorCollection)
     if err != nil {
-        log.Fatalf("Error creating OpenAI embedding function: %s", err)
+        return nil, nil, nil, nil, err
     }
+    return ctx, client, coursesCollection, instructorsCollection, nil
+}
 
-    // Get or create the courses collection
-    coursesCollection, err := client.GetCollection(ctx, "courses-collection", openaiEf)
+// DropCollections deletes the course and instructor collections named in config, if they exist,
+// so that the next Add loads them afresh
+func DropCollections(config Config) error {
+    ctx := context.TODO()
+    client, err := chroma.NewClient(config.ChromaURL)
+    if err != nil {
+        return &VectorStoreError{Op: "create client", Kind: ErrConnection, Err: err}
+    }
+    collections, err := client.ListCollections(ctx)
     if err != nil {
-        log.Fatalf("Failed to get courses collection: %v", err)
+        return classifyStoreError(ctx, client, "list collections", ErrQueryFailed, err)
+    }
+    for _, collection := range collections {
+        if collection.Name != config.CourseCollection && collection.Name != config.InstructorCollection {
+            continue
+        }
+        if _, err := client.DeleteCollection(ctx, collection.Name); err != nil {
+            return classifyStoreError(ctx, client, "delete "+collection.Name, ErrAddFailed, err)
+        }
+    }
+    return nil
+}
+
+// Add adds a list of Course objects to the ChromaDB collections named in config, creating them if needed.
+// If the course collection already has documents, the courses are not added again; only course summaries
+// it lacks are added, and loaded reports whether there were any.
+func Add(config Config, courses []Course) (ctx context.Context, client *chroma.Client, coursesCollection, instructorsCollection *chroma.Collection, loaded bool, err error) {
+    if config.APIKey == "" {
+        return nil, nil, nil, nil, false, &VectorStoreError{Op: "add", Kind: ErrMissingAPIKey, Err: fmt.Errorf("%s is empty", config.APIKeyEnv)}
     }
 
-    // Get or create the instructors collection
-    instructorsCollection, err := client.GetCollection(ctx, "instructors-collection", openaiEf)
+    ctx, client, coursesCollection, instructorsCollection, err = OpenCollections(config, true)
     if err != nil {
-        log.Fatalf("Failed to get instructors collection: %v", err)
+        return nil, nil, nil, nil, false, err
     }
 
     // Check if there are existing documents in the courses collection
-    testQueryResults, err := coursesCollection.Query(ctx, []string{"test"}, 1, nil, nil, nil)
-    if err == nil && len(testQueryResults.Documents) > 0 {
+    count, err := coursesCollection.Count(ctx)
+    if err == nil && count > 0 {
         fmt.Println("Courses already loaded in ChromaDB, skipping addition.")
-        return ctx, client, coursesCollection, instructorsCollection
+        // Collections loaded before course summaries existed still need them
+        added, err := ensureCourseSummaries(ctx, coursesCollection, config.AddRetries, courses)
+        if err != nil {
+            return nil, nil, nil, nil, false, classifyStoreError(ctx, client, "add course summaries", ErrAddFailed, err)
+        }
+        if added {
+            fmt.Println("Added the missing course summaries to the collection.")
+        }
+        // Instructor profiles are refreshed anyway, so re-ingesting keeps them up to date
+        if err := syncInstructorProfiles(ctx, instructorsCollection, courses); err != nil {
+            return nil, nil, nil, nil, false, classifyStoreError(ctx, client, "sync instructor profiles", ErrAddFailed, err)
+        }
+        return ctx, client, coursesCollection, instructorsCollection, added, nil
     }
 
     instructors := InitializeInstructors()
-    uniqueInstructorNames := make(map[string]struct{}) // Track unique instructors
 
     fmt.Printf("Adding %d courses to the collection...\n", len(courses))
     for i, course := range courses {
         fullName := course.InstructorFirstName + " " + course.InstructorLastName
         canonicalName := findCanonicalName(fullName, instructors)
 
-        // Track unique instructor canonical names
-        uniqueInstructorNames[canonicalName] = struct{}{}
-
         metadata := map[string]interface{}{
             "instructor_canonical_name": canonicalName,
+            documentLevel:               levelSection,
         }
 
         jsonData, err := json.Marshal(course)
@@ -79,54 +220,85 @@ func Add(courses []Course) (context.Context, *chroma.Client, *chroma.Collection,
 
         // Use retry mechanism to add the course
         fmt.Printf("Processing course %d of %d: %s\n", i+1, len(courses), course.Title)
-        addCourseWithRetry(ctx, coursesCollection, []map[string]interface{}{metadata}, []string{string(jsonData)}, []string{documentID})
+        if err := addCourseWithRetry(ctx, coursesCollection, config.AddRetries, []map[string]interface{}{metadata}, []string{string(jsonData)}, []string{documentID}); err != nil {
+            return nil, nil, nil, nil, false, classifyStoreError(ctx, client, "add course "+documentID, ErrAddFailed, err)
+        }
     }
 
-    fmt.Printf("Adding %d unique instructors to the collection...\n", len(uniqueInstructorNames))
-    for name := range uniqueInstructorNames {
-        // Unique document ID for the instructor
-        instructorID := name
+    // One summary per course, so searches can find courses before sections
+    fmt.Println("Adding course summaries to the collection...")
+    if err := addCourseSummaries(ctx, coursesCollection, config.AddRetries, courses); err != nil {
+        return nil, nil, nil, nil, false, classifyStoreError(ctx, client, "add course summaries", ErrAddFailed, err)
+    }
 
-        // Add instructor name to the instructors collection
-        addCourseWithRetry(ctx, instructorsCollection, nil, []string{name}, []string{instructorID})
+    // One profile per instructor, so instructor questions have their courses, times and places to go on
+    fmt.Println("Adding instructor profiles to the collection...")
+    if err := syncInstructorProfiles(ctx, instructorsCollection, courses); err != nil {
+        return nil, nil, nil, nil, false, classifyStoreError(ctx, client, "add instructor profiles", ErrAddFailed, err)
     }
 
     fmt.Println("Finished adding courses and instructors to the collections.")
-    return ctx, client, coursesCollection, instructorsCollection
+    return ctx, client, coursesCollection, instructorsCollection, true, nil
 }
 
 // addCourseWithRetry handles adding a document to the ChromaDB collection with retries.
-func addCourseWithRetry(ctx context.Context, collection *chroma.Collection, metadata []map[string]interface{}, documents []string, ids []string) {
-    retries := 3 // Maximum number of retries
+// It returns the last error if every attempt fails.
+func addCourseWithRetry(ctx context.Context, collection *chroma.Collection, retries int, metadata []map[string]interface{}, documents []string, ids []string) error {
+    return writeWithRetry(retries, "add", "added", ids[0], func() error {
+        _, err := collection.Add(ctx, nil, metadata, documents, ids)
+        return err
+    })
+}
+
+// upsertCourseWithRetry is addCourseWithRetry for documents that may already be stored,
+// which are replaced rather than left as they were.
+func upsertCourseWithRetry(ctx context.Context, collection *chroma.Collection, retries int, metadata []map[string]interface{}, documents []string, ids []string) error {
+    return writeWithRetry(retries, "upsert", "upserted", ids[0], func() error {
+        _, err := collection.Upsert(ctx, nil, metadata, documents, ids)
+        return err
+    })
+}
+
+// writeWithRetry calls write up to retries times, backing off between attempts.
+// It returns the last error if every attempt fails.
+func writeWithRetry(retries int, verb, done, id string, write func() error) error {
     var err error
 
     for i := 0; i < retries; i++ {
-        _, err = collection.Add(ctx, nil, metadata, documents, ids)
+        err = write()
         if err == nil {
-            fmt.Printf("Successfully added document with ID: %s\n", ids[0])
-            return
+            fmt.Printf("Successfully %s document with ID: %s\n", done, id)
+            return nil
         }
-        log.Printf("Retry %d: Failed to add document with ID %s: %v", i+1, ids[0], err)
+        log.Printf("Retry %d: Failed to %s document with ID %s: %v", i+1, verb, id, err)
         time.Sleep(time.Second * time.Duration(i+1)) // Exponential backoff
     }
 
-    // If all retries fail, log the final error
-    log.Printf("Failed to add document with ID %s after %d retries: %v", ids[0], retries, err)
+    // If all retries fail, log and return the final error
+    log.Printf("Failed to %s document with ID %s after %d retries: %v", verb, id, retries, err)
+    return err
+}
+
+// Query searches the ChromaDB collection for a term and retrieves up to nResults matching documents
+func Query(ctx context.Context, client *chroma.Client, collection *chroma.Collection, term string, nResults int) ([][]string, error) {
+    return QueryWhere(ctx, client, collection, term, nResults, nil)
 }
 
-// Query searches the ChromaDB collection for a term and retrieves matching documents
-func Query(ctx context.Context, client *chroma.Client, collection *chroma.Collection, term string) [][]string {
+// QueryWhere searches like Query, among only the documents whose metadata matches where
+func QueryWhere(ctx context.Context, client *chroma.Client, collection *chroma.Collection, term string, nResults int, where map[string]interface{}) ([][]string, error) {
+    if collection == nil {
+        return nil, &VectorStoreError{Op: "query", Kind: ErrCollectionMissing}
+    }
+
     terms := []string{term}
-    fmt.Printf("Querying for term: %s\n", term)
+    log.Printf("Querying for term: %s", term)
 
-    queryResults, err := collection.Query(ctx, terms, 5, nil, nil, nil)
+    queryResults, err := collection.Query(ctx, terms, int32(nResults), where, nil, nil)
     if err != nil {
-        log.Fatalf("failed to query collection: %v", err)
+        return nil, classifyStoreError(ctx, client, "query "+collection.Name, ErrQueryFailed, err)
     }
 
     documents := queryResults.Documents
 
-    return documents
+    return documents, nil
 }
-
-
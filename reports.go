@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// unassignedInstructor is the report bucket for sections with no instructor listed.
+const unassignedInstructor = "unassigned"
+
+// maxBackToBackGap is the longest break, in minutes, between two sessions
+// that still counts as back-to-back.
+const maxBackToBackGap = 15
+
+// Report output formats.
+const (
+	ReportCSV      = "csv"
+	ReportMarkdown = "markdown"
+	ReportJSON     = "json"
+)
+
+// BackToBack is a pair of an instructor's sessions in different buildings
+// with at most maxBackToBackGap minutes between them.
+type BackToBack struct {
+	Days           string `json:"days"`
+	FirstCRN       string `json:"first_crn"`
+	FirstBuilding  string `json:"first_building"`
+	SecondCRN      string `json:"second_crn"`
+	SecondBuilding string `json:"second_building"`
+	Ends           string `json:"ends"`   // When the first session ends, e.g. "10:50am".
+	Starts         string `json:"starts"` // When the second session starts.
+}
+
+func (b BackToBack) String() string {
+	return fmt.Sprintf("%s %s %s (%s) -> %s %s (%s)", b.Days, b.Ends, b.FirstCRN, b.FirstBuilding, b.Starts, b.SecondCRN, b.SecondBuilding)
+}
+
+// InstructorLoad summarizes one instructor's teaching for the term.
+type InstructorLoad struct {
+	Instructor   string       `json:"instructor"`
+	Email        string       `json:"email,omitempty"`
+	Sections     int          `json:"sections"`
+	CRNs         []string     `json:"crns"`
+	ContactHours float64      `json:"contact_hours_per_week"`
+	Enrollment   int          `json:"enrollment"`
+	Campuses     []string     `json:"campuses"`
+	BackToBack   []BackToBack `json:"back_to_back"`
+}
+
+// placedMeeting is a meeting with the CRN and building it belongs to.
+type placedMeeting struct {
+	crn      string
+	building string
+	meeting  Meeting
+}
+
+// InstructorLoads builds a teaching-load summary per canonical instructor,
+// sorted by name, with sections that list no instructor in a final
+// "unassigned" bucket.
+func InstructorLoads(courses []Course) []InstructorLoad {
+	instructors := InitializeInstructors()
+	loads := make(map[string]*InstructorLoad)
+	meetings := make(map[string][]placedMeeting)
+	seenCRN := make(map[string]map[string]bool)
+	seenMeeting := make(map[string]bool)
+
+	for _, course := range courses {
+		name := courseInstructor(course, instructors)
+		if name == "" {
+			name = unassignedInstructor
+		}
+		load, ok := loads[name]
+		if !ok {
+			load = &InstructorLoad{Instructor: name, CRNs: []string{}, Campuses: []string{}, BackToBack: []BackToBack{}}
+			loads[name] = load
+			seenCRN[name] = make(map[string]bool)
+		}
+		if load.Email == "" {
+			load.Email = strings.TrimSpace(course.InstructorEmail)
+		}
+		if campus := strings.TrimSpace(course.CampusCode); campus != "" && !slices.Contains(load.Campuses, campus) {
+			load.Campuses = append(load.Campuses, campus)
+		}
+
+		// Enrollment is repeated on every meeting row of a section; count it once.
+		if !seenCRN[name][course.CRN] {
+			seenCRN[name][course.CRN] = true
+			load.Sections++
+			load.CRNs = append(load.CRNs, course.CRN)
+			enrollment, _ := strconv.Atoi(strings.TrimSpace(course.ActualEnrollment))
+			load.Enrollment += enrollment
+		}
+
+		meeting, ok := parseMeeting(course)
+		if !ok {
+			continue
+		}
+		// Some sections list the same meeting once per room, as CRN 40355 does;
+		// the instructor only teaches it once.
+		key := fmt.Sprintf("%s|%s|%s|%d|%d", name, course.CRN, meeting.Days, meeting.Start, meeting.End)
+		if seenMeeting[key] {
+			continue
+		}
+		seenMeeting[key] = true
+		load.ContactHours += float64((meeting.End-meeting.Start)*len(meeting.Days)) / 60
+		meetings[name] = append(meetings[name], placedMeeting{course.CRN, strings.ToUpper(strings.TrimSpace(course.Building)), meeting})
+	}
+
+	result := make([]InstructorLoad, 0, len(loads))
+	for name, load := range loads {
+		sort.Strings(load.Campuses)
+		load.ContactHours = math.Round(load.ContactHours*100) / 100
+		if name != unassignedInstructor {
+			load.BackToBack = backToBackSessions(meetings[name])
+		}
+		result = append(result, *load)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if (result[i].Instructor == unassignedInstructor) != (result[j].Instructor == unassignedInstructor) {
+			return result[j].Instructor == unassignedInstructor
+		}
+		return result[i].Instructor < result[j].Instructor
+	})
+	return result
+}
+
+// filterLoads keeps the loads whose instructor name contains name, ignoring case.
+func filterLoads(loads []InstructorLoad, name string) []InstructorLoad {
+	if name == "" {
+		return loads
+	}
+	matches := []InstructorLoad{}
+	for _, load := range loads {
+		if containsFold(load.Instructor, name) {
+			matches = append(matches, load)
+		}
+	}
+	return matches
+}
+
+// backToBackSessions finds sessions that start in a different building
+// within maxBackToBackGap minutes of another session ending on the same day.
+func backToBackSessions(meetings []placedMeeting) []BackToBack {
+	found := []BackToBack{}
+	for _, first := range meetings {
+		for _, second := range meetings {
+			gap := second.meeting.Start - first.meeting.End
+			if gap < 0 || gap > maxBackToBackGap || !physicalBuilding(first.building) || !physicalBuilding(second.building) {
+				continue
+			}
+			if first.building == second.building || !first.meeting.datesOverlap(second.meeting) {
+				continue
+			}
+			days := daysIn(first.meeting.Days, second.meeting.Days)
+			if days == "" {
+				continue
+			}
+			found = append(found, BackToBack{
+				Days:           days,
+				FirstCRN:       first.crn,
+				FirstBuilding:  first.building,
+				SecondCRN:      second.crn,
+				SecondBuilding: second.building,
+				Ends:           formatClock(first.meeting.End),
+				Starts:         formatClock(second.meeting.Start),
+			})
+		}
+	}
+	return found
+}
+
+// physicalBuilding reports whether a building code is a real place rather
+// than the online or TBA placeholders.
+func physicalBuilding(building string) bool {
+	return building != "" && building != buildingOnline && building != buildingTBA
+}
+
+// ParseReportFormat accepts "csv", "markdown" (or "md") and "json".
+func ParseReportFormat(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case ReportCSV:
+		return ReportCSV, nil
+	case ReportMarkdown, "md":
+		return ReportMarkdown, nil
+	case ReportJSON:
+		return ReportJSON, nil
+	}
+	return "", fmt.Errorf("unknown report format %q (want csv, markdown or json)", s)
+}
+
+// WriteLoadReport writes instructor loads in the given format.
+func WriteLoadReport(w io.Writer, loads []InstructorLoad, format string) error {
+	switch format {
+	case ReportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(loads)
+	case ReportCSV:
+		out := csv.NewWriter(w)
+		out.Write([]string{"Instructor", "Email", "Sections", "CRNs", "Contact Hours/Week", "Enrollment", "Campuses", "Back-to-Back"})
+		for _, load := range loads {
+			out.Write(loadReportRow(load, " "))
+		}
+		out.Flush()
+		return out.Error()
+	case ReportMarkdown:
+		var b strings.Builder
+		b.WriteString("| Instructor | Email | Sections | CRNs | Contact Hours/Week | Enrollment | Campuses | Back-to-Back |\n")
+		b.WriteString("|---|---|---:|---|---:|---:|---|---|\n")
+		for _, load := range loads {
+			row := loadReportRow(load, "<br>")
+			for i := range row {
+				row[i] = strings.ReplaceAll(row[i], "|", `\|`)
+			}
+			fmt.Fprintf(&b, "| %s |\n", strings.Join(row, " | "))
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
+
+// loadReportRow formats one load as table cells, joining back-to-back
+// sessions with separator.
+func loadReportRow(load InstructorLoad, separator string) []string {
+	backToBack := make([]string, len(load.BackToBack))
+	for i, b := range load.BackToBack {
+		backToBack[i] = b.String()
+	}
+	return []string{
+		load.Instructor,
+		load.Email,
+		strconv.Itoa(load.Sections),
+		strings.Join(load.CRNs, " "),
+		strconv.FormatFloat(load.ContactHours, 'f', 2, 64),
+		strconv.Itoa(load.Enrollment),
+		strings.Join(load.Campuses, " "),
+		strings.Join(backToBack, ";"+separator),
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// loadRow builds a schedule row for report tests; an empty last name leaves
+// the instructor unassigned.
+func loadRow(crn, last, building, days, begin, end, enrollment string) Course {
+	first := "Greg"
+	if last == "" {
+		first = ""
+	}
+	return Course{
+		Subject:             "CS",
+		CourseNumber:        "100",
+		Section:             "01",
+		CRN:                 crn,
+		CampusCode:          "M",
+		MeetDays:            days,
+		BeginTime:           begin,
+		EndTime:             end,
+		MeetStart:           "8/20/24",
+		MeetEnd:             "12/4/24",
+		Building:            building,
+		Room:                "101",
+		ActualEnrollment:    enrollment,
+		InstructorFirstName: first,
+		InstructorLastName:  last,
+		InstructorEmail:     "teacher@usfca.edu",
+	}
+}
+
+var loadCourses = []Course{
+	loadRow("60001", "Benson", "LM", "MWF", "0915", "1020", "30"),
+	// Starts ten minutes after 60001 ends, in another building.
+	loadRow("60002", "Benson", "HR", "MW", "1030", "1215", "25"),
+	// A second meeting row of 60002; its enrollment must not count twice.
+	loadRow("60002", "Benson", "HR", "F", "1400", "1500", "25"),
+	// The same meeting listed once per room, like CRN 40355.
+	loadRow("40355", "", "FR", "MW", "1515", "1800", "6"),
+	loadRow("40355", "", "HR", "MW", "1515", "1800", "6"),
+}
+
+func TestInstructorLoads(t *testing.T) {
+	loads := InstructorLoads(loadCourses)
+	if len(loads) != 2 {
+		t.Fatalf("Expected Benson and the unassigned bucket, got %v", loads)
+	}
+
+	benson := loads[0]
+	if benson.Instructor != "Gregory Benson" {
+		t.Errorf("Expected the canonical name Gregory Benson, got %q", benson.Instructor)
+	}
+	if benson.Sections != 2 || benson.Enrollment != 55 {
+		t.Errorf("Expected 2 sections and 55 students, got %d and %d", benson.Sections, benson.Enrollment)
+	}
+	// 65 minutes x 3 + 105 minutes x 2 + 60 minutes = 465 minutes.
+	if benson.ContactHours != 7.75 {
+		t.Errorf("Expected 7.75 contact hours, got %v", benson.ContactHours)
+	}
+	if len(benson.BackToBack) != 1 || benson.BackToBack[0].Days != "MW" || benson.BackToBack[0].SecondBuilding != "HR" {
+		t.Errorf("Expected one MW back-to-back move from LM to HR, got %v", benson.BackToBack)
+	}
+
+	unassigned := loads[1]
+	if unassigned.Instructor != unassignedInstructor || unassigned.Sections != 1 || unassigned.Enrollment != 6 {
+		t.Errorf("Expected CRN 40355 alone in the unassigned bucket, got %+v", unassigned)
+	}
+	// 165 minutes twice a week, counted once despite the two rooms.
+	if unassigned.ContactHours != 5.5 {
+		t.Errorf("Expected 5.5 contact hours, got %v", unassigned.ContactHours)
+	}
+}
+
+func TestWriteLoadReport(t *testing.T) {
+	loads := InstructorLoads(loadCourses)
+
+	var csvOut strings.Builder
+	if err := WriteLoadReport(&csvOut, loads, ReportCSV); err != nil {
+		t.Fatalf("CSV report failed: %v", err)
+	}
+	records, err := csv.NewReader(strings.NewReader(csvOut.String())).ReadAll()
+	if err != nil || len(records) != 1+len(loads) || records[0][0] != "Instructor" {
+		t.Errorf("Expected a header and %d rows, got %v, %v", len(loads), records, err)
+	}
+
+	var md strings.Builder
+	if err := WriteLoadReport(&md, loads, ReportMarkdown); err != nil {
+		t.Fatalf("Markdown report failed: %v", err)
+	}
+	if !strings.Contains(md.String(), "| unassigned |") {
+		t.Errorf("Expected an unassigned row, got:\n%s", md.String())
+	}
+
+	if _, err := ParseReportFormat("xml"); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
+
+func TestServerInstructorReport(t *testing.T) {
+	server := newTestServer()
+	report := getJSON(t, server, http.MethodGet, "/reports/instructors?instructor=davis", "", http.StatusOK)
+	if instructors := report["instructors"].([]interface{}); len(instructors) != 1 {
+		t.Errorf("Expected one instructor matching davis, got %v", instructors)
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reports/instructors?format=csv", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Errorf("Expected a CSV report, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	getJSON(t, server, http.MethodGet, "/reports/instructors?format=xml", "", http.StatusBadRequest)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Building codes the schedule uses for rows that have no physical room.
+const (
+	buildingOnline = "ONL"
+	buildingTBA    = "TBA"
+)
+
+// Reasons a schedule row has no place in the room timetable.
+const (
+	unplacedOnline    = "online"     // Taught online, so it never occupies a room.
+	unplacedRoomTBA   = "room TBA"   // Meets at set times but has no room yet.
+	unplacedTimeTBA   = "time TBA"   // Has a room but no meeting days or times.
+	unplacedNoMeeting = "no meeting" // Neither a room nor a meeting time.
+)
+
+// RoomKey identifies a room by building code and room number.
+type RoomKey struct {
+	Building string `json:"building"`
+	Room     string `json:"room"`
+}
+
+func (k RoomKey) String() string {
+	return strings.TrimSpace(k.Building + " " + k.Room)
+}
+
+// newRoomKey normalizes a building and room so lookups ignore case and spacing.
+func newRoomKey(building, room string) RoomKey {
+	return RoomKey{
+		Building: strings.ToUpper(strings.TrimSpace(building)),
+		Room:     strings.ToUpper(strings.TrimSpace(room)),
+	}
+}
+
+// RoomBooking is one schedule row's use of a room.
+type RoomBooking struct {
+	CRN          string  `json:"crn"`
+	Subject      string  `json:"subject"`
+	CourseNumber string  `json:"course_number"`
+	Section      string  `json:"section"`
+	Title        string  `json:"title"`
+	Instructor   string  `json:"instructor,omitempty"`
+	Room         RoomKey `json:"room"`
+	Meeting      Meeting `json:"meeting"`
+}
+
+// UnplacedRow is a schedule row that is not in the room timetable, and why.
+type UnplacedRow struct {
+	CRN          string `json:"crn"`
+	Subject      string `json:"subject"`
+	CourseNumber string `json:"course_number"`
+	Section      string `json:"section"`
+	Building     string `json:"building,omitempty"`
+	Room         string `json:"room,omitempty"`
+	Mode         string `json:"instruction_mode"`
+	Reason       string `json:"reason"`
+}
+
+// DoubleBooking is a pair of sections scheduled in the same room at
+// overlapping times. CrossListed is set when both have identical meeting
+// times and instructor, which usually means one class listed under two CRNs.
+type DoubleBooking struct {
+	Room        RoomKey     `json:"room"`
+	First       RoomBooking `json:"first"`
+	Second      RoomBooking `json:"second"`
+	CrossListed bool        `json:"cross_listed"`
+}
+
+// RoomIndex is a weekly timetable of every room that appears in the schedule.
+type RoomIndex struct {
+	rooms    map[RoomKey][]RoomBooking
+	Unplaced []UnplacedRow
+}
+
+// NewRoomIndex builds the room timetable from schedule rows. Online and TBA
+// rows are kept in Unplaced rather than silently dropped.
+func NewRoomIndex(courses []Course) *RoomIndex {
+	instructors := InitializeInstructors()
+	index := &RoomIndex{rooms: make(map[RoomKey][]RoomBooking), Unplaced: []UnplacedRow{}}
+	for _, course := range courses {
+		key := newRoomKey(course.Building, course.Room)
+		meeting, timed := parseMeeting(course)
+
+		reason := ""
+		switch {
+		case key.Building == buildingOnline || (key.Building == "" && containsFold(course.InstructionModeDesc, "online")):
+			reason = unplacedOnline
+		case (key.Building == "" || key.Building == buildingTBA) && timed:
+			reason = unplacedRoomTBA
+		case key.Building == "" || key.Building == buildingTBA:
+			reason = unplacedNoMeeting
+		case !timed:
+			reason = unplacedTimeTBA
+		}
+		if reason != "" {
+			index.Unplaced = append(index.Unplaced, UnplacedRow{
+				CRN:          course.CRN,
+				Subject:      course.Subject,
+				CourseNumber: course.CourseNumber,
+				Section:      course.Section,
+				Building:     strings.TrimSpace(course.Building),
+				Room:         strings.TrimSpace(course.Room),
+				Mode:         course.InstructionModeDesc,
+				Reason:       reason,
+			})
+			continue
+		}
+
+		index.rooms[key] = append(index.rooms[key], RoomBooking{
+			CRN:          course.CRN,
+			Subject:      course.Subject,
+			CourseNumber: course.CourseNumber,
+			Section:      course.Section,
+			Title:        strings.TrimSpace(course.Title),
+			Instructor:   courseInstructor(course, instructors),
+			Room:         key,
+			Meeting:      meeting,
+		})
+	}
+	for key := range index.rooms {
+		sortBookings(index.rooms[key])
+	}
+	return index
+}
+
+// sortBookings orders bookings by first meeting day, then start time.
+func sortBookings(bookings []RoomBooking) {
+	sort.SliceStable(bookings, func(i, j int) bool {
+		a, b := bookings[i].Meeting, bookings[j].Meeting
+		if da, db := strings.IndexByte(weekDays, a.Days[0]), strings.IndexByte(weekDays, b.Days[0]); da != db {
+			return da < db
+		}
+		return a.Start < b.Start
+	})
+}
+
+// Rooms lists the rooms in a building, or in every building when building
+// is empty, sorted by building and room.
+func (idx *RoomIndex) Rooms(building string) []RoomKey {
+	building = strings.ToUpper(strings.TrimSpace(building))
+	rooms := []RoomKey{}
+	for key := range idx.rooms {
+		if building == "" || key.Building == building {
+			rooms = append(rooms, key)
+		}
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		if rooms[i].Building != rooms[j].Building {
+			return rooms[i].Building < rooms[j].Building
+		}
+		return rooms[i].Room < rooms[j].Room
+	})
+	return rooms
+}
+
+// Schedule returns a room's bookings in weekly order. The second result is
+// false when no section is ever scheduled in the room.
+func (idx *RoomIndex) Schedule(building, room string) ([]RoomBooking, bool) {
+	bookings, ok := idx.rooms[newRoomKey(building, room)]
+	return bookings, ok
+}
+
+// Occupants returns the bookings of a room that overlap the window from
+// start to end minutes on any of the given days.
+func (idx *RoomIndex) Occupants(building, room, days string, start, end int) []RoomBooking {
+	window := Meeting{Days: normalizeDays(days), Start: start, End: end}
+	occupants := []RoomBooking{}
+	for _, booking := range idx.rooms[newRoomKey(building, room)] {
+		if booking.Meeting.Conflicts(window) {
+			occupants = append(occupants, booking)
+		}
+	}
+	return occupants
+}
+
+// FreeRooms lists the rooms in a building with no booking overlapping the
+// window from start to end minutes on any of the given days.
+func (idx *RoomIndex) FreeRooms(building, days string, start, end int) []RoomKey {
+	free := []RoomKey{}
+	for _, key := range idx.Rooms(building) {
+		if len(idx.Occupants(key.Building, key.Room, days, start, end)) == 0 {
+			free = append(free, key)
+		}
+	}
+	return free
+}
+
+// DoubleBookings finds every pair of sections booked into the same room at
+// overlapping times, sorted by room.
+func (idx *RoomIndex) DoubleBookings() []DoubleBooking {
+	found := []DoubleBooking{}
+	for _, key := range idx.Rooms("") {
+		bookings := idx.rooms[key]
+		for i := range bookings {
+			for j := i + 1; j < len(bookings); j++ {
+				a, b := bookings[i], bookings[j]
+				if a.CRN == b.CRN || !a.Meeting.Conflicts(b.Meeting) {
+					continue
+				}
+				found = append(found, DoubleBooking{
+					Room:   key,
+					First:  a,
+					Second: b,
+					CrossListed: a.Meeting.Days == b.Meeting.Days && a.Meeting.Start == b.Meeting.Start &&
+						a.Meeting.End == b.Meeting.End && a.Instructor != "" && a.Instructor == b.Instructor,
+				})
+			}
+		}
+	}
+	return found
+}
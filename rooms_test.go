@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+// roomRow builds a schedule row in a room for room index tests.
+func roomRow(crn, building, room, days, begin, end, mode, last string) Course {
+	return Course{
+		Subject:             "CS",
+		CourseNumber:        "1" + crn[len(crn)-2:],
+		Section:             "01",
+		CRN:                 crn,
+		Title:               "Course " + crn,
+		InstructionModeDesc: mode,
+		MeetDays:            days,
+		BeginTime:           begin,
+		EndTime:             end,
+		MeetStart:           "8/20/24",
+		MeetEnd:             last,
+		Building:            building,
+		Room:                room,
+		InstructorFirstName: "Pat",
+		InstructorLastName:  "Lee",
+	}
+}
+
+var roomCourses = []Course{
+	roomRow("50001", "KA", "311", "TR", "1400", "1545", "In-Person", "12/4/24"),
+	roomRow("50002", "KA", "311", "MW", "0800", "0905", "In-Person", "12/4/24"),
+	roomRow("50003", "KA", "312", "TR", "0955", "1140", "In-Person", "12/4/24"),
+	// Overlaps 50003 in the same room: a double booking.
+	roomRow("50004", "KA", "312", "R", "1100", "1200", "Hybrid", "12/4/24"),
+	roomRow("50005", "ONL", "", "", "", "", "Online Asynchronous", ""),
+	roomRow("50006", "TBA", "TBA", "F", "1000", "1200", "In-Person", "12/4/24"),
+	roomRow("50007", "LM", "140", "", "", "", "In-Person", ""),
+}
+
+func TestRoomIndexSchedule(t *testing.T) {
+	index := NewRoomIndex(roomCourses)
+
+	bookings, ok := index.Schedule("ka", " 311")
+	if !ok || len(bookings) != 2 {
+		t.Fatalf("Expected 2 bookings in KA 311, got %v", bookings)
+	}
+	if bookings[0].CRN != "50002" {
+		t.Errorf("Expected Monday's class first, got CRN %s", bookings[0].CRN)
+	}
+	if _, ok := index.Schedule("LM", "140"); ok {
+		t.Error("Expected a room with only time-TBA rows to have no schedule")
+	}
+
+	// "Is KA 311 free Tuesday at 2pm?"
+	if occupants := index.Occupants("KA", "311", "T", 14*60, 14*60+1); len(occupants) != 1 || occupants[0].CRN != "50001" {
+		t.Errorf("Expected CRN 50001 in KA 311 on Tuesday at 2pm, got %v", occupants)
+	}
+	if occupants := index.Occupants("KA", "311", "T", 16*60, 17*60); len(occupants) != 0 {
+		t.Errorf("Expected KA 311 to be free Tuesday 4-5pm, got %v", occupants)
+	}
+}
+
+func TestRoomIndexFreeRooms(t *testing.T) {
+	index := NewRoomIndex(roomCourses)
+	free := index.FreeRooms("KA", "T", 14*60, 15*60)
+	if len(free) != 1 || free[0] != (RoomKey{"KA", "312"}) {
+		t.Errorf("Expected only KA 312 free Tuesday 2-3pm, got %v", free)
+	}
+	if free := index.FreeRooms("KA", "R", 10*60, 11*60); len(free) != 1 || free[0].Room != "311" {
+		t.Errorf("Expected only KA 311 free Thursday 10-11am, got %v", free)
+	}
+}
+
+func TestRoomIndexDoubleBookingsAndUnplaced(t *testing.T) {
+	index := NewRoomIndex(roomCourses)
+	doubles := index.DoubleBookings()
+	if len(doubles) != 1 || doubles[0].First.CRN != "50003" || doubles[0].Second.CRN != "50004" {
+		t.Fatalf("Expected 50003 and 50004 to be double-booked, got %v", doubles)
+	}
+	if doubles[0].CrossListed {
+		t.Error("Expected sections with different times not to look cross-listed")
+	}
+
+	reasons := map[string]string{}
+	for _, row := range index.Unplaced {
+		reasons[row.CRN] = row.Reason
+	}
+	want := map[string]string{"50005": unplacedOnline, "50006": unplacedRoomTBA, "50007": unplacedTimeTBA}
+	for crn, reason := range want {
+		if reasons[crn] != reason {
+			t.Errorf("Expected CRN %s to be unplaced as %q, got %q", crn, reason, reasons[crn])
+		}
+	}
+}
+
+func TestServerRooms(t *testing.T) {
+	server := newTestServer()
+
+	room := getJSON(t, server, http.MethodGet, "/rooms/LM/140?days=M&from=5pm", "", http.StatusOK)
+	if room["free"] != false {
+		t.Errorf("Expected LM 140 to be in use Monday at 5pm, got %v", room)
+	}
+	free := getJSON(t, server, http.MethodGet, "/rooms/LM/free?days=T&from=9am&to=10am", "", http.StatusOK)
+	if rooms := free["rooms"].([]interface{}); len(rooms) != 1 {
+		t.Errorf("Expected LM 140 free Tuesday morning, got %v", rooms)
+	}
+	getJSON(t, server, http.MethodGet, "/rooms/double-bookings", "", http.StatusOK)
+	getJSON(t, server, http.MethodGet, "/rooms/unplaced", "", http.StatusOK)
+	getJSON(t, server, http.MethodGet, "/rooms/LM/999", "", http.StatusNotFound)
+	getJSON(t, server, http.MethodGet, "/rooms/LM/free?from=9am", "", http.StatusBadRequest)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SectionFilter selects course sections by field. Empty fields match every
+// section; text fields match case-insensitively.
+type SectionFilter struct {
+	Subject      string            // Exact subject code, e.g. "CS".
+	CourseNumber string            // Exact course number, e.g. "272".
+	Section      string            // Exact section number, e.g. "01".
+	CRN          string            // Exact CRN.
+	Instructor   string            // Substring of the instructor's canonical name or email.
+	Building     string            // Exact building code, e.g. "KA".
+	Room         string            // Exact room.
+	Days         string            // Day letters the section must meet on, e.g. "MW".
+	Mode         string            // Substring of the instruction mode, e.g. "online".
+	Campus       string            // Exact campus code.
+	College      string            // Exact college code.
+	Title        string            // Substring of the title.
+	Extra        map[string]string // Exact values of extra attributes, by attribute name.
+}
+
+// Match reports whether a section satisfies every non-empty field of the filter.
+func (f SectionFilter) Match(course Course) bool {
+	exact := []struct{ want, got string }{
+		{f.Subject, course.Subject},
+		{f.CourseNumber, course.CourseNumber},
+		{f.Section, course.Section},
+		{f.CRN, course.CRN},
+		{f.Building, course.Building},
+		{f.Room, course.Room},
+		{f.Campus, course.CampusCode},
+		{f.College, course.College},
+	}
+	for _, field := range exact {
+		if field.want != "" && !strings.EqualFold(strings.TrimSpace(field.got), strings.TrimSpace(field.want)) {
+			return false
+		}
+	}
+
+	if f.Title != "" && !containsFold(course.Title, f.Title) {
+		return false
+	}
+	if f.Mode != "" && !containsFold(course.InstructionModeDesc, f.Mode) {
+		return false
+	}
+	if f.Instructor != "" {
+		name := courseInstructor(course, InitializeInstructors())
+		if !containsFold(name, f.Instructor) && !containsFold(course.InstructorEmail, f.Instructor) {
+			return false
+		}
+	}
+	for name, want := range f.Extra {
+		if !strings.EqualFold(strings.TrimSpace(course.Extra[name]), strings.TrimSpace(want)) {
+			return false
+		}
+	}
+	for _, day := range strings.ToUpper(f.Days) {
+		if !strings.ContainsRune(strings.ToUpper(course.MeetDays), day) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterSections returns the sections that match the filter, in their original order.
+func FilterSections(courses []Course, filter SectionFilter) []Course {
+	matches := []Course{}
+	for _, course := range courses {
+		if filter.Match(course) {
+			matches = append(matches, course)
+		}
+	}
+	return matches
+}
+
+// InstructorSummary describes one instructor and the sections they teach.
+type InstructorSummary struct {
+	Name     string   `json:"name"`
+	Email    string   `json:"email,omitempty"`
+	Sections int      `json:"sections"`
+	Subjects []string `json:"subjects"`
+}
+
+// summarizeInstructors groups sections by canonical instructor name, sorted
+// by name. Sections without an instructor are left out.
+func summarizeInstructors(courses []Course) []InstructorSummary {
+	instructors := InitializeInstructors()
+	byName := make(map[string]*InstructorSummary)
+	seenCRN := make(map[string]map[string]bool)
+	for _, course := range courses {
+		name := courseInstructor(course, instructors)
+		if name == "" {
+			continue
+		}
+		summary, ok := byName[name]
+		if !ok {
+			summary = &InstructorSummary{Name: name, Subjects: []string{}}
+			byName[name] = summary
+			seenCRN[name] = make(map[string]bool)
+		}
+		if summary.Email == "" {
+			summary.Email = strings.TrimSpace(course.InstructorEmail)
+		}
+		// A section with several meeting rows shares one CRN; count it once.
+		if !seenCRN[name][course.CRN] {
+			seenCRN[name][course.CRN] = true
+			summary.Sections++
+		}
+		if !slices.Contains(summary.Subjects, course.Subject) {
+			summary.Subjects = append(summary.Subjects, course.Subject)
+		}
+	}
+
+	summaries := make([]InstructorSummary, 0, len(byName))
+	for _, summary := range byName {
+		sort.Strings(summary.Subjects)
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
+
+// containsFold reports whether substr is in s, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
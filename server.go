@@ -0,0 +1,673 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// shutdownTimeout bounds how long the server waits for in-flight requests when shutting down.
+const shutdownTimeout = 10 * time.Second
+
+// healthCheckTimeout bounds the vector store probe of GET /healthz.
+const healthCheckTimeout = 2 * time.Second
+
+// maxRequestBody caps the size of JSON request bodies.
+const maxRequestBody = 64 << 10
+
+// Default and maximum page sizes for GET /sections.
+const (
+	defaultSectionLimit = 100
+	maxSectionLimit     = 1000
+)
+
+// Server exposes the chatbot over an HTTP JSON API.
+type Server struct {
+	bot           *ChatBot
+	sessions      *SessionManager
+	mux           *http.ServeMux
+	answerTimeout time.Duration
+}
+
+// NewServer creates a Server that answers requests with bot and keeps conversations in sessions.
+func NewServer(config Config, bot *ChatBot, sessions *SessionManager) *Server {
+	s := &Server{
+		bot:           bot,
+		sessions:      sessions,
+		mux:           http.NewServeMux(),
+		answerTimeout: time.Duration(config.AnswerTimeout),
+	}
+	s.routes()
+	return s
+}
+
+// routes registers the API endpoints.
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /healthz", s.handleHealthz)
+	s.mux.HandleFunc("POST /ask", s.handleAsk)
+	s.mux.HandleFunc("GET /ask/stream", s.handleAskStream)
+	s.mux.HandleFunc("POST /ask/stream", s.handleAskStream)
+	s.mux.HandleFunc("GET /sections", s.handleSections)
+	s.mux.HandleFunc("GET /sections/{crn}", s.handleSection)
+	s.mux.HandleFunc("GET /instructors", s.handleInstructors)
+	s.mux.HandleFunc("GET /terms", s.handleTerms)
+	s.mux.HandleFunc("POST /schedules", s.handleSchedules)
+	s.mux.HandleFunc("GET /calendar", s.handleCalendar)
+	s.mux.HandleFunc("GET /reports/instructors", s.handleInstructorReport)
+	s.mux.HandleFunc("GET /analytics/enrollment", s.handleEnrollment)
+	s.mux.HandleFunc("GET /analytics/top-sections", s.handleTopSections)
+	s.mux.HandleFunc("GET /rooms", s.handleRooms)
+	s.mux.HandleFunc("GET /rooms/double-bookings", s.handleDoubleBookings)
+	s.mux.HandleFunc("GET /rooms/unplaced", s.handleUnplacedRows)
+	s.mux.HandleFunc("GET /rooms/{building}/free", s.handleFreeRooms)
+	s.mux.HandleFunc("GET /rooms/{building}/{room}", s.handleRoom)
+	s.mux.HandleFunc("GET /v1/models", s.handleModels)
+	s.mux.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+}
+
+// ServeHTTP lets a Server be used as an http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled, then shuts
+// down gracefully, letting in-flight requests finish.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("Serving API on %s", addr)
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down API server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// handleHealthz reports that the server is up and whether the vector store
+// is reachable now. Without it answers fall back to lexical search, so the
+// server is still up but its status is "degraded".
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	reachable := false
+	if s.bot.courseCollection != nil && s.bot.chromaClient != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+		_, err := s.bot.chromaClient.Heartbeat(ctx)
+		reachable = err == nil
+	}
+	status := map[string]interface{}{
+		"status":       "ok",
+		"vector_store": reachable,
+	}
+	if !reachable {
+		status["status"] = "degraded"
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// askRequest is the body of POST /ask.
+type askRequest struct {
+	Question  string `json:"question"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// askResponse is the body returned by POST /ask.
+type askResponse struct {
+	Answer         string     `json:"answer"`
+	Grounded       bool       `json:"grounded"`
+	FallbackPolicy string     `json:"fallback_policy,omitempty"`
+	Retrieval      string     `json:"retrieval"`
+	Citations      []Citation `json:"citations"`
+	SessionID      string     `json:"session_id,omitempty"`
+}
+
+// newAskResponse converts an Answer into the API response shape.
+func newAskResponse(answer Answer, sessionID string) askResponse {
+	response := askResponse{
+		Answer:    answer.Text,
+		Grounded:  answer.Grounded,
+		Retrieval: answer.Retrieval,
+		Citations: answer.Citations(),
+		SessionID: sessionID,
+	}
+	if !answer.Grounded {
+		response.FallbackPolicy = answer.Policy.String()
+	}
+	return response
+}
+
+// handleAsk answers a question with citations of the sections it was grounded in.
+func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
+	var req askRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	req.Question = strings.TrimSpace(req.Question)
+	if req.Question == "" {
+		writeError(w, http.StatusBadRequest, "question is required")
+		return
+	}
+	session, err := s.sessions.Get(req.SessionID)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.answerTimeout)
+	defer cancel()
+	answer, err := s.bot.AskStream(ctx, session.Contextualize(req.Question), nil)
+	if err != nil {
+		if r.Context().Err() != nil {
+			return // The client went away; nobody is left to answer.
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, "answering the question took too long")
+			return
+		}
+		log.Printf("Error answering %q: %v", req.Question, err)
+		writeError(w, http.StatusBadGateway, "failed to answer the question")
+		return
+	}
+	s.recordTurn(session.ID, req.Question, answer)
+	writeJSON(w, http.StatusOK, newAskResponse(answer, session.ID))
+}
+
+// recordTurn saves a question and its answer in the session. Failing to save
+// is logged rather than failing the request, since the answer is still good.
+func (s *Server) recordTurn(sessionID, question string, answer Answer) {
+	if _, err := s.sessions.Record(sessionID, question, answer); err != nil {
+		log.Printf("Failed to record turn in session %s: %v", sessionID, err)
+	}
+}
+
+// writeSessionError reports a failure to load a session.
+func writeSessionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrInvalidSessionID) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	log.Printf("Failed to load session: %v", err)
+	writeError(w, http.StatusInternalServerError, "failed to load session")
+}
+
+// courses returns the schedule of the term named by the term query
+// parameter, or of the default term. It writes an error response and
+// returns false when the term is malformed or not loaded.
+func (s *Server) courses(w http.ResponseWriter, r *http.Request) ([]Course, bool) {
+	courses, err := s.bot.termCourses(r.URL.Query().Get("term"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrTermNotLoaded) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err.Error())
+		return nil, false
+	}
+	return courses, true
+}
+
+// handleTerms lists the loaded terms and the default term.
+func (s *Server) handleTerms(w http.ResponseWriter, r *http.Request) {
+	terms := []string{}
+	for _, term := range s.bot.Terms() {
+		if !term.IsZero() {
+			terms = append(terms, term.String())
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"terms":   terms,
+		"default": s.bot.defaultTerm.term.String(),
+	})
+}
+
+// handleSections lists sections matching the query-parameter filters, one page at a time.
+func (s *Server) handleSections(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	limit, err := intParam(query, "limit", defaultSectionLimit, 1, maxSectionLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	offset, err := intParam(query, "offset", 0, 0, math.MaxInt)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	matches := FilterSections(courses, sectionFilterFromQuery(query))
+
+	start := min(offset, len(matches))
+	page := matches[start:min(start+limit, len(matches))]
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":    len(matches),
+		"offset":   offset,
+		"limit":    limit,
+		"sections": page,
+	})
+}
+
+// sectionFilterFromQuery reads a SectionFilter from query parameters.
+// Parameters named "extra.<attribute>" filter on extra attributes.
+func sectionFilterFromQuery(query url.Values) SectionFilter {
+	var extra map[string]string
+	for name := range query {
+		if attribute, ok := strings.CutPrefix(name, "extra."); ok && attribute != "" {
+			if extra == nil {
+				extra = make(map[string]string)
+			}
+			extra[attribute] = query.Get(name)
+		}
+	}
+	return SectionFilter{
+		Subject:      query.Get("subject"),
+		CourseNumber: query.Get("number"),
+		Section:      query.Get("section"),
+		CRN:          query.Get("crn"),
+		Instructor:   query.Get("instructor"),
+		Building:     query.Get("building"),
+		Room:         query.Get("room"),
+		Days:         query.Get("days"),
+		Mode:         query.Get("mode"),
+		Campus:       query.Get("campus"),
+		College:      query.Get("college"),
+		Title:        query.Get("title"),
+		Extra:        extra,
+	}
+}
+
+// handleSection returns every meeting row of the section with the given CRN.
+func (s *Server) handleSection(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	crn := r.PathValue("crn")
+	rows := FilterSections(courses, SectionFilter{CRN: crn})
+	if len(rows) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no section with CRN %s", crn))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"crn":      crn,
+		"meetings": rows,
+	})
+}
+
+// handleInstructors lists instructors with their section counts and subjects.
+func (s *Server) handleInstructors(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"instructors": summarizeInstructors(courses),
+	})
+}
+
+// scheduleRequest is the body of POST /schedules.
+type scheduleRequest struct {
+	Courses       []string `json:"courses"`         // e.g. "CS 272" or "CS 272@Peterson,Benson".
+	NotBefore     string   `json:"not_before"`      // e.g. "10am".
+	DaysOff       string   `json:"days_off"`        // e.g. "F".
+	MaxCampusDays int      `json:"max_campus_days"` // 0 for no preference.
+	Limit         int      `json:"limit"`
+}
+
+// handleSchedules lists conflict-free schedules for the requested courses, best fit first.
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	var req scheduleRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if len(req.Courses) == 0 {
+		writeError(w, http.StatusBadRequest, "courses must list at least one course")
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultSectionLimit
+	}
+	if req.Limit < 1 || req.Limit > maxSectionLimit || req.MaxCampusDays < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d and max_campus_days must not be negative", maxSectionLimit))
+		return
+	}
+
+	requests := make([]CourseRequest, 0, len(req.Courses))
+	for _, course := range req.Courses {
+		request, err := ParseCourseRequest(course)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		requests = append(requests, request)
+	}
+	prefs := Preferences{DaysOff: normalizeDays(req.DaysOff), MaxCampusDays: req.MaxCampusDays}
+	if req.NotBefore != "" {
+		minutes, err := parseTimeOfDay(req.NotBefore)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		prefs.NotBefore = minutes
+	}
+
+	schedules, err := PlanSchedules(courses, requests, prefs)
+	switch {
+	case errors.Is(err, ErrTooManySchedules):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	total := len(schedules)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":     total,
+		"schedules": schedules[:min(req.Limit, total)],
+	})
+}
+
+// handleCalendar returns the sections named by the crn query parameters as
+// an iCalendar file. CRNs may be repeated or comma-separated, so a planned
+// schedule's CRNs can be passed straight through.
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	var crns []string
+	for _, value := range r.URL.Query()["crn"] {
+		for _, crn := range strings.Split(value, ",") {
+			if crn = strings.TrimSpace(crn); crn != "" {
+				crns = append(crns, crn)
+			}
+		}
+	}
+	if len(crns) == 0 {
+		writeError(w, http.StatusBadRequest, "crn is required")
+		return
+	}
+	sections, missing := sectionsByCRN(courses, crns)
+	if len(missing) > 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no section with CRN %s", strings.Join(missing, ", ")))
+		return
+	}
+
+	var body bytes.Buffer
+	if _, err := WriteICalendar(&body, sections, time.Now()); err != nil {
+		log.Printf("Failed to build calendar: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to build calendar")
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="schedule.ics"`)
+	w.Write(body.Bytes())
+}
+
+// handleInstructorReport returns the per-instructor teaching-load report as
+// JSON (the default), CSV or Markdown, optionally filtered by instructor name.
+func (s *Server) handleInstructorReport(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	format := ReportJSON
+	if query.Has("format") {
+		var err error
+		if format, err = ParseReportFormat(query.Get("format")); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	loads := filterLoads(InstructorLoads(courses), query.Get("instructor"))
+	if format == ReportJSON {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"instructors": loads})
+		return
+	}
+
+	var body bytes.Buffer
+	if err := WriteLoadReport(&body, loads, format); err != nil {
+		log.Printf("Failed to write instructor report: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to write report")
+		return
+	}
+	contentType := "text/csv; charset=utf-8"
+	if format == ReportMarkdown {
+		contentType = "text/markdown; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body.Bytes())
+}
+
+// handleEnrollment groups the sections matching the section filters by the
+// "by" dimension and reports enrollment statistics for each group.
+func (s *Server) handleEnrollment(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	by := BySubject
+	if query.Has("by") {
+		var err error
+		if by, err = ParseDimension(query.Get("by")); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	percentiles, err := ParsePercentiles(query.Get("percentiles"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	top, err := intParam(query, "top", math.MaxInt, 1, math.MaxInt)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sections := groupSections(FilterSections(courses, sectionFilterFromQuery(query)))
+	groups := EnrollmentByGroup(sections, by, percentiles)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"by":       by,
+		"sections": len(sections),
+		"groups":   groups[:min(top, len(groups))],
+	})
+}
+
+// handleTopSections lists the n sections matching the section filters with
+// the highest enrollment.
+func (s *Server) handleTopSections(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	n, err := intParam(query, "n", 10, 1, maxSectionLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sections := groupSections(FilterSections(courses, sectionFilterFromQuery(query)))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sections": TopSections(sections, n),
+	})
+}
+
+// handleRooms lists the rooms in the timetable, optionally only those in one building.
+func (s *Server) handleRooms(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"rooms": NewRoomIndex(courses).Rooms(r.URL.Query().Get("building")),
+	})
+}
+
+// handleRoom returns a room's weekly schedule. With days and from (and
+// optionally to) it also reports whether the room is free in that window.
+func (s *Server) handleRoom(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	building, room := r.PathValue("building"), r.PathValue("room")
+	index := NewRoomIndex(courses)
+	bookings, ok := index.Schedule(building, room)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no sections are scheduled in %s", newRoomKey(building, room)))
+		return
+	}
+	response := map[string]interface{}{
+		"room":     newRoomKey(building, room),
+		"bookings": bookings,
+	}
+	if r.URL.Query().Has("from") {
+		days, start, end, err := timeWindow(r.URL.Query())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		occupants := index.Occupants(building, room, days, start, end)
+		response["free"] = len(occupants) == 0
+		response["occupants"] = occupants
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleFreeRooms lists the rooms in a building that are free during a time window.
+func (s *Server) handleFreeRooms(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	days, start, end, err := timeWindow(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	index := NewRoomIndex(courses)
+	building := r.PathValue("building")
+	if len(index.Rooms(building)) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no rooms in building %s", building))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"building": strings.ToUpper(building),
+		"days":     days,
+		"from":     formatClock(start),
+		"to":       formatClock(end),
+		"rooms":    index.FreeRooms(building, days, start, end),
+	})
+}
+
+// handleDoubleBookings lists sections booked into the same room at overlapping times.
+func (s *Server) handleDoubleBookings(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"double_bookings": NewRoomIndex(courses).DoubleBookings(),
+	})
+}
+
+// handleUnplacedRows lists the online and TBA rows left out of the room timetable.
+func (s *Server) handleUnplacedRows(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courses(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"rows": NewRoomIndex(courses).Unplaced,
+	})
+}
+
+// timeWindow reads the days, from and to query parameters. Without to, the
+// window is the single minute at from, answering "is it free at 2pm?".
+func timeWindow(query url.Values) (string, int, int, error) {
+	days := normalizeDays(query.Get("days"))
+	if days == "" {
+		return "", 0, 0, errors.New("days is required, e.g. days=T")
+	}
+	start, err := parseTimeOfDay(query.Get("from"))
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("from: %w", err)
+	}
+	end := start + 1
+	if query.Get("to") != "" {
+		if end, err = parseTimeOfDay(query.Get("to")); err != nil {
+			return "", 0, 0, fmt.Errorf("to: %w", err)
+		}
+		if end <= start {
+			return "", 0, 0, errors.New("to must be later than from")
+		}
+	}
+	return days, start, end, nil
+}
+
+// intParam parses an integer query parameter, returning def when it is absent.
+func intParam(query url.Values, name string, def, lo, hi int) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("%s must be an integer between %d and %d", name, lo, hi)
+	}
+	return n, nil
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write JSON response: %v", err)
+	}
+}
+
+// writeError writes a JSON error response.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
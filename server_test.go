@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	chroma "github.com/amikos-tech/chroma-go"
+	"github.com/amikos-tech/chroma-go/types"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// newTestServer returns a server over the test courses with no vector store or LLM behind it
+func newTestServer() *Server {
+	bot := NewChatBot(DefaultConfig(), nil, &MetadataExtractor{courses: testCourses}, nil, nil, nil, nil)
+	return NewServer(DefaultConfig(), bot, NewSessionManager(NewMemorySessionStore(), time.Hour))
+}
+
+// getJSON performs a request against the server and decodes the JSON response
+func getJSON(t *testing.T, server *Server, method, target, body string, status int) map[string]interface{} {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != status {
+		t.Fatalf("%s %s: expected status %d, got %d: %s", method, target, status, rec.Code, rec.Body.String())
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("%s %s: invalid JSON response: %v", method, target, err)
+	}
+	return decoded
+}
+
+func TestServerSections(t *testing.T) {
+	server := newTestServer()
+
+	all := getJSON(t, server, http.MethodGet, "/sections", "", http.StatusOK)
+	if all["total"] != float64(2) {
+		t.Errorf("Expected 2 sections, got %v", all["total"])
+	}
+
+	filtered := getJSON(t, server, http.MethodGet, "/sections?days=TR&instructor=wayne", "", http.StatusOK)
+	sections := filtered["sections"].([]interface{})
+	if len(sections) != 1 || sections[0].(map[string]interface{})["CRN"] != "99999" {
+		t.Errorf("Expected only CRN 99999, got %v", sections)
+	}
+
+	getJSON(t, server, http.MethodGet, "/sections?limit=0", "", http.StatusBadRequest)
+	getJSON(t, server, http.MethodGet, "/sections/42180", "", http.StatusOK)
+	getJSON(t, server, http.MethodGet, "/sections/12345", "", http.StatusNotFound)
+}
+
+func TestServerInstructorsAndHealth(t *testing.T) {
+	server := newTestServer()
+
+	instructors := getJSON(t, server, http.MethodGet, "/instructors", "", http.StatusOK)["instructors"].([]interface{})
+	if len(instructors) != 2 || instructors[0].(map[string]interface{})["name"] != "Bruce Wayne" {
+		t.Errorf("Expected Bruce Wayne and Sheryl Davis, got %v", instructors)
+	}
+
+	health := getJSON(t, server, http.MethodGet, "/healthz", "", http.StatusOK)
+	if health["status"] != "degraded" || health["vector_store"] != false {
+		t.Errorf("Expected degraded without a vector store, got %v", health)
+	}
+}
+
+func TestServerHealthProbesVectorStore(t *testing.T) {
+	chromaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"nanosecond heartbeat": 1}`)
+	}))
+	defer chromaServer.Close()
+	client, err := chroma.NewClient(chromaServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection := chroma.NewCollection(client.ApiClient, "courses", "courses", nil, nil, types.DefaultTenant, types.DefaultDatabase)
+	bot := NewChatBot(DefaultConfig(), nil, &MetadataExtractor{courses: testCourses}, context.Background(), client, collection, nil)
+	server := NewServer(DefaultConfig(), bot, NewSessionManager(NewMemorySessionStore(), time.Hour))
+
+	health := getJSON(t, server, http.MethodGet, "/healthz", "", http.StatusOK)
+	if health["status"] != "ok" || health["vector_store"] != true {
+		t.Errorf("Expected ok with the vector store up, got %v", health)
+	}
+
+	// The store going down after startup shows on the next probe.
+	chromaServer.Close()
+	health = getJSON(t, server, http.MethodGet, "/healthz", "", http.StatusOK)
+	if health["status"] != "degraded" || health["vector_store"] != false {
+		t.Errorf("Expected degraded with the vector store down, got %v", health)
+	}
+}
+
+func TestServerAsk(t *testing.T) {
+	server := newTestServer()
+
+	getJSON(t, server, http.MethodPost, "/ask", `{"question": "  "}`, http.StatusBadRequest)
+	getJSON(t, server, http.MethodPost, "/ask", `not json`, http.StatusBadRequest)
+
+	// Nothing matches, so the suggest fallback answers without calling the LLM.
+	answer := getJSON(t, server, http.MethodPost, "/ask", `{"question": "underwater basket weaving", "session_id": "s1"}`, http.StatusOK)
+	if answer["grounded"] != false || answer["fallback_policy"] != "suggest" || answer["session_id"] != "s1" {
+		t.Errorf("Expected an ungrounded suggest answer for session s1, got %v", answer)
+	}
+}
+
+func TestServerAskStream(t *testing.T) {
+	server := newTestServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/ask/stream?question=underwater+basket+weaving", nil)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var events []string
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if event, ok := strings.CutPrefix(line, "event: "); ok {
+			events = append(events, event)
+		}
+	}
+	want := []string{eventToken, eventCitations, eventDone}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected events %v, got %v", want, events)
+	}
+	if !strings.Contains(rec.Body.String(), `"fallback_policy":"suggest"`) {
+		t.Errorf("Expected the done event to name the suggest policy, got:\n%s", rec.Body.String())
+	}
+
+	getJSON(t, server, http.MethodGet, "/ask/stream", "", http.StatusBadRequest)
+}
+
+// TestServerChatCompletions talks to the OpenAI-compatible facade with the OpenAI client library
+func TestServerChatCompletions(t *testing.T) {
+	httpServer := httptest.NewServer(newTestServer())
+	defer httpServer.Close()
+
+	config := openai.DefaultConfig("unused")
+	config.BaseURL = httpServer.URL + "/v1"
+	client := openai.NewClientWithConfig(config)
+	req := openai.ChatCompletionRequest{
+		Model: catalogModelID,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "You are helpful."},
+			{Role: openai.ChatMessageRoleUser, Content: "underwater basket weaving"},
+		},
+	}
+
+	resp, err := client.CreateChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(resp.Choices) != 1 || !strings.Contains(resp.Choices[0].Message.Content, "fallback policy: suggest") {
+		t.Errorf("Expected the suggest fallback answer, got %+v", resp.Choices)
+	}
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer stream.Close()
+	var streamed strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected no stream error, got %v", err)
+		}
+		if len(chunk.Choices) > 0 {
+			streamed.WriteString(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if streamed.String() != resp.Choices[0].Message.Content {
+		t.Errorf("Expected streamed answer %q, got %q", resp.Choices[0].Message.Content, streamed.String())
+	}
+
+	if _, err := client.ListModels(context.Background()); err != nil {
+		t.Errorf("Expected models to list, got %v", err)
+	}
+}
+
+func TestConversationQuestion(t *testing.T) {
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "You are helpful."},
+		{Role: openai.ChatMessageRoleUser, Content: "Who teaches CS 272?"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "Philip Peterson teaches CS 272-01 (CRN 41231)."},
+		{Role: openai.ChatMessageRoleUser, Content: "Where does it meet?"},
+	}
+	got := conversationQuestion(messages)
+	if !strings.HasPrefix(got, "Where does it meet? (") || !strings.Contains(got, "course CS 272") || !strings.Contains(got, "crn 41231") {
+		t.Errorf("Expected the follow-up resolved against earlier messages, got %q", got)
+	}
+
+	messages[3].Content = "Where does MATH 201 meet?"
+	if got := conversationQuestion(messages); got != "Where does MATH 201 meet?" {
+		t.Errorf("Expected a question naming its own course to be unchanged, got %q", got)
+	}
+	if got := conversationQuestion(messages[:1]); got != "" {
+		t.Errorf("Expected no question without a user message, got %q", got)
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSessionTurns caps how many turns of history a session keeps.
+const maxSessionTurns = 50
+
+// Keys of the entities a session remembers between questions.
+const (
+	entityInstructor = "instructor"
+	entityCourse     = "course"
+	entityCRN        = "crn"
+)
+
+// Turn is one question and its answer within a session.
+type Turn struct {
+	Question string    `json:"question"`
+	Answer   string    `json:"answer"`
+	Time     time.Time `json:"time"`
+}
+
+// Session is one user's conversation: its history and the entities it has
+// most recently talked about, so follow-up questions can refer back to them.
+type Session struct {
+	ID       string            `json:"id"`
+	History  []Turn            `json:"history"`
+	Entities map[string]string `json:"entities"`
+	LastSeen time.Time         `json:"last_seen"`
+}
+
+// ErrInvalidSessionID is returned for session IDs that are not 1-64 letters, digits, '-' or '_'.
+var ErrInvalidSessionID = errors.New("invalid session ID")
+
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// SessionStore persists sessions. Implementations must be safe for concurrent use.
+type SessionStore interface {
+	// Get returns the session with the given ID and whether it exists.
+	Get(id string) (Session, bool, error)
+	// Put saves a session, replacing any with the same ID.
+	Put(session Session) error
+	// Delete removes a session. Deleting a missing session is not an error.
+	Delete(id string) error
+	// DeleteIdleSince removes sessions last seen before cutoff and returns how many it removed.
+	DeleteIdleSince(cutoff time.Time) (int, error)
+}
+
+// MemorySessionStore keeps sessions in memory; they are lost on restart.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewMemorySessionStore creates an empty in-memory store.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]Session)}
+}
+
+func (m *MemorySessionStore) Get(id string) (Session, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[id]
+	return session.clone(), ok, nil
+}
+
+func (m *MemorySessionStore) Put(session Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[session.ID] = session.clone()
+	return nil
+}
+
+func (m *MemorySessionStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *MemorySessionStore) DeleteIdleSince(cutoff time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	removed := 0
+	for id, session := range m.sessions {
+		if session.LastSeen.Before(cutoff) {
+			delete(m.sessions, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// FileSessionStore keeps each session as a JSON file in a directory, so
+// sessions survive restarts.
+type FileSessionStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileSessionStore creates a store in dir, creating the directory if needed.
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating session directory: %w", err)
+	}
+	return &FileSessionStore{dir: dir}, nil
+}
+
+// path returns the file holding a session. IDs are validated before they reach here.
+func (f *FileSessionStore) path(id string) string {
+	return filepath.Join(f.dir, id+".json")
+}
+
+func (f *FileSessionStore) Get(id string) (Session, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.read(id)
+}
+
+// read loads a session file. The caller holds f.mu.
+func (f *FileSessionStore) read(id string) (Session, bool, error) {
+	data, err := os.ReadFile(f.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Session{}, false, nil
+	}
+	if err != nil {
+		return Session{}, false, err
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return Session{}, false, fmt.Errorf("reading session %s: %w", id, err)
+	}
+	return session, true, nil
+}
+
+func (f *FileSessionStore) Put(session Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Write to a temporary file and rename it so a crash never leaves a half-written session.
+	tmp, err := os.CreateTemp(f.dir, session.ID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(session.ID))
+}
+
+func (f *FileSessionStore) Delete(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := os.Remove(f.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (f *FileSessionStore) DeleteIdleSince(cutoff time.Time) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		session, found, err := f.read(id)
+		if err != nil || !found || !session.LastSeen.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(f.path(id)); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// SessionManager hands out sessions, records each turn and expires sessions
+// that have been idle longer than the TTL. It is safe for concurrent use.
+type SessionManager struct {
+	store SessionStore
+	ttl   time.Duration
+	now   func() time.Time
+
+	// locks serialise updates to the same session so concurrent requests in
+	// one session do not lose each other's turns. Sessions share a fixed set
+	// of locks by hash so the set does not grow with the number of sessions.
+	locks [64]sync.Mutex
+}
+
+// NewSessionManager creates a manager over store that expires sessions idle for longer than ttl.
+func NewSessionManager(store SessionStore, ttl time.Duration) *SessionManager {
+	return &SessionManager{store: store, ttl: ttl, now: time.Now}
+}
+
+// NewSessionStore returns the file-backed store for dir, or an in-memory store when dir is empty.
+func NewSessionStore(dir string) (SessionStore, error) {
+	if dir == "" {
+		return NewMemorySessionStore(), nil
+	}
+	return NewFileSessionStore(dir)
+}
+
+// lock returns the mutex guarding one session ID.
+func (m *SessionManager) lock(id string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return &m.locks[h.Sum32()%uint32(len(m.locks))]
+}
+
+// Get returns the session with the given ID. An empty ID gets a newly
+// generated one; an unknown or expired ID starts a fresh session under that ID.
+func (m *SessionManager) Get(id string) (Session, error) {
+	if id == "" {
+		id = newSessionID()
+	} else if !sessionIDPattern.MatchString(id) {
+		return Session{}, ErrInvalidSessionID
+	}
+
+	session, ok, err := m.store.Get(id)
+	if err != nil {
+		return Session{}, err
+	}
+	if !ok || m.expired(session) {
+		return Session{ID: id, Entities: map[string]string{}, LastSeen: m.now()}, nil
+	}
+	if session.Entities == nil {
+		session.Entities = map[string]string{}
+	}
+	return session, nil
+}
+
+// Record appends a turn to a session, updates its entities from the question
+// and the answer's citations, and saves it.
+func (m *SessionManager) Record(id, question string, answer Answer) (Session, error) {
+	l := m.lock(id)
+	l.Lock()
+	defer l.Unlock()
+
+	session, err := m.Get(id)
+	if err != nil {
+		return Session{}, err
+	}
+
+	now := m.now()
+	session.History = append(session.History, Turn{Question: question, Answer: answer.Text, Time: now})
+	if len(session.History) > maxSessionTurns {
+		session.History = session.History[len(session.History)-maxSessionTurns:]
+	}
+	for key, value := range resolveEntities(question, answer) {
+		session.Entities[key] = value
+	}
+	session.LastSeen = now
+
+	if err := m.store.Put(session); err != nil {
+		return Session{}, err
+	}
+	return session, nil
+}
+
+// Expire deletes sessions idle for longer than the TTL.
+func (m *SessionManager) Expire() (int, error) {
+	return m.store.DeleteIdleSince(m.now().Add(-m.ttl))
+}
+
+// RunJanitor expires idle sessions every interval until ctx is cancelled.
+func (m *SessionManager) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if removed, err := m.Expire(); err != nil {
+				log.Printf("Failed to expire sessions: %v", err)
+			} else if removed > 0 {
+				log.Printf("Expired %d idle sessions", removed)
+			}
+		}
+	}
+}
+
+// expired reports whether a session has been idle longer than the TTL.
+func (m *SessionManager) expired(session Session) bool {
+	return m.now().Sub(session.LastSeen) > m.ttl
+}
+
+// referringWords mark follow-up questions that lean on earlier turns, e.g.
+// "who teaches it?". Demonstratives such as "this" and "that" are left out:
+// they usually point within the question, as in "is that section full?".
+var referringWords = map[string]bool{
+	"it": true, "its": true, "they": true, "them": true,
+	"he": true, "she": true, "his": true, "her": true, "their": true,
+}
+
+// crnPattern matches a CRN named in a question, e.g. "CRN 41231" or "41231".
+var crnPattern = regexp.MustCompile(`(?i)\bcrn\b|\b\d{5}\b`)
+
+// Contextualize adds the session's remembered entities to a follow-up
+// question that refers back to them, so retrieval and the LLM can resolve
+// words like "it" or "she". Other questions, including follow-ups that name
+// a course or CRN of their own, are returned unchanged.
+func (session Session) Contextualize(question string) string {
+	if len(session.Entities) == 0 {
+		return question
+	}
+	if len(findCourseCodes(question, nil)) > 0 || crnPattern.MatchString(question) {
+		return question
+	}
+	refers := false
+	for _, word := range strings.FieldsFunc(strings.ToLower(question), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z')
+	}) {
+		if referringWords[word] {
+			refers = true
+			break
+		}
+	}
+	if !refers {
+		return question
+	}
+
+	var remembered []string
+	for _, key := range []string{entityCourse, entityCRN, entityInstructor} {
+		if value := session.Entities[key]; value != "" {
+			remembered = append(remembered, fmt.Sprintf("%s %s", key, value))
+		}
+	}
+	return fmt.Sprintf("%s (Earlier in this conversation: %s)", question, strings.Join(remembered, "; "))
+}
+
+// resolveEntities finds the instructor, course and section a turn was about.
+// Citations only set an entity when they all agree on it.
+func resolveEntities(question string, answer Answer) map[string]string {
+	entities := make(map[string]string)
+
+	lower := strings.ToLower(question)
+	for _, instructor := range InitializeInstructors() {
+		for _, alias := range append([]string{instructor.CanonicalName}, instructor.Aliases...) {
+			if strings.Contains(lower, strings.ToLower(alias)) {
+				entities[entityInstructor] = instructor.CanonicalName
+			}
+		}
+	}
+
+	citations := answer.Citations()
+	if len(citations) == 0 {
+		return entities
+	}
+	sameCourse, sameCRN, sameInstructor := true, true, true
+	first := citations[0]
+	for _, c := range citations[1:] {
+		sameCourse = sameCourse && c.Subject == first.Subject && c.CourseNumber == first.CourseNumber
+		sameCRN = sameCRN && c.CRN == first.CRN
+		sameInstructor = sameInstructor && c.Instructor == first.Instructor
+	}
+	if sameCourse {
+		entities[entityCourse] = first.Subject + " " + first.CourseNumber
+	}
+	if sameCRN {
+		entities[entityCRN] = first.CRN
+	}
+	if sameInstructor && first.Instructor != "" {
+		name, _ := canonicalName(first.Instructor, InitializeInstructors())
+		entities[entityInstructor] = name
+	}
+	return entities
+}
+
+// clone copies a session so stored sessions are not shared with callers.
+func (session Session) clone() Session {
+	session.History = append([]Turn(nil), session.History...)
+	entities := make(map[string]string, len(session.Entities))
+	for k, v := range session.Entities {
+		entities[k] = v
+	}
+	session.Entities = entities
+	return session
+}
+
+// newSessionID returns a random session ID.
+func newSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("s%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// courseDocument encodes a course the way Add stores it, for building test answers
+func courseDocument(t *testing.T, course Course) string {
+	t.Helper()
+	data, err := json.Marshal(course)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSessionManagerRecordAndExpire(t *testing.T) {
+	now := time.Date(2024, 8, 20, 9, 0, 0, 0, time.UTC)
+	manager := NewSessionManager(NewMemorySessionStore(), 30*time.Minute)
+	manager.now = func() time.Time { return now }
+
+	session, err := manager.Get("")
+	if err != nil || session.ID == "" {
+		t.Fatalf("Expected a new session with an ID, got %+v, %v", session, err)
+	}
+
+	answer := Answer{Text: "Sheryl Davis teaches it.", Grounded: true, Documents: []string{courseDocument(t, testCourses[0])}}
+	if _, err := manager.Record(session.ID, "Who teaches Black Activists?", answer); err != nil {
+		t.Fatal(err)
+	}
+
+	session, _ = manager.Get(session.ID)
+	if len(session.History) != 1 || session.Entities[entityCourse] != "AAS 100" || session.Entities[entityInstructor] != "Sheryl Davis" {
+		t.Errorf("Expected one turn about AAS 100 with Sheryl Davis, got %+v", session)
+	}
+	if got := session.Contextualize("Where does it meet?"); !strings.Contains(got, "course AAS 100") {
+		t.Errorf("Expected the follow-up to mention AAS 100, got %q", got)
+	}
+	if got := session.Contextualize("Where does Bioinformatics meet?"); got != "Where does Bioinformatics meet?" {
+		t.Errorf("Expected an unrelated question to be unchanged, got %q", got)
+	}
+	for _, question := range []string{"Is CS 272 like it?", "Who teaches them in CRN 41231?", "Is this section full?"} {
+		if got := session.Contextualize(question); got != question {
+			t.Errorf("Expected %q to be unchanged, got %q", question, got)
+		}
+	}
+
+	now = now.Add(31 * time.Minute)
+	if session, _ := manager.Get(session.ID); len(session.History) != 0 {
+		t.Errorf("Expected an expired session to start fresh, got %+v", session)
+	}
+	if removed, err := manager.Expire(); err != nil || removed != 1 {
+		t.Errorf("Expected to expire 1 session, got %d, %v", removed, err)
+	}
+
+	if _, err := manager.Get("../etc/passwd"); err != ErrInvalidSessionID {
+		t.Errorf("Expected ErrInvalidSessionID, got %v", err)
+	}
+}
+
+func TestFileSessionStoreSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileSessionStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSessionManager(store, time.Hour).Record("student-1", "What is CS 272?", Answer{Text: "Software Development"}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileSessionStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := NewSessionManager(reopened, time.Hour).Get("student-1")
+	if err != nil || len(session.History) != 1 || session.History[0].Answer != "Software Development" {
+		t.Errorf("Expected the recorded turn after reopening, got %+v, %v", session, err)
+	}
+}
+
+func TestSessionManagerConcurrentRecords(t *testing.T) {
+	manager := NewSessionManager(NewMemorySessionStore(), time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := manager.Record("shared", fmt.Sprintf("question %d", i), Answer{}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	session, _ := manager.Get("shared")
+	if len(session.History) != 20 {
+		t.Errorf("Expected 20 turns, got %d", len(session.History))
+	}
+}
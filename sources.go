@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Schedule source formats.
+const (
+	FormatCSV   = "csv"
+	FormatTSV   = "tsv"
+	FormatJSON  = "json"  // An array of objects, one per schedule row.
+	FormatJSONL = "jsonl" // One object per line.
+	FormatXLSX  = "xlsx"  // The first worksheet of an Excel workbook.
+)
+
+// formatExtensions maps file extensions to source formats.
+var formatExtensions = map[string]string{
+	".csv":    FormatCSV,
+	".tsv":    FormatTSV,
+	".tab":    FormatTSV,
+	".json":   FormatJSON,
+	".jsonl":  FormatJSONL,
+	".ndjson": FormatJSONL,
+	".xlsx":   FormatXLSX,
+}
+
+// maxSourceErrors caps how many row errors are reported for one source.
+const maxSourceErrors = 20
+
+// SourceError is a problem with a schedule source, or with one of its rows.
+// Every reader reports its errors this way.
+type SourceError struct {
+	Format string
+	Row    int // Line or row number in the source, or for JSON arrays the element's position; 0 for the source as a whole.
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	if e.Row == 0 {
+		return fmt.Sprintf("%s: %v", e.Format, e.Err)
+	}
+	return fmt.Sprintf("%s row %d: %v", e.Format, e.Row, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// rowSource yields a schedule source's rows of text cells after its
+// header, the common form every reader produces.
+type rowSource interface {
+	columns() []string
+	// next returns the next non-blank row and its row number in the
+	// source, or io.EOF after the last row.
+	next() ([]string, int, error)
+}
+
+// sourceTable is a schedule source read whole, for formats whose columns
+// are only known once every row has been seen.
+type sourceTable struct {
+	header  []string
+	rows    [][]string
+	numbers []int // Source row number of each row, for error messages.
+	read    int   // Rows already returned by next.
+}
+
+func (t *sourceTable) columns() []string {
+	return t.header
+}
+
+func (t *sourceTable) next() ([]string, int, error) {
+	if t.read == len(t.rows) {
+		return nil, 0, io.EOF
+	}
+	t.read++
+	return t.rows[t.read-1], t.numbers[t.read-1], nil
+}
+
+// add appends a row unless every cell is blank.
+func (t *sourceTable) add(row []string, number int) {
+	if strings.TrimSpace(strings.Join(row, "")) == "" {
+		return
+	}
+	t.rows = append(t.rows, row)
+	t.numbers = append(t.numbers, number)
+}
+
+// SourceReader reads one schedule format. Each call to open keeps its own
+// parser state, so one SourceReader can read several sources at once.
+type SourceReader interface {
+	Format() string
+	open(r io.Reader) (rowSource, error)
+}
+
+// ParseSourceFormat accepts a format name, or a file extension such as ".xlsx".
+func ParseSourceFormat(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if format, ok := formatExtensions["."+strings.TrimPrefix(s, ".")]; ok {
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown schedule format %q (want csv, tsv, json, jsonl or xlsx)", s)
+}
+
+// SourceReaderFor returns the reader for format, or for the file extension of
+// path when format is empty. A mapping's delimiter overrides the CSV and TSV
+// defaults.
+func SourceReaderFor(format, path string, mapping *ColumnMapping) (SourceReader, error) {
+	if format == "" {
+		ext := strings.ToLower(filepath.Ext(path))
+		var ok bool
+		if format, ok = formatExtensions[ext]; !ok {
+			return nil, fmt.Errorf("can't tell the format of %s from its extension %q; set the format explicitly", path, ext)
+		}
+	} else {
+		var err error
+		if format, err = ParseSourceFormat(format); err != nil {
+			return nil, err
+		}
+	}
+
+	switch format {
+	case FormatCSV, FormatTSV:
+		reader := delimitedReader{format: format, comma: ','}
+		if format == FormatTSV {
+			reader.comma = '\t'
+		}
+		if mapping != nil && mapping.Delimiter != "" {
+			reader.comma = mapping.delimiter()
+		}
+		return reader, nil
+	case FormatJSON:
+		return jsonReader{}, nil
+	case FormatJSONL:
+		return jsonLinesReader{}, nil
+	default:
+		return xlsxReader{}, nil
+	}
+}
+
+// ReadSourceFile reads every record of a schedule file; see OpenSourceFile.
+func ReadSourceFile(path, format string, mapping *ColumnMapping) ([]Course, []string, error) {
+	records, err := OpenSourceFile(path, format, mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer records.Close()
+	return records.All()
+}
+
+// ReadSource reads every record of a schedule source; see NewCourseIterator.
+func ReadSource(r io.Reader, reader SourceReader, mapping *ColumnMapping) ([]Course, []string, error) {
+	records, err := NewCourseIterator(r, reader, mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+	return records.All()
+}
+
+// CourseIterator yields a schedule source's records one at a time. CSV and
+// TSV sources are streamed; the other formats are read whole when opened.
+// Use it like bufio.Scanner:
+//
+//	for records.Next() {
+//		course := records.Course()
+//	}
+//	if err := records.Err(); err != nil {
+//
+// Rows without a CRN are skipped and reported by Err.
+type CourseIterator struct {
+	format   string
+	rows     rowSource
+	binding  *columnBinding
+	closer   io.Closer
+	course   Course
+	row      int
+	err      error
+	problems []error
+}
+
+// NewCourseIterator reads the header of a schedule source with reader and
+// checks it against a column mapping; a nil mapping reads the standard
+// export's columns.
+func NewCourseIterator(r io.Reader, reader SourceReader, mapping *ColumnMapping) (*CourseIterator, error) {
+	if mapping == nil {
+		mapping = DefaultColumnMapping()
+	}
+	rows, err := reader.open(r)
+	if err != nil {
+		var sourceErr *SourceError
+		if !errors.As(err, &sourceErr) {
+			err = &SourceError{Format: reader.Format(), Err: err}
+		}
+		return nil, err
+	}
+	binding, err := mapping.bind(rows.columns())
+	if err != nil {
+		return nil, &SourceError{Format: reader.Format(), Err: err}
+	}
+	return &CourseIterator{format: reader.Format(), rows: rows, binding: binding}, nil
+}
+
+// OpenSourceFile opens a schedule file in the given format, or the one its
+// extension names, for reading through a column mapping. The caller must
+// Close the iterator.
+func OpenSourceFile(path, format string, mapping *ColumnMapping) (*CourseIterator, error) {
+	reader, err := SourceReaderFor(format, path, mapping)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	records, err := NewCourseIterator(file, reader, mapping)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	records.closer = file
+	return records, nil
+}
+
+// Next advances to the next record, reporting false at the end of the
+// source or on an error that stops reading.
+func (it *CourseIterator) Next() bool {
+	for it.err == nil {
+		row, number, err := it.rows.next()
+		if err == io.EOF {
+			return false
+		}
+		if err != nil {
+			var sourceErr *SourceError
+			if !errors.As(err, &sourceErr) {
+				err = &SourceError{Format: it.format, Row: number, Err: err}
+			}
+			it.err = err
+			return false
+		}
+		course := it.binding.course(row)
+		if strings.TrimSpace(course.CRN) == "" {
+			it.problems = append(it.problems, &SourceError{Format: it.format, Row: number, Err: errors.New("missing CRN")})
+			continue
+		}
+		it.course, it.row = course, number
+		return true
+	}
+	return false
+}
+
+// Course returns the current record.
+func (it *CourseIterator) Course() Course {
+	return it.course
+}
+
+// Row returns the current record's row number in the source.
+func (it *CourseIterator) Row() int {
+	return it.row
+}
+
+// Header returns the source's column headers.
+func (it *CourseIterator) Header() []string {
+	return it.rows.columns()
+}
+
+// Err returns the error that stopped reading, if any, joined with the
+// problems of skipped rows.
+func (it *CourseIterator) Err() error {
+	problems := it.problems
+	if len(problems) > maxSourceErrors {
+		problems = append(problems[:maxSourceErrors:maxSourceErrors], fmt.Errorf("and %d more", len(problems)-maxSourceErrors))
+	}
+	if it.err != nil {
+		problems = append([]error{it.err}, problems...)
+	}
+	return errors.Join(problems...)
+}
+
+// Close closes the file an iterator from OpenSourceFile reads.
+func (it *CourseIterator) Close() error {
+	if it.closer == nil {
+		return nil
+	}
+	return it.closer.Close()
+}
+
+// All reads the remaining records and returns them with the header. It
+// returns no records if any row had a problem.
+func (it *CourseIterator) All() ([]Course, []string, error) {
+	var courses []Course
+	for it.Next() {
+		courses = append(courses, it.Course())
+	}
+	if err := it.Err(); err != nil {
+		return nil, nil, err
+	}
+	return courses, it.Header(), nil
+}
+
+// delimitedReader reads CSV and TSV files. The first row is the header.
+type delimitedReader struct {
+	format string
+	comma  rune
+}
+
+func (d delimitedReader) Format() string {
+	return d.format
+}
+
+func (d delimitedReader) open(r io.Reader) (rowSource, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = d.comma
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header row: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff") // Byte order mark written by spreadsheet exports.
+	}
+
+	return &delimitedRows{format: d.format, reader: reader, header: header}, nil
+}
+
+// delimitedRows streams the rows of a CSV or TSV source.
+type delimitedRows struct {
+	format string
+	reader *csv.Reader
+	header []string
+}
+
+func (d *delimitedRows) columns() []string {
+	return d.header
+}
+
+func (d *delimitedRows) next() ([]string, int, error) {
+	for {
+		record, err := d.reader.Read()
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, parseErr.Line, &SourceError{Format: d.format, Row: parseErr.Line, Err: parseErr.Err}
+			}
+			return nil, 0, err
+		}
+		if strings.TrimSpace(strings.Join(record, "")) != "" {
+			line, _ := d.reader.FieldPos(0)
+			return record, line, nil
+		}
+	}
+}
+
+// jsonReader reads a JSON array of objects. Object keys are the columns,
+// in the order they first appear.
+type jsonReader struct{}
+
+func (jsonReader) Format() string {
+	return FormatJSON
+}
+
+func (jsonReader) open(r io.Reader) (rowSource, error) {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, errors.New("expected an array of objects")
+	}
+	table := &sourceTable{}
+	columns := make(map[string]int)
+	for n := 1; decoder.More(); n++ {
+		object, err := decodeObject(decoder)
+		if err != nil {
+			return nil, &SourceError{Format: FormatJSON, Row: n, Err: err}
+		}
+		table.add(object.row(table, columns), n)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// jsonLinesReader reads one JSON object per line. Blank lines are skipped.
+type jsonLinesReader struct{}
+
+func (jsonLinesReader) Format() string {
+	return FormatJSONL
+}
+
+func (jsonLinesReader) open(r io.Reader) (rowSource, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	table := &sourceTable{}
+	columns := make(map[string]int)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		object, err := decodeObject(json.NewDecoder(bytes.NewReader(text)))
+		if err != nil {
+			return nil, &SourceError{Format: FormatJSONL, Row: line, Err: err}
+		}
+		table.add(object.row(table, columns), line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// jsonObject is a decoded JSON object with its keys in source order.
+type jsonObject struct {
+	keys   []string
+	values map[string]string
+}
+
+// decodeObject decodes the next value of decoder, which must be an object.
+// Values become cell text: strings as they are, null as "", and numbers,
+// booleans and nested values as their JSON text.
+func decodeObject(decoder *json.Decoder) (jsonObject, error) {
+	object := jsonObject{values: make(map[string]string)}
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return object, errors.New("expected an object")
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return object, err
+		}
+		key := token.(string)
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return object, fmt.Errorf("value of %q: %w", key, err)
+		}
+		var text string
+		switch {
+		case json.Unmarshal(raw, &text) == nil:
+		case string(raw) == "null":
+		default:
+			var compact bytes.Buffer
+			json.Compact(&compact, raw)
+			text = compact.String()
+		}
+		if _, seen := object.values[key]; !seen {
+			object.keys = append(object.keys, key)
+		}
+		object.values[key] = text
+	}
+	_, err := decoder.Token()
+	return object, err
+}
+
+// row lays out the object's values in the table's columns, adding a column
+// for each key not seen before.
+func (o jsonObject) row(table *sourceTable, columns map[string]int) []string {
+	for _, key := range o.keys {
+		if _, ok := columns[key]; !ok {
+			columns[key] = len(table.header)
+			table.header = append(table.header, key)
+		}
+	}
+	row := make([]string, len(table.header))
+	for key, value := range o.values {
+		row[columns[key]] = value
+	}
+	return row
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// sourceRows is the same two schedule rows in the standard layout, for every format.
+var sourceRows = []Course{
+	{Subject: "CS", CourseNumber: "272", Section: "01", CRN: "41234", Title: "Software Development, Lab", MeetDays: "MW", BeginTime: "0955", EndTime: "1140", MeetStart: "8/20/24", InstructorLastName: "Peterson"},
+	{Subject: "MATH", CourseNumber: "201", Section: "02", CRN: "41235", Title: "Discrete Mathematics", MeetDays: "TR", BeginTime: "1330", EndTime: "1515", MeetStart: "8/20/24", InstructorLastName: "Doe"},
+}
+
+const sourceCSV = "SUBJ,CRSE NUM,SEC,CRN,Title Short Desc,Meet Days,Begin Time,End Time,Meet Start,Primary Instructor Last Name\n" +
+	"CS,272,01,41234,\"Software Development, Lab\",MW,0955,1140,8/20/24,Peterson\n" +
+	"MATH,201,02,41235,Discrete Mathematics,TR,1330,1515,8/20/24,Doe\n"
+
+const sourceJSONL = `{"SUBJ": "CS", "CRSE NUM": 272, "SEC": "01", "CRN": 41234, "Title Short Desc": "Software Development, Lab", "Meet Days": "MW", "Begin Time": "0955", "End Time": "1140", "Meet Start": "8/20/24", "Primary Instructor Last Name": "Peterson"}
+
+{"SUBJ": "MATH", "CRSE NUM": 201, "SEC": "02", "CRN": 41235, "Title Short Desc": "Discrete Mathematics", "Meet Days": "TR", "Begin Time": "1330", "End Time": "1515", "Meet Start": "8/20/24", "Primary Instructor Last Name": "Doe", "Room": null}
+`
+
+// sourceMapping reads the ten standard columns the test sources have.
+func sourceMapping() *ColumnMapping {
+	mapping := DefaultColumnMapping()
+	for _, name := range []string{"schedule_type", "campus", "instruction_mode", "meeting_types", "meet_end", "building", "room", "enrollment", "instructor_first_name", "instructor_email", "college"} {
+		delete(mapping.Fields, name)
+	}
+	mapping.DropUnmapped = true
+	return mapping
+}
+
+// writeXLSX builds a minimal workbook whose first sheet holds the given XML rows.
+func writeXLSX(t *testing.T, sheetRows string, sharedStrings []string) []byte {
+	t.Helper()
+	var shared strings.Builder
+	for _, s := range sharedStrings {
+		shared.WriteString("<si><t>" + s + "</t></si>")
+	}
+	parts := map[string]string{
+		"[Content_Types].xml":        `<?xml version="1.0"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`,
+		"xl/workbook.xml":            `<?xml version="1.0"?><workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Schedule" sheetId="1" r:id="rId7"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId7" Type="worksheet" Target="worksheets/schedule.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       `<?xml version="1.0"?><sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + shared.String() + `</sst>`,
+		"xl/styles.xml":              `<?xml version="1.0"?><styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><numFmts><numFmt numFmtId="164" formatCode="m/d/yy"/></numFmts><cellXfs><xf numFmtId="0"/><xf numFmtId="164"/><xf numFmtId="20"/></cellXfs></styleSheet>`,
+		"xl/worksheets/schedule.xml": `<?xml version="1.0"?><worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + sheetRows + `</sheetData></worksheet>`,
+	}
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// sourceXLSX holds sourceRows with shared and inline strings, numeric CRNs,
+// a date-formatted start date and a time-formatted begin time.
+func sourceXLSX(t *testing.T) []byte {
+	header := []string{"SUBJ", "CRSE NUM", "SEC", "CRN", "Title Short Desc", "Meet Days", "Begin Time", "End Time", "Meet Start", "Primary Instructor Last Name"}
+	var headerCells strings.Builder
+	for i := range header {
+		headerCells.WriteString(`<c t="s"><v>` + string(rune('0'+i)) + `</v></c>`)
+	}
+	rows := `<row r="1">` + headerCells.String() + `</row>` +
+		`<row r="2"><c r="A2" t="inlineStr"><is><t>CS</t></is></c><c r="B2"><v>272</v></c><c r="C2" t="inlineStr"><is><t>01</t></is></c><c r="D2"><v>41234</v></c>` +
+		`<c r="E2" t="inlineStr"><is><r><t>Software Development</t></r><r><t>, Lab</t></r></is></c><c r="F2" t="inlineStr"><is><t>MW</t></is></c>` +
+		`<c r="G2" s="2"><v>0.41319444444444442</v></c><c r="H2" t="str"><v>1140</v></c><c r="I2" s="1"><v>45524</v></c><c r="J2" t="inlineStr"><is><t>Peterson</t></is></c></row>` +
+		`<row r="3"/>` +
+		`<row r="4"><c r="A4" t="inlineStr"><is><t>MATH</t></is></c><c r="B4"><v>201</v></c><c r="C4" t="inlineStr"><is><t>02</t></is></c><c r="D4"><v>41235</v></c>` +
+		`<c r="E4" t="inlineStr"><is><t>Discrete Mathematics</t></is></c><c r="F4" t="inlineStr"><is><t>TR</t></is></c>` +
+		`<c r="G4" t="str"><v>1330</v></c><c r="H4" t="str"><v>1515</v></c><c r="I4" s="1"><v>45524</v></c><c r="J4" t="inlineStr"><is><t>Doe</t></is></c></row>`
+	return writeXLSX(t, rows, header)
+}
+
+func TestReadSourceFormats(t *testing.T) {
+	var jsonArray strings.Builder
+	jsonArray.WriteString("[")
+	for i, line := range strings.Split(strings.TrimSpace(strings.ReplaceAll(sourceJSONL, "\n\n", "\n")), "\n") {
+		if i > 0 {
+			jsonArray.WriteString(",\n")
+		}
+		jsonArray.WriteString(line)
+	}
+	jsonArray.WriteString("]")
+
+	sources := map[string][]byte{
+		"fall.csv":    []byte(sourceCSV),
+		"fall.tsv":    []byte(strings.ReplaceAll(strings.ReplaceAll(sourceCSV, ",", "\t"), "\"Software Development\t Lab\"", "Software Development, Lab")),
+		"fall.json":   []byte(jsonArray.String()),
+		"fall.ndjson": []byte(sourceJSONL),
+		"fall.xlsx":   sourceXLSX(t),
+	}
+	dir := t.TempDir()
+	for name, data := range sources {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		courses, header, err := ReadSourceFile(path, "", sourceMapping())
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(courses, sourceRows) {
+			t.Errorf("%s: expected %+v, got %+v", name, sourceRows, courses)
+		}
+		if len(header) < 10 || header[0] != "SUBJ" {
+			t.Errorf("%s: expected the standard header, got %v", name, header)
+		}
+	}
+
+	// An explicit format overrides the extension.
+	path := filepath.Join(dir, "export.txt")
+	os.WriteFile(path, []byte(sourceJSONL), 0o644)
+	if _, _, err := ReadSourceFile(path, "", sourceMapping()); err == nil || !strings.Contains(err.Error(), "set the format explicitly") {
+		t.Errorf("Expected an unknown extension to be rejected, got %v", err)
+	}
+	if courses, _, err := ReadSourceFile(path, "jsonl", sourceMapping()); err != nil || len(courses) != 2 {
+		t.Errorf("Expected the explicit format to be used, got %d courses, %v", len(courses), err)
+	}
+}
+
+func TestReadSourceErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		reader SourceReader
+		input  string
+		want   string
+	}{
+		{"missing CRN", delimitedReader{format: FormatCSV, comma: ','}, strings.Replace(sourceCSV, "41235", "", 1), "csv row 3: missing CRN"},
+		{"bad quote", delimitedReader{format: FormatCSV, comma: ','}, sourceCSV + "CS,\"1\"x\"\n", "csv row 4"},
+		{"missing column", jsonLinesReader{}, `{"SUBJ": "CS", "CRN": "1"}`, `column "CRSE NUM" for field course_number is not in the header`},
+		{"not an object", jsonLinesReader{}, sourceJSONL + "[1, 2]\n", "jsonl row 4: expected an object"},
+		{"not an array", jsonReader{}, `{"SUBJ": "CS"}`, "json: expected an array of objects"},
+		{"bad element", jsonReader{}, `[{"CRN": "1"}, 7]`, "json row 2: expected an object"},
+		{"not a workbook", xlsxReader{}, sourceCSV, "xlsx: not an xlsx workbook"},
+	}
+	for _, test := range tests {
+		_, _, err := ReadSource(strings.NewReader(test.input), test.reader, sourceMapping())
+		var sourceErr *SourceError
+		if err == nil || !errors.As(err, &sourceErr) || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: expected a SourceError containing %q, got %v", test.name, test.want, err)
+		}
+	}
+}
+
+func TestXLSXHelpers(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "J7": 9, "AA12": 26} {
+		if got, err := xlsxColumn(ref); err != nil || got != want {
+			t.Errorf("xlsxColumn(%q) = %d, %v; want %d", ref, got, err, want)
+		}
+	}
+	if kind := numberFormatKind(`[Red]"Due "m/d/yyyy`); kind != xlsxDate {
+		t.Errorf("Expected a date format, got %d", kind)
+	}
+	if kind := numberFormatKind("h:mm AM/PM"); kind != xlsxTime {
+		t.Errorf("Expected a time format, got %d", kind)
+	}
+	if got := formatXLSXNumber("45524.5", xlsxDate); got != "8/20/24" {
+		t.Errorf("Expected 8/20/24, got %q", got)
+	}
+	if got := formatXLSXNumber("3.0", xlsxNumber); got != "3" {
+		t.Errorf("Expected 3, got %q", got)
+	}
+}
+
+func TestCourseIterator(t *testing.T) {
+	input := strings.Replace(sourceCSV, "41235", "", 1) + "\nPHIL,110,01,41236,Great Philosophical Questions,TR,0800,0945,8/20/24,Smith\n"
+	records, err := NewCourseIterator(strings.NewReader(input), delimitedReader{format: FormatCSV, comma: ','}, sourceMapping())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if header := records.Header(); header[3] != "CRN" {
+		t.Errorf("Expected the header before any record, got %v", header)
+	}
+	var crns []string
+	var rows []int
+	for records.Next() {
+		crns = append(crns, records.Course().CRN)
+		rows = append(rows, records.Row())
+	}
+	if !reflect.DeepEqual(crns, []string{"41234", "41236"}) || !reflect.DeepEqual(rows, []int{2, 5}) {
+		t.Errorf("Expected CRNs 41234 and 41236 from rows 2 and 5, got %v from %v", crns, rows)
+	}
+	if err := records.Err(); err == nil || !strings.Contains(err.Error(), "csv row 3: missing CRN") {
+		t.Errorf("Expected the row without a CRN reported, got %v", err)
+	}
+}
+
+// TestSourceConcurrentLoads reads schedules in different layouts at the
+// same time; run with -race to check that no parser state is shared.
+func TestSourceConcurrentLoads(t *testing.T) {
+	dir := t.TempDir()
+	mapping, err := json.Marshal(sourceMapping())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tsv := strings.ReplaceAll(strings.ReplaceAll(sourceCSV, ",", "\t"), "\"Software Development\t Lab\"", "Software Development, Lab")
+	for name, data := range map[string]string{"mapping.json": string(mapping), "fall.csv": sourceCSV, "spring.tsv": tsv, "summer.jsonl": sourceJSONL} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	terms := "Fall 2024=" + filepath.Join(dir, "fall.csv") + "; Spring 2025=" + filepath.Join(dir, "spring.tsv") + "; Summer 2025=" + filepath.Join(dir, "summer.jsonl")
+	config, err := LoadConfig(fs, []string{"-terms", terms, "-column-map", filepath.Join(dir, "mapping.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := config.termFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadTerms(config, files, nil)
+	if err != nil {
+		t.Fatalf("Expected every term to load, got %v", err)
+	}
+	for i, metadata := range loaded {
+		want := make([]Course, len(sourceRows))
+		for j, course := range sourceRows {
+			course.Term = files[i].term.String()
+			want[j] = course
+		}
+		if !reflect.DeepEqual(metadata.courses, want) {
+			t.Errorf("%s: expected %+v, got %+v", files[i].term, want, metadata.courses)
+		}
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]Course, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				results[i], _, _ = ReadSource(strings.NewReader(tsv), delimitedReader{format: FormatTSV, comma: '\t'}, sourceMapping())
+			} else {
+				results[i], _, _ = ReadSource(strings.NewReader(sourceCSV), delimitedReader{format: FormatCSV, comma: ','}, sourceMapping())
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i, courses := range results {
+		if len(courses) != 2 || courses[0].Title != "Software Development, Lab" {
+			t.Errorf("Load %d: expected both rows with their own delimiter, got %+v", i, courses)
+		}
+	}
+
+	os.Remove(filepath.Join(dir, "spring.tsv"))
+	if _, err := loadTerms(config, files, nil); err == nil || !strings.Contains(err.Error(), "spring.tsv") {
+		t.Errorf("Expected the missing term file named, got %v", err)
+	}
+}
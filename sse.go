@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Server-Sent Event names used by the /ask/stream endpoint, in the order they are sent.
+const (
+	eventToken     = "token"     // A piece of the answer text.
+	eventCitations = "citations" // The sections the answer was grounded in.
+	eventDone      = "done"      // Final event with answer metadata and token usage.
+	eventError     = "error"     // Sent instead of citations and done when answering fails.
+)
+
+// streamDone is the payload of the done event.
+type streamDone struct {
+	Grounded       bool   `json:"grounded"`
+	FallbackPolicy string `json:"fallback_policy,omitempty"`
+	Retrieval      string `json:"retrieval"`
+	Usage          Usage  `json:"usage"`
+	ElapsedMillis  int64  `json:"elapsed_ms"`
+	SessionID      string `json:"session_id,omitempty"`
+}
+
+// sseWriter writes Server-Sent Events and flushes each one to the client.
+type sseWriter struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+// newSSEWriter sends the event-stream headers.
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop reverse proxies from buffering the stream.
+	w.WriteHeader(http.StatusOK)
+	return &sseWriter{w: w, controller: http.NewResponseController(w)}
+}
+
+// send writes one event with a JSON payload.
+func (s *sseWriter) send(event string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// handleAskStream answers a question as a stream of Server-Sent Events: token
+// events as the answer is generated, then a citations event, then a done event.
+// The question comes from the "question" query parameter on GET, so browsers
+// can use EventSource, or from a JSON body on POST. If the client disconnects
+// the upstream LLM call is cancelled.
+func (s *Server) handleAskStream(w http.ResponseWriter, r *http.Request) {
+	var req askRequest
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+	} else {
+		req.Question = r.URL.Query().Get("question")
+		req.SessionID = r.URL.Query().Get("session_id")
+	}
+	req.Question = strings.TrimSpace(req.Question)
+	if req.Question == "" {
+		writeError(w, http.StatusBadRequest, "question is required")
+		return
+	}
+	session, err := s.sessions.Get(req.SessionID)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	// The request context is cancelled when the client disconnects.
+	ctx, cancel := context.WithTimeout(r.Context(), s.answerTimeout)
+	defer cancel()
+
+	start := time.Now()
+	events := newSSEWriter(w)
+	answer, err := s.bot.AskStream(ctx, session.Contextualize(req.Question), func(token string) {
+		if err := events.send(eventToken, map[string]string{"text": token}); err != nil {
+			cancel() // The client can no longer receive the answer.
+		}
+	})
+	if err != nil {
+		if r.Context().Err() != nil {
+			return
+		}
+		message := "failed to answer the question"
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			message = "answering the question took too long"
+		} else {
+			log.Printf("Error streaming answer to %q: %v", req.Question, err)
+		}
+		events.send(eventError, map[string]string{"error": message})
+		return
+	}
+
+	s.recordTurn(session.ID, req.Question, answer)
+
+	if err := events.send(eventCitations, answer.Citations()); err != nil {
+		return
+	}
+	done := streamDone{
+		Grounded:      answer.Grounded,
+		Retrieval:     answer.Retrieval,
+		Usage:         answer.Usage,
+		ElapsedMillis: time.Since(start).Milliseconds(),
+		SessionID:     session.ID,
+	}
+	if !answer.Grounded {
+		done.FallbackPolicy = answer.Policy.String()
+	}
+	events.send(eventDone, done)
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// seasons lists term seasons in calendar order within a year.
+var seasons = []string{"Intersession", "Spring", "Summer", "Fall"}
+
+// seasonAliases maps other spellings to a season in seasons.
+var seasonAliases = map[string]string{
+	"intersession": "Intersession",
+	"winter":       "Intersession",
+	"january":      "Intersession",
+	"spring":       "Spring",
+	"summer":       "Summer",
+	"fall":         "Fall",
+	"autumn":       "Fall",
+}
+
+// Term is an academic term such as Fall 2024. The zero Term stands for an
+// unnamed term, used when a single schedule file's term cannot be told.
+type Term struct {
+	Season string
+	Year   int
+}
+
+func (t Term) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%s %d", t.Season, t.Year)
+}
+
+// IsZero reports whether t is the unnamed term.
+func (t Term) IsZero() bool {
+	return t == Term{}
+}
+
+// Slug returns the term in a form usable in collection names, e.g. "fall-2024".
+func (t Term) Slug() string {
+	return fmt.Sprintf("%s-%d", strings.ToLower(t.Season), t.Year)
+}
+
+// index orders terms chronologically.
+func (t Term) index() int {
+	return t.Year*len(seasons) + seasonIndex(t.Season)
+}
+
+// Before reports whether t starts before other.
+func (t Term) Before(other Term) bool {
+	return t.index() < other.index()
+}
+
+func seasonIndex(season string) int {
+	for i, s := range seasons {
+		if s == season {
+			return i
+		}
+	}
+	return -1
+}
+
+// ErrTermNotLoaded is returned when a term is asked for whose schedule was not loaded.
+var ErrTermNotLoaded = errors.New("term not loaded")
+
+// termPattern matches a season and a year in either order, e.g. "Fall 2024",
+// "fall-2024", "2024 Fall" or "Spring '25".
+var termPattern = regexp.MustCompile(`(?i)^\s*(?:([a-z]+)[\s_-]*'?(\d{2}|\d{4})|(\d{4})[\s_-]*([a-z]+))\s*$`)
+
+// ParseTerm parses a term name such as "Fall 2024", "fall-2024" or "Spring '25".
+func ParseTerm(s string) (Term, error) {
+	m := termPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Term{}, fmt.Errorf("term %q must look like \"Fall 2024\"", s)
+	}
+	name, year := m[1], m[2]
+	if name == "" {
+		name, year = m[4], m[3]
+	}
+	season, ok := seasonAliases[strings.ToLower(name)]
+	if !ok {
+		return Term{}, fmt.Errorf("term %q has an unknown season %q", s, name)
+	}
+	n, _ := strconv.Atoi(year)
+	if n < 100 {
+		n += 2000
+	}
+	return Term{Season: season, Year: n}, nil
+}
+
+// fileTermPattern finds a term at the start of a schedule file name, as in
+// "Fall 2024 Class Schedule 08082024.csv".
+var fileTermPattern = regexp.MustCompile(`(?i)^([a-z]+)[\s_-]+(\d{4})\b`)
+
+// termFromFileName infers the term from a schedule file name.
+func termFromFileName(path string) (Term, bool) {
+	m := fileTermPattern.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return Term{}, false
+	}
+	term, err := ParseTerm(m[1] + " " + m[2])
+	return term, err == nil
+}
+
+// TermSource names the schedule file for one term in the configuration.
+type TermSource struct {
+	Term      string `json:"term"`
+	CSVPath   string `json:"csv_path"`
+	Format    string `json:"format,omitempty"`     // Overrides Config.Format for this term's file.
+	ColumnMap string `json:"column_map,omitempty"` // Overrides Config.ColumnMap for this term's file.
+}
+
+// parseTermSources parses "Fall 2024=path.csv;Spring 2025=other.csv".
+func parseTermSources(s string) ([]TermSource, error) {
+	var sources []TermSource
+	for _, entry := range strings.Split(s, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		term, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("term source %q must look like \"Fall 2024=schedule.csv\"", entry)
+		}
+		sources = append(sources, TermSource{Term: strings.TrimSpace(term), CSVPath: strings.TrimSpace(path)})
+	}
+	return sources, nil
+}
+
+// termFile is a parsed TermSource.
+type termFile struct {
+	term      Term
+	path      string
+	format    string
+	columnMap string
+}
+
+// termFiles returns the configured schedule files in term order. Without
+// configured terms, CSVPath is the only file and its term comes from its name.
+func (c Config) termFiles() ([]termFile, error) {
+	if len(c.Terms) == 0 {
+		term, _ := termFromFileName(c.CSVPath)
+		return []termFile{{term, c.CSVPath, c.Format, c.ColumnMap}}, nil
+	}
+
+	files := make([]termFile, 0, len(c.Terms))
+	seen := make(map[Term]bool)
+	var problems []error
+	for _, source := range c.Terms {
+		term, err := ParseTerm(source.Term)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if seen[term] {
+			problems = append(problems, fmt.Errorf("term %s is listed twice", term))
+		}
+		if strings.TrimSpace(source.CSVPath) == "" {
+			problems = append(problems, fmt.Errorf("term %s has no csv path", term))
+		}
+		seen[term] = true
+		file := termFile{term, source.CSVPath, source.Format, source.ColumnMap}
+		if file.format == "" {
+			file.format = c.Format
+		} else if _, err := ParseSourceFormat(file.format); err != nil {
+			problems = append(problems, fmt.Errorf("term %s: %w", term, err))
+		}
+		if file.columnMap == "" {
+			file.columnMap = c.ColumnMap
+		}
+		files = append(files, file)
+	}
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].term.Before(files[j].term) })
+	return files, nil
+}
+
+// defaultTerm returns the term used when a question or command names none:
+// DefaultTerm if set, otherwise the latest configured term.
+func (c Config) defaultTerm() (Term, error) {
+	files, err := c.termFiles()
+	if err != nil {
+		return Term{}, err
+	}
+	if c.DefaultTerm == "" {
+		return files[len(files)-1].term, nil
+	}
+	term, err := ParseTerm(c.DefaultTerm)
+	if err != nil {
+		return Term{}, err
+	}
+	for _, file := range files {
+		if file.term == term {
+			return term, nil
+		}
+	}
+	return Term{}, fmt.Errorf("default term %s is not one of the configured terms", term)
+}
+
+// ForTerm returns a single-term configuration for term: its schedule file
+// and its own pair of collections, named by suffixing the configured
+// collection names with the term, e.g. "courses-collection-fall-2024".
+// Without configured terms the configuration is returned unchanged.
+func (c Config) ForTerm(term Term) (Config, error) {
+	if len(c.Terms) == 0 {
+		return c, nil
+	}
+	files, err := c.termFiles()
+	if err != nil {
+		return Config{}, err
+	}
+	for _, file := range files {
+		if file.term == term {
+			single := c
+			single.Terms = nil
+			single.DefaultTerm = ""
+			single.CSVPath = file.path
+			single.Format = file.format
+			single.ColumnMap = file.columnMap
+			single.CourseCollection = c.CourseCollection + "-" + term.Slug()
+			single.InstructorCollection = c.InstructorCollection + "-" + term.Slug()
+			return single, nil
+		}
+	}
+	return Config{}, fmt.Errorf("term %s is not configured", term)
+}
+
+// Selected returns the single-term configuration of the default term, which
+// commands that work on one schedule use.
+func (c Config) Selected() (Config, Term, error) {
+	term, err := c.defaultTerm()
+	if err != nil {
+		return Config{}, Term{}, err
+	}
+	single, err := c.ForTerm(term)
+	return single, term, err
+}
+
+// loadTermMetadata loads a single-term configuration's schedule and tags
+// every row with the term.
+func loadTermMetadata(config Config, term Term, llmClient *LLMClient) (*MetadataExtractor, error) {
+	metadata, err := openSchedule(config, llmClient)
+	if err != nil {
+		return nil, err
+	}
+	for i := range metadata.courses {
+		metadata.courses[i].Term = term.String()
+	}
+	return metadata, nil
+}
+
+// loadTerms loads every term file's schedule at once, returning them in
+// the order of files. Each load reads its own file with its own parser.
+func loadTerms(config Config, files []termFile, llmClient *LLMClient) ([]*MetadataExtractor, error) {
+	loaded := make([]*MetadataExtractor, len(files))
+	errs := make([]error, len(files))
+	var wg sync.WaitGroup
+	for i, file := range files {
+		wg.Add(1)
+		go func(i int, file termFile) {
+			defer wg.Done()
+			single, err := config.ForTerm(file.term)
+			if err == nil {
+				loaded[i], err = loadTermMetadata(single, file.term, llmClient)
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", file.path, err)
+			}
+		}(i, file)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// openSchedule loads config.CSVPath in config.Format, through
+// config.ColumnMap when one is set.
+func openSchedule(config Config, llmClient *LLMClient) (*MetadataExtractor, error) {
+	var mapping *ColumnMapping
+	if config.ColumnMap != "" {
+		var err error
+		if mapping, err = LoadColumnMapping(config.ColumnMap); err != nil {
+			return nil, err
+		}
+	}
+	return NewMetadataExtractorFromSource(config.CSVPath, config.Format, mapping, llmClient)
+}
+
+// loadDefaultCourses loads the default term's schedule, for commands that
+// need course rows but no vector store.
+func loadDefaultCourses(config Config) ([]Course, error) {
+	single, term, err := config.Selected()
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := loadTermMetadata(single, term, nil)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.courses, nil
+}
+
+// questionTermPattern finds a season in a question with an optional
+// relative word before it and an optional year after it.
+var questionTermPattern = regexp.MustCompile(`(?i)\b(next|this|coming|upcoming|last|previous|past|every|each|all)?\s*\b(intersession|winter|spring|summer|fall|autumn)\b(?:\s+(?:of\s+)?'?(\d{4}|\d{2})\b)?`)
+
+// questionTerm works out which term a question is about. It returns false
+// when the question names no term, or asks about a season across terms
+// ("every fall"). Relative terms are counted from def: "next spring" is the
+// first spring after def, "last fall" the latest fall before it.
+func questionTerm(question string, def Term) (Term, bool) {
+	m := questionTermPattern.FindStringSubmatch(question)
+	if m == nil {
+		return Term{}, false
+	}
+	relative := strings.ToLower(m[1])
+	season := seasonAliases[strings.ToLower(m[2])]
+	switch relative {
+	case "every", "each", "all":
+		return Term{}, false
+	case "":
+		// "Which classes fall on Monday?" uses the verb; only "Fall" or "fall 2024" name the term.
+		if m[2] == "fall" && m[3] == "" {
+			return Term{}, false
+		}
+	}
+
+	if m[3] != "" {
+		year, _ := strconv.Atoi(m[3])
+		if year < 100 {
+			year += 2000
+		}
+		return Term{Season: season, Year: year}, true
+	}
+
+	// Without a default term to count from, assume the season's next occurrence is meant.
+	if def.IsZero() {
+		return Term{}, false
+	}
+	term := Term{Season: season, Year: def.Year}
+	switch relative {
+	case "last", "previous", "past":
+		if !term.Before(def) {
+			term.Year--
+		}
+	case "next", "coming", "upcoming":
+		if !def.Before(term) {
+			term.Year++
+		}
+	default: // "this spring" or just "spring": the default term or the next one of that season.
+		if term.Before(def) {
+			term.Year++
+		}
+	}
+	return term, true
+}
+
+// TermOffering is how a course was offered in one term.
+type TermOffering struct {
+	Term        string   `json:"term"`
+	Sections    int      `json:"sections"`
+	Instructors []string `json:"instructors"`
+}
+
+// offeringWords mark questions about which terms a course is offered in.
+var offeringWords = []string{"every term", "every semester", "each term", "each semester", "which terms", "which semesters", "usually offered", "always offered", "how often"}
+
+// crossTermAnswer answers questions such as "Is CS 272 offered every fall?"
+// from every loaded term's schedule. It returns false for other questions.
+func crossTermAnswer(question string, terms []*termIndex) (Answer, bool) {
+	var subjects []string
+	for _, index := range terms {
+		subjects = append(subjects, uniqueSubjects(index.courses())...)
+	}
+	codes := findCourseCodes(question, subjects)
+	if len(codes) == 0 {
+		return Answer{}, false
+	}
+	subject, number := codes[0].Subject, codes[0].Number
+
+	// "every fall" asks across terms; "every Monday" does not.
+	season, acrossSeason := "", false
+	if m := questionTermPattern.FindStringSubmatch(question); m != nil {
+		season = seasonAliases[strings.ToLower(m[2])]
+		switch strings.ToLower(m[1]) {
+		case "every", "each", "all":
+			acrossSeason = m[3] == ""
+		}
+	}
+	if !acrossSeason && !containsAny(strings.ToLower(question), offeringWords) {
+		return Answer{}, false
+	}
+
+	var considered, offered, missing []string
+	var documents []string
+	for _, index := range terms {
+		if index.term.IsZero() || (season != "" && index.term.Season != season) {
+			continue
+		}
+		considered = append(considered, index.term.String())
+		offering := offeringIn(index.courses(), subject, number)
+		if offering.Sections == 0 {
+			missing = append(missing, index.term.String())
+			continue
+		}
+		offering.Term = index.term.String()
+		count := fmt.Sprintf("%d sections", offering.Sections)
+		if offering.Sections == 1 {
+			count = "1 section"
+		}
+		offered = append(offered, fmt.Sprintf("%s (%s)", offering.Term, count))
+		documents = append(documents, fmt.Sprintf("%s %s %s: %d sections taught by %s", offering.Term, subject, number, offering.Sections, strings.Join(offering.Instructors, ", ")))
+	}
+
+	scope := "term"
+	if season != "" {
+		scope = strings.ToLower(season) + " term"
+	}
+	var text string
+	switch {
+	case len(considered) == 0:
+		text = fmt.Sprintf("No %s schedules are loaded, so I can't tell whether %s %s is offered every %s.", scope, subject, number, strings.TrimSuffix(scope, " term"))
+	case len(missing) == 0:
+		text = fmt.Sprintf("Yes. %s %s is offered in every loaded %s: %s.", subject, number, scope, strings.Join(offered, ", "))
+	case len(offered) == 0:
+		text = fmt.Sprintf("No. %s %s is not offered in any loaded %s (%s).", subject, number, scope, strings.Join(considered, ", "))
+	default:
+		text = fmt.Sprintf("Not every %s. %s %s is offered in %s, but not in %s.", strings.TrimSuffix(scope, " term"), subject, number, strings.Join(offered, ", "), strings.Join(missing, ", "))
+	}
+	if len(considered) == 1 {
+		text += " Only one such term is loaded, so this says nothing about other years."
+	}
+	return Answer{Text: text, Grounded: len(considered) > 0, Documents: documents, Retrieval: RetrievalAnalytics}, true
+}
+
+// offeringIn summarizes the sections of a course in one term's schedule.
+func offeringIn(courses []Course, subject, number string) TermOffering {
+	offering := TermOffering{Instructors: []string{}}
+	sections := groupSections(FilterSections(courses, SectionFilter{Subject: subject, CourseNumber: number}))
+	offering.Sections = len(sections)
+	for _, section := range sections {
+		if section.Instructor != "" && !slices.Contains(offering.Instructors, section.Instructor) {
+			offering.Instructors = append(offering.Instructors, section.Instructor)
+		}
+	}
+	sort.Strings(offering.Instructors)
+	return offering
+}
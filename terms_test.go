@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTerm(t *testing.T) {
+	for input, want := range map[string]Term{
+		"Fall 2024":   {Season: "Fall", Year: 2024},
+		"fall-2024":   {Season: "Fall", Year: 2024},
+		"2025 Spring": {Season: "Spring", Year: 2025},
+		"Spring '25":  {Season: "Spring", Year: 2025},
+		"autumn 2023": {Season: "Fall", Year: 2023},
+	} {
+		got, err := ParseTerm(input)
+		if err != nil || got != want {
+			t.Errorf("ParseTerm(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "2024", "Monsoon 2024"} {
+		if _, err := ParseTerm(input); err == nil {
+			t.Errorf("ParseTerm(%q): expected an error", input)
+		}
+	}
+
+	if term, ok := termFromFileName("data/Fall 2024 Class Schedule 08082024.csv"); !ok || term.String() != "Fall 2024" {
+		t.Errorf("Expected Fall 2024 from the schedule file name, got %v", term)
+	}
+	if _, ok := termFromFileName("schedule.csv"); ok {
+		t.Error("Expected no term from a file name without one")
+	}
+}
+
+func TestQuestionTerm(t *testing.T) {
+	fall := Term{Season: "Fall", Year: 2024}
+	tests := []struct {
+		question string
+		want     string // Empty when the question names no single term.
+	}{
+		{"What CS classes are offered next spring?", "Spring 2025"},
+		{"Who taught CS 272 last fall?", "Fall 2023"},
+		{"What is offered this fall?", "Fall 2024"},
+		{"Which sections run in Summer 2025?", "Summer 2025"},
+		{"Is CS 272 offered every fall?", ""},
+		{"Which classes fall on Monday?", ""},
+		{"Who teaches CS 272?", ""},
+	}
+	for _, test := range tests {
+		term, ok := questionTerm(test.question, fall)
+		if got := term.String(); ok != (test.want != "") || got != test.want {
+			t.Errorf("questionTerm(%q) = %q, %v; want %q", test.question, got, ok, test.want)
+		}
+	}
+}
+
+func TestLoadConfigTerms(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	config, err := LoadConfig(fs, []string{"-terms", "Spring 2025=spring.csv; Fall 2024=fall.csv"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	files, err := config.termFiles()
+	if err != nil || len(files) != 2 || files[0].term.String() != "Fall 2024" || files[1].path != "spring.csv" {
+		t.Fatalf("Expected Fall 2024 then Spring 2025, got %+v, %v", files, err)
+	}
+	if term, err := config.defaultTerm(); err != nil || term.String() != "Spring 2025" {
+		t.Errorf("Expected the latest term as default, got %v, %v", term, err)
+	}
+
+	single, term, err := config.Selected()
+	if err != nil || term.String() != "Spring 2025" || single.CSVPath != "spring.csv" {
+		t.Fatalf("Expected the Spring 2025 schedule, got %v %q, %v", term, single.CSVPath, err)
+	}
+	if single.CourseCollection != "courses-collection-spring-2025" || single.InstructorCollection != "instructors-collection-spring-2025" {
+		t.Errorf("Expected per-term collections, got %q and %q", single.CourseCollection, single.InstructorCollection)
+	}
+
+	config.DefaultTerm = "Summer 2025"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "not one of the configured terms") {
+		t.Errorf("Expected an unconfigured default term to be rejected, got %v", err)
+	}
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	if _, err := LoadConfig(fs, []string{"-terms", "Fall 2024"}); err == nil {
+		t.Error("Expected a term without a file to be rejected")
+	}
+}
+
+// newTermBot returns a bot with Fall 2023, Fall 2024 (the default) and Spring 2025 loaded.
+func newTermBot() *ChatBot {
+	fall2023 := []Course{statsRow("MATH", "60001", "In-Person", "MW", "0900", "20")}
+	bot := NewChatBot(DefaultConfig(), nil, &MetadataExtractor{courses: statsCourses[:2]}, nil, nil, nil, nil)
+	bot.AddTerm(Term{Season: "Spring", Year: 2025}, &MetadataExtractor{courses: statsCourses}, nil, nil)
+	bot.AddTerm(Term{Season: "Fall", Year: 2023}, &MetadataExtractor{courses: fall2023}, nil, nil)
+	return bot
+}
+
+func TestChatBotTerms(t *testing.T) {
+	bot := newTermBot()
+	var names []string
+	for _, term := range bot.Terms() {
+		names = append(names, term.String())
+	}
+	if strings.Join(names, ", ") != "Fall 2023, Fall 2024, Spring 2025" {
+		t.Fatalf("Expected terms in order, got %v", names)
+	}
+
+	answer, err := bot.Ask("How many students take CS classes next spring?")
+	if err != nil || !strings.HasPrefix(answer.Text, "75 students are enrolled in 3 CS sections") {
+		t.Errorf("Expected the Spring 2025 totals, got %q, %v", answer.Text, err)
+	}
+	answer, err = bot.Ask("How many students take CS classes?")
+	if err != nil || !strings.HasPrefix(answer.Text, "50 students are enrolled in 2 CS sections") {
+		t.Errorf("Expected the default term's totals, got %q, %v", answer.Text, err)
+	}
+	answer, err = bot.Ask("How many students take CS classes in Spring 2026?")
+	if err != nil || !strings.Contains(answer.Text, "Spring 2026 schedule is not loaded") || !strings.Contains(answer.Text, "Fall 2023, Fall 2024, Spring 2025") {
+		t.Errorf("Expected a not-loaded answer listing the terms, got %q, %v", answer.Text, err)
+	}
+}
+
+func TestCrossTermAnswer(t *testing.T) {
+	bot := newTermBot()
+	answer, ok := crossTermAnswer("Is CS 100 offered every fall?", bot.terms)
+	if !ok || !strings.HasPrefix(answer.Text, "Not every fall. CS 100 is offered in Fall 2024 (2 sections), but not in Fall 2023.") {
+		t.Errorf("Expected CS 100 missing from Fall 2023, got %q", answer.Text)
+	}
+	answer, ok = crossTermAnswer("Which terms is MATH 100 offered in?", bot.terms)
+	if !ok || !strings.HasPrefix(answer.Text, "Not every term. MATH 100 is offered in Fall 2023 (1 section), Spring 2025 (2 sections), but not in Fall 2024.") {
+		t.Errorf("Expected MATH 100 offerings across terms, got %q", answer.Text)
+	}
+	if _, ok := crossTermAnswer("Is CS 100 taught every Monday?", bot.terms); ok {
+		t.Error("Expected a weekday question not to be answered across terms")
+	}
+}
+
+func TestServerTerms(t *testing.T) {
+	server := NewServer(DefaultConfig(), newTermBot(), NewSessionManager(NewMemorySessionStore(), time.Hour))
+	terms := getJSON(t, server, http.MethodGet, "/terms", "", http.StatusOK)
+	if terms["default"] != "Fall 2024" || len(terms["terms"].([]interface{})) != 3 {
+		t.Errorf("Expected three terms with Fall 2024 as default, got %v", terms)
+	}
+	sections := getJSON(t, server, http.MethodGet, "/sections?term=spring-2025", "", http.StatusOK)
+	if sections["total"] != float64(5) {
+		t.Errorf("Expected 5 Spring 2025 rows, got %v", sections["total"])
+	}
+	getJSON(t, server, http.MethodGet, "/sections?term=Spring+2026", "", http.StatusNotFound)
+	getJSON(t, server, http.MethodGet, "/sections?term=someday", "", http.StatusBadRequest)
+}
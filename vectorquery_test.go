@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
 	"testing"
 	"strings"
@@ -65,7 +67,10 @@ func TestAdd(t *testing.T) {
     }
 
     // Call Add function with test courses to add them to the collection
-    ctx, client, courseCollection, instructorCollection := Add(testCourses)
+    ctx, client, courseCollection, instructorCollection, _, err := Add(testConfig(), testCourses)
+    if err != nil {
+        t.Fatalf("Add returned error: %v", err)
+    }
 
     // Verify that none of the returned values are nil
     if ctx == nil || client == nil || courseCollection == nil || instructorCollection == nil {
@@ -84,11 +89,17 @@ func TestQuery(t *testing.T) {
     }
 
     // Initialize client and collections with test data
-    ctx, client, courseCollection, _ := Add(testCourses) // Ignore instructorCollection for this test
+    ctx, client, courseCollection, _, _, err := Add(testConfig(), testCourses) // Ignore instructorCollection for this test
+    if err != nil {
+        t.Fatalf("Add returned error: %v", err)
+    }
 
     // Define the course title to search for
     queryTitle := "Black Activists & Visionaries"
-    results := Query(ctx, client, courseCollection, queryTitle)
+    results, err := Query(ctx, client, courseCollection, queryTitle, 5)
+    if err != nil {
+        t.Fatalf("Query returned error: %v", err)
+    }
 
     // Check if the results contain the expected course title
     found := false
@@ -110,3 +121,24 @@ func TestQuery(t *testing.T) {
         t.Logf("Query returned result(s) for title '%s'", queryTitle) // Log success
     }
 }
+
+// TestQueryMissingCollection verifies that Query returns a typed error instead of exiting
+func TestQueryMissingCollection(t *testing.T) {
+    _, err := Query(context.Background(), nil, nil, "anything", 5)
+    var storeErr *VectorStoreError
+    if !errors.As(err, &storeErr) || !errors.Is(err, ErrCollectionMissing) {
+        t.Fatalf("Expected a VectorStoreError matching ErrCollectionMissing, got %v", err)
+    }
+}
+
+// TestLexicalSearch verifies the lexical fallback used when the vector store is unavailable
+func TestLexicalSearch(t *testing.T) {
+    results := lexicalSearch("Who teaches Dark Knight tactics?", testCourses, 5)
+    if len(results) == 0 || !strings.Contains(results[0], "The Dark Knight's Tactics") {
+        t.Fatalf("Expected 'The Dark Knight's Tactics' first, got %v", results)
+    }
+
+    if results := lexicalSearch("organic chemistry", testCourses, 5); len(results) != 0 {
+        t.Errorf("Expected no results, got %v", results)
+    }
+}
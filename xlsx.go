@@ -0,0 +1,310 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xlsxReader reads the first worksheet of an Excel workbook. The first
+// non-blank row is the header. Cells formatted as dates become "1/2/06" and
+// cells formatted as times become "1504", the forms the standard export uses.
+type xlsxReader struct{}
+
+func (xlsxReader) Format() string {
+	return FormatXLSX
+}
+
+// Parts of an Office Open XML workbook read by xlsxReader.
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a string item: plain text or rich-text runs.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Style  int      `xml:"s,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// Kinds of number format, which decide how numeric cells are shown.
+const (
+	xlsxNumber = iota
+	xlsxDate
+	xlsxTime
+)
+
+func (xlsxReader) open(r io.Reader) (rowSource, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not an xlsx workbook: %w", err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+	var sheet xlsxSheet
+	if err := readXMLPart(files, sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := readXMLPart(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+	var styles xlsxStyles
+	if _, ok := files["xl/styles.xml"]; ok {
+		if err := readXMLPart(files, "xl/styles.xml", &styles); err != nil {
+			return nil, err
+		}
+	}
+	kinds := styleKinds(styles)
+
+	table := &sourceTable{}
+	for i, row := range sheet.Rows {
+		number := row.R
+		if number == 0 {
+			number = i + 1
+		}
+		var cells []string
+		for j, cell := range row.Cells {
+			column := j
+			if cell.Ref != "" {
+				if column, err = xlsxColumn(cell.Ref); err != nil {
+					return nil, &SourceError{Format: FormatXLSX, Row: number, Err: err}
+				}
+			}
+			for len(cells) <= column {
+				cells = append(cells, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(shared.Items) {
+					return nil, &SourceError{Format: FormatXLSX, Row: number, Err: fmt.Errorf("cell %s refers to missing shared string %q", cell.Ref, cell.Value)}
+				}
+				cells[column] = shared.Items[index].String()
+			case "inlineStr":
+				cells[column] = cell.Inline.String()
+			case "b":
+				cells[column] = map[string]string{"0": "FALSE", "1": "TRUE"}[cell.Value]
+			case "str", "e":
+				cells[column] = cell.Value
+			default:
+				kind := xlsxNumber
+				if cell.Style >= 0 && cell.Style < len(kinds) {
+					kind = kinds[cell.Style]
+				}
+				cells[column] = formatXLSXNumber(cell.Value, kind)
+			}
+		}
+
+		if table.header == nil {
+			if strings.TrimSpace(strings.Join(cells, "")) != "" {
+				table.header = cells
+			}
+			continue
+		}
+		table.add(cells, number)
+	}
+	if table.header == nil {
+		return nil, errors.New("the first worksheet is empty")
+	}
+	return table, nil
+}
+
+// firstSheetPath finds the first worksheet's part through the workbook and
+// its relationships, falling back to the conventional name.
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook xlsxWorkbook
+	var rels xlsxRelationships
+	if readXMLPart(files, "xl/workbook.xml", &workbook) == nil && len(workbook.Sheets) > 0 &&
+		readXMLPart(files, "xl/_rels/workbook.xml.rels", &rels) == nil {
+		for _, rel := range rels.Relationships {
+			if rel.ID != workbook.Sheets[0].ID {
+				continue
+			}
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+	}
+	if _, ok := files["xl/worksheets/sheet1.xml"]; ok {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	return "", errors.New("workbook has no worksheets")
+}
+
+// readXMLPart decodes one XML part of the workbook.
+func readXMLPart(files map[string]*zip.File, name string, v interface{}) error {
+	file, ok := files[name]
+	if !ok {
+		return fmt.Errorf("workbook has no %s", name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	return nil
+}
+
+// styleKinds classifies each cell style's number format as a plain number,
+// a date or a time of day.
+func styleKinds(styles xlsxStyles) []int {
+	custom := make(map[int]string, len(styles.NumFmts))
+	for _, format := range styles.NumFmts {
+		custom[format.ID] = format.Code
+	}
+	kinds := make([]int, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		switch {
+		case id >= 14 && id <= 17 || id == 22:
+			kinds[i] = xlsxDate
+		case id >= 18 && id <= 21 || id >= 45 && id <= 47:
+			kinds[i] = xlsxTime
+		case custom[id] != "":
+			kinds[i] = numberFormatKind(custom[id])
+		}
+	}
+	return kinds
+}
+
+// numberFormatKind classifies a custom number format code such as "m/d/yy"
+// or "hh:mm AM/PM". Quoted text and bracketed colors are ignored.
+func numberFormatKind(code string) int {
+	var b strings.Builder
+	quoted, bracketed := false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '[':
+			bracketed = true
+		case r == ']':
+			bracketed = false
+		case !bracketed:
+			b.WriteRune(r)
+		}
+	}
+	code = b.String()
+	switch {
+	case strings.ContainsAny(code, "dy"):
+		return xlsxDate
+	case strings.ContainsAny(code, "hs"):
+		return xlsxTime
+	}
+	return xlsxNumber
+}
+
+// xlsxEpoch is day zero of Excel's 1900 date system, allowing for its
+// fictitious 29 February 1900.
+var xlsxEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// formatXLSXNumber shows a numeric cell value as text.
+func formatXLSXNumber(value string, kind int) string {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return value
+	}
+	switch kind {
+	case xlsxDate:
+		return xlsxEpoch.AddDate(0, 0, int(math.Floor(f))).Format("1/2/06")
+	case xlsxTime:
+		minutes := int(math.Round((f-math.Floor(f))*24*60)) % (24 * 60)
+		return fmt.Sprintf("%02d%02d", minutes/60, minutes%60)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// xlsxColumn converts a cell reference such as "C7" or "AA12" to a
+// zero-based column index.
+func xlsxColumn(ref string) (int, error) {
+	column := 0
+	letters := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+		letters++
+	}
+	if letters == 0 || letters > 3 {
+		return 0, fmt.Errorf("bad cell reference %q", ref)
+	}
+	return column - 1, nil
+}